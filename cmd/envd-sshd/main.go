@@ -28,6 +28,7 @@ import (
 
 	"github.com/tensorchord/envd/pkg/config"
 	"github.com/tensorchord/envd/pkg/remote/sshd"
+	"github.com/tensorchord/envd/pkg/types"
 	"github.com/tensorchord/envd/pkg/version"
 )
 
@@ -38,6 +39,24 @@ const (
 	flagPort    = "port"
 	flagShell   = "shell"
 	flagHostKey = "hostkey"
+
+	flagAuditLog     = "audit-log"
+	flagRecord       = "record"
+	flagRecordingDir = "recording-dir"
+
+	flagNoPortForward      = "no-port-forward"
+	flagNoLocalForward     = "no-local-forward"
+	flagNoRemoteForward    = "no-remote-forward"
+	flagLocalForwardPorts  = "allow-local-forward-ports"
+	flagLocalForwardHosts  = "allow-local-forward-hosts"
+	flagRemoteForwardPorts = "allow-remote-forward-ports"
+	flagRemoteForwardHosts = "allow-remote-forward-hosts"
+	flagNoSFTP             = "no-sftp"
+	flagMaxSessions        = "max-sessions"
+	flagIdleTimeout        = "idle-timeout"
+
+	flagWebTerminalPort  = "web-terminal-port"
+	flagWebTerminalToken = "web-terminal-token"
 )
 
 func main() {
@@ -66,6 +85,82 @@ func main() {
 			Usage:   "path to the host key",
 			EnvVars: []string{"ENVD_HOST_KEY"},
 		},
+		&cli.StringFlag{
+			Name:    flagAuditLog,
+			Usage:   "path to the audit log of the sessions in JSON lines, empty to disable",
+			Value:   config.ContainerAuditLogPath,
+			EnvVars: []string{"ENVD_SSHD_AUDIT_LOG"},
+		},
+		&cli.BoolFlag{
+			Name:    flagRecord,
+			Usage:   "record the PTY sessions in the asciinema v2 format",
+			EnvVars: []string{"ENVD_SSHD_RECORD"},
+		},
+		&cli.StringFlag{
+			Name:    flagRecordingDir,
+			Usage:   "directory of the session recordings",
+			Value:   config.ContainerSessionsDir,
+			EnvVars: []string{"ENVD_SSHD_RECORDING_DIR"},
+		},
+		&cli.BoolFlag{
+			Name:    flagNoPortForward,
+			Usage:   "disable the local and remote port forwarding",
+			EnvVars: []string{"ENVD_SSHD_NO_PORT_FORWARD"},
+		},
+		&cli.BoolFlag{
+			Name:    flagNoLocalForward,
+			Usage:   "disable the local port forwarding",
+			EnvVars: []string{"ENVD_SSHD_NO_LOCAL_FORWARD"},
+		},
+		&cli.BoolFlag{
+			Name:    flagNoRemoteForward,
+			Usage:   "disable the remote port forwarding",
+			EnvVars: []string{"ENVD_SSHD_NO_REMOTE_FORWARD"},
+		},
+		&cli.StringSliceFlag{
+			Name:    flagLocalForwardPorts,
+			Usage:   "allow only the local port forwarding to the ports, e.g. 8888 or 8000-9000",
+			EnvVars: []string{"ENVD_SSHD_ALLOW_LOCAL_FORWARD_PORTS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    flagLocalForwardHosts,
+			Usage:   "allow only the local port forwarding to the hosts, e.g. localhost or 10.0.0.0/8",
+			EnvVars: []string{"ENVD_SSHD_ALLOW_LOCAL_FORWARD_HOSTS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    flagRemoteForwardPorts,
+			Usage:   "allow only the remote port forwarding on the ports, e.g. 8888 or 8000-9000",
+			EnvVars: []string{"ENVD_SSHD_ALLOW_REMOTE_FORWARD_PORTS"},
+		},
+		&cli.StringSliceFlag{
+			Name:    flagRemoteForwardHosts,
+			Usage:   "allow only the remote port forwarding on the bind addresses, e.g. 127.0.0.1",
+			EnvVars: []string{"ENVD_SSHD_ALLOW_REMOTE_FORWARD_HOSTS"},
+		},
+		&cli.BoolFlag{
+			Name:    flagNoSFTP,
+			Usage:   "disable the sftp subsystem",
+			EnvVars: []string{"ENVD_SSHD_NO_SFTP"},
+		},
+		&cli.IntFlag{
+			Name:    flagMaxSessions,
+			Usage:   "maximum number of the concurrent sessions, 0 for unlimited",
+			EnvVars: []string{"ENVD_SSHD_MAX_SESSIONS"},
+		},
+		&cli.DurationFlag{
+			Name:    flagIdleTimeout,
+			Usage:   "close the connection without activity for the duration, 0 to disable",
+			EnvVars: []string{"ENVD_SSHD_IDLE_TIMEOUT"},
+		},
+		&cli.IntFlag{
+			Name:  flagWebTerminalPort,
+			Usage: "port to serve the web terminal for the browsers, 0 to disable",
+		},
+		&cli.StringFlag{
+			Name:    flagWebTerminalToken,
+			Usage:   "token to access the web terminal",
+			EnvVars: []string{types.EnvdWebTerminalToken},
+		},
 		&cli.BoolFlag{
 			Name:  flagNoAuth,
 			Usage: "disable authentication",
@@ -138,16 +233,36 @@ func sshServer(c *cli.Context) error {
 	if c.String(flagHostKey) != "" {
 		// read private key file
 		pemBytes, err := os.ReadFile(c.String(flagHostKey))
-		if err != nil {
+		switch {
+		case os.IsNotExist(err):
+			// The host key is only copied into the environments started by envd.
+			logrus.Warnf("host key %s does not exist, use a generated one", c.String(flagHostKey))
+		case err != nil:
 			return errors.Wrapf(
 				err, "reading private key %s failed", c.String(flagHostKey))
+		default:
+			privateKey, err := rawssh.ParsePrivateKey(pemBytes)
+			if err != nil {
+				return err
+			}
+			logrus.Debugf("load host key from %s", c.String(flagHostKey))
+			hostKey = privateKey
 		}
-		privateKey, err := rawssh.ParsePrivateKey(pemBytes)
-		if err != nil {
-			return err
-		}
-		logrus.Debugf("load host key from %s", c.String(flagHostKey))
-		hostKey = privateKey
+	}
+
+	noPortForward := c.Bool(flagNoPortForward)
+	localForward, err := forwardPolicy(c, noPortForward || c.Bool(flagNoLocalForward),
+		flagLocalForwardPorts, flagLocalForwardHosts)
+	if err != nil {
+		return err
+	}
+	remoteForward, err := forwardPolicy(c, noPortForward || c.Bool(flagNoRemoteForward),
+		flagRemoteForwardPorts, flagRemoteForwardHosts)
+	if err != nil {
+		return err
+	}
+	if c.Int(flagMaxSessions) < 0 {
+		return errors.New("max sessions must not be negative")
 	}
 
 	srv := sshd.Server{
@@ -155,12 +270,51 @@ func sshServer(c *cli.Context) error {
 		Shell:          shell,
 		AuthorizedKeys: keys,
 		Hostkey:        hostKey,
+		LocalForward:   localForward,
+		RemoteForward:  remoteForward,
+		DisableSFTP:    c.Bool(flagNoSFTP),
+		MaxSessions:    c.Int(flagMaxSessions),
+		IdleTimeout:    c.Duration(flagIdleTimeout),
+
+		WebTerminalPort:  c.Int(flagWebTerminalPort),
+		WebTerminalToken: c.String(flagWebTerminalToken),
+	}
+	if !noAuth {
+		srv.AuthorizedKeysPath = c.String(flagAuthKey)
+	}
+	if path := c.String(flagAuditLog); path != "" {
+		auditLog, closer, err := sshd.OpenAuditLog(path)
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+		srv.AuditLog = auditLog
+		logrus.Infof("audit log is written to %s", path)
+	}
+	if c.Bool(flagRecord) {
+		srv.RecordingDir = c.String(flagRecordingDir)
+		logrus.Infof("PTY sessions are recorded in %s", srv.RecordingDir)
 	}
 
 	logrus.Infof("ssh server %s started in 0.0.0.0:%d", version.GetVersion().String(), srv.Port)
 	return srv.ListenAndServe()
 }
 
+func forwardPolicy(c *cli.Context, disabled bool, portsFlag, hostsFlag string) (sshd.ForwardPolicy, error) {
+	policy := sshd.ForwardPolicy{
+		Disabled: disabled,
+		Hosts:    c.StringSlice(hostsFlag),
+	}
+	for _, s := range c.StringSlice(portsFlag) {
+		r, err := types.ParsePortRange(s)
+		if err != nil {
+			return policy, errors.Wrapf(err, "failed to parse --%s", portsFlag)
+		}
+		policy.Ports = append(policy.Ports, r)
+	}
+	return policy, nil
+}
+
 func handleErr(debug bool, err error) {
 	if err == nil {
 		return
@@ -21,7 +21,7 @@ Please update the python file there instead of directly editing file inside envd
 :::
 """
 
-from typing import List, Optional
+from typing import Any, Dict, List, Optional, Sequence
 
 
 def apt_source(source: Optional[str]):
@@ -47,12 +47,37 @@ def apt_source(source: Optional[str]):
     """
 
 
-def jupyter(token: str, port: int):
+def jupyter(
+    token: str,
+    port: int,
+    kernels: Sequence[str] = (),
+    extensions: Sequence[str] = (),
+    settings: Optional[Dict[str, Any]] = None,
+):
     """Configure jupyter notebook configuration
 
+    The kernels of the installed languages (IRkernel for R, IJulia for Julia,
+    evcxr for Rust and gophernotes for Go) are registered automatically. The
+    Scala or Kotlin kernel is configured by `install.java(kernel=...)`.
+
+    Example usage:
+    ```python
+    config.jupyter(
+        kernels=["python", "r"],
+        extensions=["jupyterlab-git"],
+        settings={"@jupyterlab/apputils-extension:themes": {"theme": "JupyterLab Dark"}},
+    )
+    ```
+
     Args:
         token (str): Token for access authentication
         port (int): Port to serve jupyter notebook
+        kernels (Sequence[str]): Kernels to register, can be 'python', 'r', 'julia',
+            'rust', 'go', 'scala' or 'kotlin'. If not specified, the kernels of all
+            the installed languages are registered. The Python kernel is always available.
+        extensions (Sequence[str]): JupyterLab extensions installed from PyPI,
+            such as ['jupyterlab-git']
+        settings (Optional[Dict[str, Any]]): JupyterLab settings written to `overrides.json`
     """
 
 
@@ -170,3 +195,117 @@ def owner(uid: int, gid: int):
         uid (int): UID
         gid (int): GID
     """
+
+
+def ssh(
+    port_forward: bool = True,
+    allow_local_forward_ports: Optional[List[str]] = None,
+    allow_local_forward_hosts: Optional[List[str]] = None,
+    allow_remote_forward_ports: Optional[List[str]] = None,
+    allow_remote_forward_hosts: Optional[List[str]] = None,
+    sftp: bool = True,
+    max_sessions: int = 0,
+    idle_timeout: str = "",
+):
+    """Restrict the SSH server in the environment for the hardened images.
+
+    Example usage:
+    ```python
+    config.ssh(
+        allow_local_forward_ports=[8888, "6006-6010"],
+        allow_local_forward_hosts=["localhost", "127.0.0.1"],
+        allow_remote_forward_ports=[],
+        sftp=False,
+        max_sessions=4,
+        idle_timeout="30m",
+    )
+    ```
+
+    Args:
+        port_forward (bool): enable the local and remote port forwarding
+        allow_local_forward_ports (Optional[List[str]]): allow only the local port
+            forwarding to the ports or port ranges, e.g. `8888` or `"8000-9000"`.
+            None allows all the ports, while an empty list denies all of them
+        allow_local_forward_hosts (Optional[List[str]]): allow only the local port
+            forwarding to the hosts, IP addresses or CIDRs
+        allow_remote_forward_ports (Optional[List[str]]): allow only the remote port
+            forwarding on the ports or port ranges. None allows all the ports,
+            while an empty list denies all of them
+        allow_remote_forward_hosts (Optional[List[str]]): allow only the remote port
+            forwarding on the bind addresses
+        sftp (bool): enable the SFTP subsystem
+        max_sessions (int): maximum number of the concurrent sessions, 0 for unlimited
+        idle_timeout (str): close the idle connections after the duration, e.g. `"30m"`
+    """
+
+
+def web_terminal(host_port: Optional[int] = 0, listen_addr: Optional[str] = "127.0.0.1"):
+    """Serve a terminal in the browser for the environment.
+
+    The terminal is protected by a random token of the environment, and
+    `envd up` prints the URL with the token.
+
+    Example usage:
+    ```python
+    config.web_terminal(host_port=7681)
+    ```
+
+    Args:
+        host_port (Optional[int]): port on the host, 0 to choose a free port
+        listen_addr (Optional[str]): address to listen on the host
+    """
+
+
+def dotfiles(
+    repo: str,
+    ref: str = "",
+    install_script: str = "",
+    on_start: bool = False,
+):
+    """Install the dotfiles repository for the user of the environment.
+
+    The repository is cloned to `~/.dotfiles` and the install script runs as the
+    user. If `install_script` is not specified, the common installers like
+    `install.sh`, `bootstrap.sh` or `setup.sh` are detected, or the dotfiles are
+    linked to the home directory. The installation is cached per revision of the
+    repository. Private repositories cloned at build time use the host ssh agent.
+
+    Put it in `~/.config/envd/config.envd` to use the dotfiles in all the
+    environments without committing it to the `build.envd` of the project.
+
+    Example usage:
+    ```python
+    config.dotfiles(repo="git@github.com:user/dotfiles.git", install_script="install.sh")
+    ```
+
+    Args:
+        repo (str): git repository of the dotfiles
+        ref (str): branch, tag or commit, defaults to the default branch
+        install_script (str): install script relative to the repository
+        on_start (bool): clone the repository at the first start instead of the build,
+            only the https URL is supported since the ssh agent is not available
+            in the environment
+    """
+
+
+def vscode_server(
+    port: Optional[int] = 0,
+    extensions: Sequence[str] = (),
+    listen_addr: Optional[str] = "127.0.0.1",
+):
+    """Serve OpenVSCode Server in the browser for the environment.
+
+    The server shares the extensions with VS Code Remote-SSH, thus the extensions
+    in `install.vscode_extensions` are also available. It is protected by a random
+    connection token of the environment, and `envd up` prints the URL with the token.
+
+    Example usage:
+    ```python
+    config.vscode_server(port=3000, extensions=["ms-python.python"])
+    ```
+
+    Args:
+        port (Optional[int]): port on the host, 0 to choose a free port
+        extensions (Sequence[str]): extensions from Open VSX, such as ['ms-python.python']
+        listen_addr (Optional[str]): address to listen on the host
+    """
@@ -43,7 +43,12 @@ def conda(use_mamba: bool = False):
     """
 
 
-def pixi(use_pixi_mirror: bool = False, pypi_index: Optional[str] = None):
+def pixi(
+    use_pixi_mirror: bool = False,
+    pypi_index: Optional[str] = None,
+    manifest: str = "",
+    environment: str = "default",
+):
     """Install Pixi (https://github.com/prefix-dev/pixi).
 
     `pixi` is an alternative to `conda` that is written in Rust and provides faster
@@ -53,9 +58,21 @@ def pixi(use_pixi_mirror: bool = False, pypi_index: Optional[str] = None):
     because that part should be managed by `pixi`. You can run `pixi shell` in the
     `envd` environment to sync all the dependencies.
 
+    If `manifest` is specified, the environment is installed from the `pixi.lock`
+    next to it with `pixi install --frozen` and activated in the shell. The pixi
+    tasks are exposed as runtime commands, which can be run with
+    `envd exec --command <task>`.
+
+    Example usage:
+    ```
+    install.pixi(manifest="pixi.toml", environment="default")
+    ```
+
     Args:
         use_pixi_mirror (bool): use pixi mirror
         pypi_index (Optional[str]): customize pypi index url
+        manifest (str): pixi.toml or pyproject.toml relative to the build context
+        environment (str): pixi environment to install and activate
     """
 
 
@@ -82,12 +99,23 @@ def julia():
     """Install Julia."""
 
 
-def rust(version: Optional[str] = None):
+def rust(
+    version: Optional[str] = None,
+    components: Sequence[str] = (),
+    targets: Sequence[str] = (),
+):
     """Install Rust programming language.
 
+    Example usage:
+    ```
+    install.rust(components=["rust-analyzer"], targets=["wasm32-unknown-unknown"])
+    ```
+
     Args:
         version (Optional[str]): Rust version, such as '1.72.0'.
             If not specified, the latest stable version will be installed.
+        components (Sequence[str]): rustup components, such as ['rust-analyzer', 'clippy']
+        targets (Sequence[str]): rustup targets, such as ['wasm32-unknown-unknown']
     """
 
 
@@ -99,6 +127,40 @@ def go(version: Optional[str] = None):
     """
 
 
+def cargo_packages(name: Sequence[str]):
+    """Install Rust crates by `cargo install --locked`.
+
+    Requires `install.rust()`. The crate registry and the build artifacts are
+    cached across builds.
+
+    Example usage:
+    ```
+    install.cargo_packages(name=["ripgrep", "cargo-nextest@0.9"])
+    ```
+
+    Args:
+        name (Sequence[str]): crate names with optional versions, such as
+            ['ripgrep@14.1.0']. A partial version like '0.9' means '^0.9'.
+    """
+
+
+def go_tools(name: Sequence[str]):
+    """Install Go tools by `go install`.
+
+    Requires `install.go()`. The module cache and the build cache are kept
+    across builds.
+
+    Example usage:
+    ```
+    install.go_tools(name=["golang.org/x/tools/gopls@v0.15.3"])
+    ```
+
+    Args:
+        name (Sequence[str]): package paths with optional versions,
+            `@latest` is used if the version is not specified
+    """
+
+
 def nodejs(version: Optional[str] = None):
     """Install NodeJS programming language.
 
@@ -107,6 +169,38 @@ def nodejs(version: Optional[str] = None):
     """
 
 
+def java(
+    version: str = "17",
+    distribution: str = "temurin",
+    build_tools: Sequence[str] = (),
+    kernel: str = "",
+    cache_dependencies: bool = False,
+):
+    """Install the Java Development Kit.
+
+    `JAVA_HOME` is set to the JDK directory and its `bin` is added to `PATH`.
+
+    Args:
+        version (str): JDK major version, such as '17' or '21'.
+        distribution (str): JDK distribution, can be 'temurin', 'corretto'
+            or 'microsoft'.
+        build_tools (Sequence[str]): Build tools to install, can be 'maven',
+            'gradle' or 'sbt'.
+        kernel (str): Jupyter kernel for the JVM, can be 'scala' (almond)
+            or 'kotlin'. Requires `config.jupyter()` to be useful.
+        cache_dependencies (bool): Keep the dependency caches of the build
+            tools (`~/.m2`, `~/.gradle`, `~/.ivy2` and `~/.cache/coursier`) in
+            the cache mounts of `run()`. The files written there by `run()`,
+            e.g. the pre-fetched dependencies or `~/.m2/settings.xml`, are not
+            kept in the image.
+
+    Example usage:
+    ```python
+    install.java(version="17", build_tools=["maven", "sbt"], kernel="scala")
+    ```
+    """
+
+
 def codex(version: Optional[str] = None):
     """Install Codex agent.
 
@@ -139,6 +233,36 @@ def python_packages(
     """
 
 
+def python_project(
+    path: str = ".",
+    lock: str = "uv.lock",
+    groups: Sequence[str] = (),
+    editable: bool = True,
+):
+    """Install a `pyproject.toml` project with the exact versions in its lock file.
+
+    The lock file name selects the tool: `uv.lock` runs `uv sync --frozen`,
+    `poetry.lock` runs `poetry install` and `pdm.lock` runs `pdm sync`. The
+    dependencies only depend on `pyproject.toml` and the lock file, so editing
+    the source code of the project doesn't invalidate the cache.
+
+    The packages are installed into the envd Python if `install.python()` is
+    specified, otherwise into a uv managed virtual environment (uv only).
+
+    Example usage:
+    ```
+    install.python_project(path=".", lock="uv.lock", groups=["dev"])
+    ```
+
+    Args:
+        path (str): project directory relative to the build context
+        lock (str): lock file path relative to the project directory
+        groups (Sequence[str]): extra dependency groups to install
+        editable (bool): install the project itself in editable mode
+            (only for dev environments, where the source code is mounted)
+    """
+
+
 def conda_packages(
     name: Sequence[str] = (), channel: Sequence[str] = (), env_file: str = ""
 ):
@@ -152,19 +276,79 @@ def conda_packages(
     """
 
 
-def r_packages(name: Sequence[str]):
+def r_packages(name: Sequence[str] = (), lockfile: str = ""):
     """Install R packages by R package manager.
 
+    If `lockfile` is specified, the exact versions pinned in the `renv.lock`
+    are restored with `renv::restore`.
+
+    Example usage:
+    ```
+    install.r_packages(lockfile="renv.lock")
+    ```
+
     Args:
         name (Sequence[str]): package name list
+        lockfile (str): renv lockfile path relative to the build context
     """
 
 
-def julia_packages(name: Sequence[str]):
+def julia_packages(name: Sequence[str] = (), project: str = "", manifest: str = ""):
     """Install Julia packages.
 
+    If `project` is specified, the Julia environment is instantiated from the
+    `Project.toml` and `Manifest.toml`, which are copied to `/opt/julia/project`
+    and activated with `JULIA_PROJECT`.
+
+    Example usage:
+    ```
+    install.julia_packages(project="Project.toml", manifest="Manifest.toml")
+    ```
+
     Args:
         name (Sequence[str]): List of Julia packages
+        project (str): Project.toml path relative to the build context
+        manifest (str): Manifest.toml path, defaults to the one next to the project
+    """
+
+
+def node_packages(name: Sequence[str], global_: bool = True):
+    """Install Node.js packages by npm.
+
+    Requires `install.nodejs()`. The argument is named `global` in the
+    `build.envd`, it's renamed here since `global` is a Python keyword.
+
+    Example usage:
+    ```
+    install.node_packages(name=["typescript", "prettier"], global=True)
+    ```
+
+    Args:
+        name (Sequence[str]): package name list, such as ['typescript@5.4.5']
+        global_ (bool): install with `npm install -g`, otherwise into a separate
+            prefix that is added to `NODE_PATH` and `PATH`
+    """
+
+
+def node_project(path: str = ".", lock: str = "package-lock.json"):
+    """Install the Node.js project dependencies with the exact versions in the lock file.
+
+    The lock file name selects the package manager: `package-lock.json` runs
+    `npm ci`, `pnpm-lock.yaml` runs `pnpm install --frozen-lockfile` and
+    `yarn.lock` runs `yarn install --frozen-lockfile` (or `--immutable` for yarn
+    berry). Only `package.json` and the lock file are used, so editing the source
+    code doesn't invalidate the cache. The `node_modules` is installed in the
+    parent directory of the project, which is found by the Node.js module
+    resolution.
+
+    Example usage:
+    ```
+    install.node_project(path=".", lock="pnpm-lock.yaml")
+    ```
+
+    Args:
+        path (str): project directory relative to the build context
+        lock (str): lock file path relative to the project directory
     """
 
 
@@ -176,6 +360,39 @@ def vscode_extensions(name: Sequence[str]):
     """
 
 
+def editor(
+    name: str,
+    version: str = "",
+    product: str = "",
+    config: str = "",
+    sync: str = "",
+):
+    """Install the editor into the development environment.
+
+    Supported editors:
+    - `jetbrains`: pre-download the IDE backend for JetBrains Gateway,
+      `envd up` prints the Gateway link to connect to the environment.
+    - `neovim`: install the Neovim release, the configuration repository is
+      cloned to `~/.config/nvim` and the plugins are synced at build time.
+
+    Example usage:
+    ```python
+    install.editor(name="jetbrains", product="PY", version="2025.2.4")
+    install.editor(name="neovim", config="https://github.com/LazyVim/starter")
+    ```
+
+    Args:
+        name (str): editor name, can be 'jetbrains' or 'neovim'
+        version (str): editor version, it is required by JetBrains IDE (e.g. '2025.2.4'),
+            and defaults to '0.11.4' for Neovim
+        product (str): JetBrains product code, can be 'IU' (default), 'IC', 'PY',
+            'PC', 'GO', 'CL', 'WS' or 'RR'
+        config (str): git repository of the Neovim configuration
+        sync (str): Ex command to sync the Neovim plugins, defaults to 'Lazy! sync'
+            if `config` is specified
+    """
+
+
 def cuda(version: str, cudnn: Optional[str] = "8"):
     """Replace the base image with a `nvidia/cuda` image.
 
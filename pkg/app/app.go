@@ -46,6 +46,10 @@ func New() EnvdApp {
 			Name:  "debug",
 			Usage: "enable debug output in logs",
 		},
+		&cli.StringFlag{
+			Name:  "context",
+			Usage: "envd context to use, overrides ENVD_CONTEXT, the .envd/context file in the project and the context in use",
+		},
 		&cli.BoolFlag{
 			Name:    flag.FlagAnalytics,
 			Usage:   "enable analytics",
@@ -84,16 +88,23 @@ func New() EnvdApp {
 		CommandCreate,
 		CommandCompletion,
 		CommandContext,
+		CommandCopy,
 		CommandBuild,
+		CommandDaemon,
 		CommandDestroy,
 		CommandEnvironment,
 		CommandImage,
 		CommandInit,
+		CommandOpen,
 		CommandLogin,
+		CommandLogout,
 		CommandPause,
+		CommandPortForward,
 		CommandPrune,
 		CommandRun,
 		CommandResume,
+		CommandSessions,
+		CommandSSHKeys,
 		CommandUp,
 		CommandDebug,
 		CommandVersion,
@@ -140,6 +151,15 @@ func New() EnvdApp {
 		if err := home.Initialize(); err != nil {
 			return errors.Wrap(err, "failed to initialize home manager")
 		}
+		// The commands with `--path` select the context again for the project
+		// in the path, the others use the project in the current directory.
+		if err := selectContext(context, "."); err != nil {
+			// Keep the context commands working to fix the selection.
+			if context.Args().First() != CommandContext.Name {
+				return errors.Wrap(err, "failed to select the context")
+			}
+			logrus.WithError(err).Warn("failed to select the context")
+		}
 
 		analytics := context.Bool(flag.FlagAnalytics)
 		if err := telemetry.Initialize(analytics,
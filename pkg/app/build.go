@@ -37,6 +37,10 @@ To build an image using build.envd:
 	$ envd build
 To build and push the image to a registry:
 	$ envd build --output type=image,name=docker.io/username/image,push=true
+To emit machine-readable progress events and write the build profile:
+	$ envd build --progress=json --profile out.json
+To launch an interactive shell in the failed step for debugging:
+	$ envd build --on-failure=shell
 `,
 	Flags: []cli.Flag{
 		&cli.StringFlag{
@@ -97,7 +101,22 @@ To build and push the image to a registry:
 Build images with same tags could cause image overwriting, platform suffixes will be added to differentiate the images.`,
 			DefaultText: runtimeutil.GetRuntimePlatform(),
 		},
+		&cli.StringFlag{
+			Name:  "on-failure",
+			Usage: "Action when a build step fails: `error` returns the error, `shell` launches an interactive shell on the input state of the failed step",
+			Value: "error",
+		},
+		&cli.StringFlag{
+			Name:  "progress",
+			Usage: "Set type of progress output (auto, tty, plain, json)",
+			Value: "auto",
+		},
+		&cli.PathFlag{
+			Name:  "profile",
+			Usage: "Write per-step durations, cache hits and transferred bytes to the file in Chrome trace format",
+		},
 	},
+	Before: selectProjectContext,
 	Action: build,
 }
 
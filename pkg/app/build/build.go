@@ -29,6 +29,7 @@ import (
 	"github.com/tensorchord/envd/pkg/envd"
 	"github.com/tensorchord/envd/pkg/home"
 	progressmode "github.com/tensorchord/envd/pkg/progress/mode"
+	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
 	"github.com/tensorchord/envd/pkg/util/fileutil"
 )
 
@@ -101,12 +102,14 @@ func CreateEnvNameFromDir(absDir string) (string, error) {
 	return name, nil
 }
 
-func ParseBuildOpt(clicontext *cli.Context) (builder.Options, error) {
-	buildContext, err := filepath.Abs(clicontext.Path("path"))
+// ResolveBuildContext returns the options with the manifest, the config, the
+// build function and the tag resolved from the build context.
+func ResolveBuildContext(path, from, tag string) (builder.Options, error) {
+	buildContext, err := filepath.Abs(path)
 	if err != nil {
 		return builder.Options{}, errors.Wrap(err, "failed to get absolute path of the build context")
 	}
-	fileName, funcName, err := builder.ParseFromStr(clicontext.String("from"))
+	fileName, funcName, err := builder.ParseFromStr(from)
 	if err != nil {
 		return builder.Options{}, err
 	}
@@ -121,7 +124,6 @@ func ParseBuildOpt(clicontext *cli.Context) (builder.Options, error) {
 
 	config := home.GetManager().ConfigFile()
 
-	tag := clicontext.String("tag")
 	if tag == "" {
 		logrus.Debug("tag not specified, using default")
 		tag = fmt.Sprintf("%s:%s", filepath.Base(buildContext), "dev")
@@ -131,30 +133,61 @@ func ParseBuildOpt(clicontext *cli.Context) (builder.Options, error) {
 	if err != nil {
 		return builder.Options{}, err
 	}
-	output := clicontext.String("output")
-	exportCache := clicontext.String("export-cache")
-	importCache := clicontext.String("import-cache")
-	useProxy := clicontext.Bool("use-proxy")
-	platform := clicontext.String("platform")
-
-	opt := builder.Options{
+	return builder.Options{
 		ManifestFilePath: manifest,
 		ConfigFilePath:   config,
 		BuildFuncName:    funcName,
 		BuildContextDir:  buildContext,
 		Tag:              tag,
-		OutputOpts:       output,
-		PubKeyPath:       clicontext.Path("public-key"),
 		ProgressMode:     progressmode.AUTO,
-		ExportCache:      exportCache,
-		ImportCache:      importCache,
-		UseHTTPProxy:     useProxy,
-		Platform:         platform,
+	}, nil
+}
+
+func ParseBuildOpt(clicontext *cli.Context) (builder.Options, error) {
+	opt, err := ResolveBuildContext(clicontext.Path("path"),
+		clicontext.String("from"), clicontext.String("tag"))
+	if err != nil {
+		return builder.Options{}, err
+	}
+	onFailure := clicontext.String("on-failure")
+	if err := builder.ValidateOnFailure(onFailure); err != nil {
+		return builder.Options{}, err
 	}
 
-	debug := clicontext.Bool("debug")
-	if debug {
-		opt.ProgressMode = progressmode.PLAIN
+	// Only `envd up` has the name flag, the others use the build context.
+	opt.EnvironmentName = clicontext.String("name")
+	if opt.EnvironmentName == "" {
+		opt.EnvironmentName, err = CreateEnvNameFromDir(opt.BuildContextDir)
+		if err != nil {
+			return builder.Options{}, errors.Wrapf(err, "failed to create the env name from %s", opt.BuildContextDir)
+		}
+	}
+	opt.OutputOpts = clicontext.String("output")
+	opt.PubKeyPath = clicontext.Path("public-key")
+	if !clicontext.IsSet("public-key") {
+		// Prefer the per-environment key pair, see `envd ssh-keys rotate --env`.
+		opt.PubKeyPath, _, err = sshconfig.GetEnvKeyPair(opt.EnvironmentName)
+		if err != nil {
+			return builder.Options{}, errors.Wrap(err, "failed to get the public key")
+		}
+	}
+	opt.ExportCache = clicontext.String("export-cache")
+	opt.ImportCache = clicontext.String("import-cache")
+	opt.UseHTTPProxy = clicontext.Bool("use-proxy")
+	opt.Platform = clicontext.String("platform")
+	opt.OnFailure = onFailure
+	opt.ProfilePath = clicontext.Path("profile")
+
+	if progress := clicontext.String("progress"); progress != "" {
+		opt.ProgressMode = progress
+	}
+	if opt.ProgressMode == progressmode.AUTO || opt.ProgressMode == progressmode.TTY {
+		debug := clicontext.Bool("debug")
+		// The interactive shell shares the terminal with the progress output,
+		// use the plain mode to avoid redrawing over the shell.
+		if debug || onFailure == builder.OnFailureShell {
+			opt.ProgressMode = progressmode.PLAIN
+		}
 	}
 	return opt, nil
 }
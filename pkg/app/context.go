@@ -15,7 +15,12 @@
 package app
 
 import (
+	"os"
+
+	"github.com/cockroachdb/errors"
 	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/home"
 )
 
 var CommandContext = &cli.Command{
@@ -26,6 +31,60 @@ var CommandContext = &cli.Command{
 		CommandContextCreate,
 		CommandContextList,
 		CommandContextRemove,
+		CommandContextShow,
 		CommandContextUse,
 	},
 }
+
+// contextSources returns the sources selecting the context in the order of
+// precedence: the --context flag, ENVD_CONTEXT, the project context file in
+// the dir or its parents, and the context set by `envd context use`.
+func contextSources(clicontext *cli.Context, dir string) ([]home.ContextSource, error) {
+	var sources []home.ContextSource
+	if name := clicontext.String("context"); name != "" {
+		sources = append(sources, home.ContextSource{
+			Kind: home.ContextSourceFlag, Origin: "--context", Name: name})
+	}
+	if name := os.Getenv(home.EnvContext); name != "" {
+		sources = append(sources, home.ContextSource{
+			Kind: home.ContextSourceEnv, Origin: home.EnvContext, Name: name})
+	}
+	project, err := home.FindProjectContext(dir)
+	if err != nil {
+		return nil, err
+	}
+	if project != nil {
+		sources = append(sources, *project)
+	}
+	contexts, err := home.GetManager().ContextList()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list context")
+	}
+	sources = append(sources, home.ContextSource{
+		Kind:   home.ContextSourceGlobal,
+		Origin: home.GetManager().ContextFile(),
+		Name:   contexts.Current,
+	})
+	return sources, nil
+}
+
+// selectContext selects the context with the highest precedence for the
+// project in the dir.
+func selectContext(clicontext *cli.Context, dir string) error {
+	sources, err := contextSources(clicontext, dir)
+	if err != nil {
+		return err
+	}
+	return home.GetManager().ContextSelect(sources[0])
+}
+
+// selectProjectContext selects the context for the build context in the
+// `--path` flag of the command, which overrides the selection for the current
+// directory by the global Before.
+func selectProjectContext(clicontext *cli.Context) error {
+	dir := clicontext.Path("path")
+	if dir == "" {
+		dir = "."
+	}
+	return selectContext(clicontext, dir)
+}
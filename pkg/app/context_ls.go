@@ -40,6 +40,8 @@ func contextList(clicontext *cli.Context) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to list context")
 	}
+	// Mark the context selected by the flag, the env or the project.
+	contexts.Current = home.GetManager().ContextGetSource().Name
 	format := clicontext.String("format")
 	switch format {
 	case "table":
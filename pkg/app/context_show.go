@@ -0,0 +1,65 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/app/formatter"
+	"github.com/tensorchord/envd/pkg/app/formatter/json"
+	"github.com/tensorchord/envd/pkg/app/formatter/table"
+)
+
+var CommandContextShow = &cli.Command{
+	Name:  "show",
+	Usage: "Show the current envd context and where it is selected",
+	Description: `The context is selected in the order of precedence:
+	1. the global --context flag
+	2. the ENVD_CONTEXT environment variable
+	3. the .envd/context file in the project directory or its parents
+	4. the context set by ` + "`envd context use`" + `
+The project directory is the --path of build, up, run, exec and destroy,
+and the current directory for the other commands, e.g. envd envs ls.
+Use --path to show the context selected for the project in the path.`,
+	Flags: []cli.Flag{
+		&cli.PathFlag{
+			Name:    "path",
+			Usage:   "Path to the directory containing the build.envd",
+			Aliases: []string{"p"},
+			Value:   ".",
+		},
+		&formatter.FormatFlag,
+	},
+	Action: contextShow,
+}
+
+func contextShow(clicontext *cli.Context) error {
+	sources, err := contextSources(clicontext, clicontext.Path("path"))
+	if err != nil {
+		return errors.Wrap(err, "failed to get the context sources")
+	}
+	switch clicontext.String("format") {
+	case "table":
+		fmt.Printf("Current context is \"%s\"\n", sources[0].Name)
+		return table.RenderContextSources(os.Stdout, sources)
+	case "json":
+		return json.PrintContextSources(sources)
+	}
+	return nil
+}
@@ -43,7 +43,12 @@ func contextUse(clicontext *cli.Context) error {
 	if err != nil {
 		return errors.Wrapf(err, "failed to use the specified context %s", name)
 	}
-	logrus.WithField("cmd", "context use").
-		Infof(`Current context is "%s"`, name)
+	logger := logrus.WithField("cmd", "context use")
+	if source := home.GetManager().ContextGetSource(); source.Kind != home.ContextSourceGlobal {
+		logger.Warnf(`The context "%s" selected by %s takes precedence, see envd context show`,
+			source.Name, source.Origin)
+		return nil
+	}
+	logger.Infof(`Current context is "%s"`, name)
 	return nil
 }
@@ -0,0 +1,129 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/app/telemetry"
+	"github.com/tensorchord/envd/pkg/ssh"
+)
+
+var CommandCopy = &cli.Command{
+	Name:      "cp",
+	Category:  CategoryBasic,
+	Usage:     "Copy files and directories between the host and the environment",
+	ArgsUsage: "[env:]src [env:]dst",
+	Description: `The path in the environment is prefixed with the environment name, e.g.
+	envd cp data/ mnist:/home/envd/data
+	envd cp 'mnist:logs/*.log' .
+The relative path in the environment is relative to its working directory.
+The directories are copied recursively, and the source can be a glob pattern.`,
+	Flags: []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "resume",
+			Usage: "Continue the partially copied files, the files with the same size are skipped",
+		},
+		&cli.BoolFlag{
+			Name:    "quiet",
+			Usage:   "Do not show the progress bars",
+			Aliases: []string{"q"},
+		},
+	},
+	Action: copyFiles,
+}
+
+func copyFiles(clicontext *cli.Context) error {
+	if clicontext.NArg() != 2 {
+		return errors.New("the source and the destination are required")
+	}
+	defer func(start time.Time) {
+		telemetry.GetReporter().Telemetry(
+			"cp", telemetry.AddField("duration", time.Since(start).Seconds()))
+	}(time.Now())
+
+	srcEnv, src := parseCopyTarget(clicontext.Args().Get(0))
+	dstEnv, dst := parseCopyTarget(clicontext.Args().Get(1))
+	var name string
+	switch {
+	case srcEnv != "" && dstEnv != "":
+		return errors.New("copying between the environments is not supported")
+	case srcEnv == "" && dstEnv == "":
+		return errors.New("either the source or the destination should be in the environment, e.g. mnist:/home/envd")
+	case srcEnv != "":
+		name = srcEnv
+	default:
+		name = dstEnv
+	}
+
+	engine, err := newEngine(clicontext)
+	if err != nil {
+		return err
+	}
+	if running, err := engine.IsRunning(clicontext.Context, name); err != nil {
+		return errors.Wrapf(err, "failed to check if the environment %s is running", name)
+	} else if !running {
+		return errors.Newf("the environment %s is not running", name)
+	}
+
+	opt, err := ssh.GetOptions(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the ssh options")
+	}
+	opt.AgentForwarding = false
+	client, err := ssh.NewClient(*opt)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the ssh client")
+	}
+	defer client.Close()
+	sftpClient, err := client.SFTP()
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	remote := ssh.RemoteFileSystem{Client: sftpClient}
+	transferOpt := ssh.TransferOptions{
+		Resume: clicontext.Bool("resume"),
+		Quiet:  clicontext.Bool("quiet"),
+	}
+	if srcEnv != "" {
+		return ssh.Transfer(remote, src, ssh.LocalFileSystem{}, dst, transferOpt)
+	}
+	return ssh.Transfer(ssh.LocalFileSystem{}, src, remote, dst, transferOpt)
+}
+
+// parseCopyTarget splits `env:path` into the environment name and the path.
+// The environment name is empty if the path is on the host.
+func parseCopyTarget(arg string) (string, string) {
+	// The local path with colons can be written as `./a:b` or `/a:b`, and
+	// the windows path `C:\a` is also local.
+	if filepath.IsAbs(arg) || filepath.VolumeName(arg) != "" || strings.HasPrefix(arg, ".") {
+		return "", arg
+	}
+	name, path, ok := strings.Cut(arg, ":")
+	if !ok || name == "" || strings.ContainsAny(name, `/\`) {
+		return "", arg
+	}
+	if path == "" {
+		path = "."
+	}
+	return name, path
+}
@@ -0,0 +1,40 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import "testing"
+
+func Test_parseCopyTarget(t *testing.T) {
+	tests := []struct {
+		arg  string
+		env  string
+		path string
+	}{
+		{"mnist:/home/envd/data", "mnist", "/home/envd/data"},
+		{"mnist:", "mnist", "."},
+		{"mnist:*.log", "mnist", "*.log"},
+		{"data", "", "data"},
+		{"./a:b", "", "./a:b"},
+		{"/tmp/a:b", "", "/tmp/a:b"},
+		{"dir/a:b", "", "dir/a:b"},
+		{":a", "", ":a"},
+	}
+	for _, tt := range tests {
+		env, path := parseCopyTarget(tt.arg)
+		if env != tt.env || path != tt.path {
+			t.Errorf("parseCopyTarget(%q) = (%q, %q), want (%q, %q)", tt.arg, env, path, tt.env, tt.path)
+		}
+	}
+}
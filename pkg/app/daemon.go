@@ -0,0 +1,56 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/daemon"
+)
+
+var CommandDaemon = &cli.Command{
+	Name:     "daemon",
+	Category: CategoryExpert,
+	Usage:    "Serve the local envd API on a unix socket for IDE plugins and automation",
+	Description: `The API wraps the environment, image and build operations in HTTP+JSON.
+The socket is only accessible by the current user. Get the OpenAPI schema with:
+    curl --unix-socket ~/.cache/envd/envd.sock http://localhost/v1/openapi.json`,
+	Flags: []cli.Flag{
+		&cli.PathFlag{
+			Name:        "socket",
+			Usage:       "Path to the unix socket",
+			DefaultText: "~/.cache/envd/" + daemon.SocketFile,
+		},
+	},
+	Action: runDaemon,
+}
+
+func runDaemon(clicontext *cli.Context) error {
+	socket := clicontext.Path("socket")
+	if socket == "" {
+		var err error
+		socket, err = daemon.DefaultSocketPath()
+		if err != nil {
+			return err
+		}
+	}
+	ctx, cancel := signal.NotifyContext(clicontext.Context, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	return daemon.NewServer(nil).Serve(ctx, socket)
+}
@@ -51,7 +51,7 @@ var CommandDestroy = &cli.Command{
 			Aliases: []string{"n"},
 		},
 	},
-
+	Before: selectProjectContext,
 	Action: destroy,
 }
 
@@ -39,7 +39,7 @@ func getCurrentDirOrPanic() string {
 var CommandDescribeEnvironment = &cli.Command{
 	Name:    "describe",
 	Aliases: []string{"d"},
-	Usage:   "Show details about environments, including dependencies and port binding",
+	Usage:   "Show details about environments, including dependencies, port binding and the active runtime graph",
 	Flags: []cli.Flag{
 		&cli.StringFlag{
 			Name:    "env",
@@ -75,6 +75,11 @@ func getEnvironmentDescriptions(clicontext *cli.Context) error {
 	if err != nil {
 		return errors.Wrap(err, "failed to list port bindings")
 	}
+
+	rg, err := envdEngine.ListEnvRuntimeGraph(clicontext.Context, envName)
+	if err != nil {
+		return errors.Wrap(err, "failed to list the runtime graph")
+	}
 	format := clicontext.String("format")
 	switch format {
 	case "table":
@@ -82,9 +87,13 @@ func getEnvironmentDescriptions(clicontext *cli.Context) error {
 		if err != nil {
 			return err
 		}
-		return table.RenderPortBindings(os.Stdout, ports)
+		err = table.RenderPortBindings(os.Stdout, ports)
+		if err != nil {
+			return err
+		}
+		return table.RenderRuntimeGraph(os.Stdout, rg)
 	case "json":
-		return json.PrintEnvironmentDescriptions(dep, ports)
+		return json.PrintEnvironmentDescriptions(dep, ports, rg)
 	}
 
 	return nil
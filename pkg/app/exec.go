@@ -63,6 +63,7 @@ var CommandRun = &cli.Command{
 		},
 	},
 
+	Before: selectProjectContext,
 	Action: exec,
 }
 
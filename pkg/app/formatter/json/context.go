@@ -17,6 +17,7 @@ package json
 import (
 	"fmt"
 
+	"github.com/tensorchord/envd/pkg/home"
 	"github.com/tensorchord/envd/pkg/types"
 )
 
@@ -46,3 +47,23 @@ func PrintContext(contexts types.EnvdContext) error {
 	}
 	return printJSON(output)
 }
+
+type contextSourceInfo struct {
+	Source   string `json:"source"`
+	Origin   string `json:"origin"`
+	Context  string `json:"context"`
+	Selected bool   `json:"selected"`
+}
+
+func PrintContextSources(sources []home.ContextSource) error {
+	output := []contextSourceInfo{}
+	for i, s := range sources {
+		output = append(output, contextSourceInfo{
+			Source:   string(s.Kind),
+			Origin:   s.Origin,
+			Context:  s.Name,
+			Selected: i == 0,
+		})
+	}
+	return printJSON(output)
+}
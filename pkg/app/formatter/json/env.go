@@ -18,6 +18,7 @@ import (
 	"fmt"
 
 	"github.com/tensorchord/envd/pkg/app/formatter"
+	"github.com/tensorchord/envd/pkg/lang/ir"
 	"github.com/tensorchord/envd/pkg/types"
 )
 
@@ -51,8 +52,9 @@ func PrintEnvironments(envs []types.EnvdEnvironment) error {
 }
 
 type envDescribe struct {
-	Ports        []envPort       `json:"ports,omitempty"`
-	Dependencies []envDependency `json:"dependencies,omitempty"`
+	Ports        []envPort        `json:"ports,omitempty"`
+	Dependencies []envDependency  `json:"dependencies,omitempty"`
+	Runtime      *ir.RuntimeGraph `json:"runtime,omitempty"`
 }
 type envPort struct {
 	Name          string `json:"name"`
@@ -67,8 +69,10 @@ type envDependency struct {
 	Type string `json:"type"`
 }
 
-func PrintEnvironmentDescriptions(dep *types.Dependency, ports []types.PortBinding) error {
-	output := envDescribe{}
+func PrintEnvironmentDescriptions(dep *types.Dependency, ports []types.PortBinding, rg *ir.RuntimeGraph) error {
+	output := envDescribe{
+		Runtime: rg,
+	}
 
 	for _, port := range ports {
 		port := envPort{
@@ -94,5 +98,26 @@ func PrintEnvironmentDescriptions(dep *types.Dependency, ports []types.PortBindi
 		}
 		output.Dependencies = append(output.Dependencies, dependency)
 	}
+	for _, p := range dep.RPackages {
+		dependency := envDependency{
+			Name: p,
+			Type: "R",
+		}
+		output.Dependencies = append(output.Dependencies, dependency)
+	}
+	for _, p := range dep.JuliaPackages {
+		dependency := envDependency{
+			Name: p,
+			Type: "Julia",
+		}
+		output.Dependencies = append(output.Dependencies, dependency)
+	}
+	for _, p := range dep.NodePackages {
+		dependency := envDependency{
+			Name: p,
+			Type: "Node.js",
+		}
+		output.Dependencies = append(output.Dependencies, dependency)
+	}
 	return printJSON(output)
 }
@@ -0,0 +1,43 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import (
+	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
+)
+
+type sshKeyInfo struct {
+	Environment string `json:"environment,omitempty"`
+	Default     bool   `json:"default"`
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+	PublicKey   string `json:"public_key"`
+	PrivateKey  string `json:"private_key"`
+}
+
+func PrintSSHKeys(pairs []sshconfig.KeyPair) error {
+	output := []sshKeyInfo{}
+	for _, p := range pairs {
+		output = append(output, sshKeyInfo{
+			Environment: p.Environment,
+			Default:     p.Environment == "",
+			Type:        p.Type,
+			Fingerprint: p.Fingerprint,
+			PublicKey:   p.PublicKeyPath,
+			PrivateKey:  p.PrivateKeyPath,
+		})
+	}
+	return printJSON(output)
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+func RenderAuth(w io.Writer, auth types.EnvdAuth) error {
+	table := CreateTable(w)
+	table.Header([]string{"login name", "credential store"})
+
+	for _, a := range auth.Auth {
+		row := make([]string, 2)
+		if a.Name == auth.Current {
+			row[0] = fmt.Sprintf("%s (current)", a.Name)
+		} else {
+			row[0] = a.Name
+		}
+		row[1] = credentialStoreName(a)
+		if err := table.Append(row); err != nil {
+			return errors.Wrapf(err, "failed to append row for login %s", a.Name)
+		}
+	}
+	return errors.Wrap(table.Render(), "failed to render auth table")
+}
+
+func credentialStoreName(a types.AuthConfig) string {
+	if a.CredentialHelper == "" {
+		return "encrypted file"
+	}
+	return fmt.Sprintf("docker-credential-%s", a.CredentialHelper)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/cockroachdb/errors"
 
 	"github.com/tensorchord/envd/pkg/app/formatter"
+	"github.com/tensorchord/envd/pkg/home"
 	"github.com/tensorchord/envd/pkg/types"
 )
 
@@ -48,3 +49,21 @@ func RenderContext(w io.Writer, contexts types.EnvdContext) error {
 	}
 	return errors.Wrap(table.Render(), "failed to render context table")
 }
+
+// RenderContextSources renders the sources selecting the context, the
+// first one is selected.
+func RenderContextSources(w io.Writer, sources []home.ContextSource) error {
+	table := CreateTable(w)
+	table.Header([]string{"source", "origin", "context", "selected"})
+
+	for i, s := range sources {
+		row := []string{string(s.Kind), s.Origin, s.Name, "false"}
+		if i == 0 {
+			row[3] = "true"
+		}
+		if err := table.Append(row); err != nil {
+			return errors.Wrapf(err, "failed to append row for context source %s", s.Kind)
+		}
+	}
+	return errors.Wrap(table.Render(), "failed to render context sources table")
+}
@@ -17,7 +17,9 @@ package table
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/olekukonko/tablewriter"
@@ -25,6 +27,7 @@ import (
 	"github.com/olekukonko/tablewriter/tw"
 
 	"github.com/tensorchord/envd/pkg/app/formatter"
+	"github.com/tensorchord/envd/pkg/lang/ir"
 	"github.com/tensorchord/envd/pkg/types"
 )
 
@@ -98,6 +101,24 @@ func RenderDependencies(w io.Writer, dep *types.Dependency) error {
 			return errors.Wrapf(err, "failed to append row for APT package %s", p)
 		}
 	}
+	for _, p := range dep.RPackages {
+		err := table.Append([]string{p, "R"})
+		if err != nil {
+			return errors.Wrapf(err, "failed to append row for R package %s", p)
+		}
+	}
+	for _, p := range dep.JuliaPackages {
+		err := table.Append([]string{p, "Julia"})
+		if err != nil {
+			return errors.Wrapf(err, "failed to append row for Julia package %s", p)
+		}
+	}
+	for _, p := range dep.NodePackages {
+		err := table.Append([]string{p, "Node.js"})
+		if err != nil {
+			return errors.Wrapf(err, "failed to append row for Node.js package %s", p)
+		}
+	}
 	return errors.Wrap(table.Render(), "failed to render dependencies table")
 }
 
@@ -139,3 +160,35 @@ func CreateTable(w io.Writer) *tablewriter.Table {
 
 	return table
 }
+
+func RenderRuntimeGraph(w io.Writer, rg *ir.RuntimeGraph) error {
+	if rg == nil {
+		return nil
+	}
+	table := CreateTable(w)
+	table.Header([]string{"Runtime", "Type"})
+	rows := [][]string{}
+	names := make([]string, 0, len(rg.RuntimeCommands))
+	for name := range rg.RuntimeCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rows = append(rows, []string{fmt.Sprintf("%s: %s", name, rg.RuntimeCommands[name]), "Command"})
+	}
+	for _, command := range rg.RuntimeInitScript {
+		rows = append(rows, []string{strings.Join(command, "; "), "Init"})
+	}
+	for _, command := range rg.RuntimeDaemon {
+		rows = append(rows, []string{strings.Join(command, " "), "Daemon"})
+	}
+	for _, item := range rg.RuntimeExpose {
+		rows = append(rows, []string{fmt.Sprintf("%s: %d", item.ServiceName, item.EnvdPort), "Expose"})
+	}
+	for _, row := range rows {
+		if err := table.Append(row); err != nil {
+			return errors.Wrapf(err, "failed to append row for runtime %s", row[0])
+		}
+	}
+	return errors.Wrap(table.Render(), "failed to render runtime graph table")
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+func RenderPortForwards(w io.Writer, sessions []types.PortForwardSession) error {
+	table := CreateTable(w)
+	table.Header([]string{"env", "pid", "type", "listen", "target", "started"})
+
+	for _, s := range sessions {
+		for _, f := range s.Forwards {
+			row := make([]string, 6)
+			row[0] = s.Env
+			row[1] = strconv.Itoa(s.PID)
+			row[2] = "local"
+			if f.Remote {
+				row[2] = "remote"
+			}
+			row[3] = f.BindAddr()
+			row[4] = f.TargetAddr()
+			row[5] = s.Started.Local().Format(time.DateTime)
+			if err := table.Append(row); err != nil {
+				return errors.Wrapf(err, "failed to append row for forward %s", f)
+			}
+		}
+	}
+	return errors.Wrap(table.Render(), "failed to render port forwards table")
+}
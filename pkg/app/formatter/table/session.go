@@ -0,0 +1,56 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+func RenderSessions(w io.Writer, sessions []types.EnvdSession) error {
+	table := CreateTable(w)
+	table.Header([]string{"session", "started", "user", "key", "command",
+		"pty", "duration", "exit code", "forwards", "recorded"})
+
+	for _, s := range sessions {
+		row := make([]string, 10)
+		row[0] = s.SessionID
+		row[1] = s.Time.Local().Format(time.DateTime)
+		row[2] = s.User
+		row[3] = s.KeyFingerprint
+		row[4] = s.Command
+		if row[4] == "" {
+			row[4] = "<shell>"
+		}
+		row[5] = strconv.FormatBool(s.PTY)
+		row[6] = time.Duration(s.Duration * float64(time.Second)).Round(time.Second).String()
+		row[7] = "-"
+		if s.ExitCode != nil {
+			row[7] = strconv.Itoa(*s.ExitCode)
+		}
+		row[8] = strings.Join(s.Forwards, ", ")
+		row[9] = strconv.FormatBool(s.Recording != "")
+		if err := table.Append(row); err != nil {
+			return errors.Wrapf(err, "failed to append row for session %s", s.SessionID)
+		}
+	}
+	return errors.Wrap(table.Render(), "failed to render sessions table")
+}
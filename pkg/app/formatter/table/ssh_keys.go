@@ -0,0 +1,44 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package table
+
+import (
+	"io"
+
+	"github.com/cockroachdb/errors"
+
+	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
+)
+
+func RenderSSHKeys(w io.Writer, pairs []sshconfig.KeyPair) error {
+	table := CreateTable(w)
+	table.Header([]string{"environment", "type", "fingerprint", "private key"})
+
+	for _, p := range pairs {
+		row := make([]string, 4)
+		if p.Environment == "" {
+			row[0] = "(default)"
+		} else {
+			row[0] = p.Environment
+		}
+		row[1] = p.Type
+		row[2] = p.Fingerprint
+		row[3] = p.PrivateKeyPath
+		if err := table.Append(row); err != nil {
+			return errors.Wrapf(err, "failed to append row for key %s", p.PrivateKeyPath)
+		}
+	}
+	return errors.Wrap(table.Render(), "failed to render ssh keys table")
+}
@@ -68,7 +68,7 @@ func getImageDependency(clicontext *cli.Context) error {
 	case "table":
 		return table.RenderDependencies(os.Stdout, dep)
 	case "json":
-		return json.PrintEnvironmentDescriptions(dep, []types.PortBinding{})
+		return json.PrintEnvironmentDescriptions(dep, []types.PortBinding{}, nil)
 	}
 	return nil
 }
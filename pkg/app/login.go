@@ -26,11 +26,13 @@ import (
 	"github.com/tensorchord/envd-server/errdefs"
 	cli "github.com/urfave/cli/v2"
 
+	"github.com/tensorchord/envd/pkg/app/formatter"
+	"github.com/tensorchord/envd/pkg/app/formatter/json"
+	"github.com/tensorchord/envd/pkg/app/formatter/table"
 	"github.com/tensorchord/envd/pkg/app/telemetry"
-	"github.com/tensorchord/envd/pkg/config"
 	"github.com/tensorchord/envd/pkg/home"
+	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
 	"github.com/tensorchord/envd/pkg/types"
-	"github.com/tensorchord/envd/pkg/util/fileutil"
 )
 
 var CommandLogin = &cli.Command{
@@ -52,7 +54,31 @@ var CommandLogin = &cli.Command{
 			Aliases:  []string{"p"},
 			Required: false,
 		},
+		&cli.StringFlag{
+			Name:    "credential-helper",
+			Usage:   "Store the token with the docker credential helper `docker-credential-<helper>`, e.g. osxkeychain, wincred or pass. The token is stored in the encrypted file if not set",
+			EnvVars: []string{"ENVD_CREDENTIAL_HELPER"},
+		},
 	},
+	Subcommands: []*cli.Command{
+		{
+			Name:    "list",
+			Aliases: []string{"ls"},
+			Usage:   "List the logins to the envd server",
+			Flags: []cli.Flag{
+				&formatter.FormatFlag,
+			},
+			Action: listLogins,
+		},
+	},
+}
+
+var CommandLogout = &cli.Command{
+	Name:      "logout",
+	Category:  CategoryManagement,
+	Usage:     "Logout from the envd server and remove the stored token",
+	ArgsUsage: "[login name]",
+	Action:    logout,
 }
 
 func login(clicontext *cli.Context) error {
@@ -71,7 +97,7 @@ func login(clicontext *cli.Context) error {
 
 	telemetry.GetReporter().Telemetry("auth", telemetry.AddField("runner", c.Runner))
 
-	publicKeyPath, err := fileutil.ConfigFile(config.PublicKeyFile)
+	publicKeyPath, err := sshconfig.GetPublicKey()
 	if err != nil {
 		return errors.Wrap(err, "failed to get the public key path")
 	}
@@ -165,8 +191,9 @@ func login(clicontext *cli.Context) error {
 
 	logger.WithField("key", keyResp.Name).Debug("key is added successfully")
 	if err := home.GetManager().AuthCreate(types.AuthConfig{
-		Name:     resp.LoginName,
-		JWTToken: resp.IdentityToken,
+		Name:             resp.LoginName,
+		JWTToken:         resp.IdentityToken,
+		CredentialHelper: clicontext.String("credential-helper"),
 	}, true); err != nil {
 		return errors.Wrap(err, "failed to create the auth config")
 	}
@@ -174,6 +201,39 @@ func login(clicontext *cli.Context) error {
 	return nil
 }
 
+func listLogins(clicontext *cli.Context) error {
+	auth, err := home.GetManager().AuthList()
+	if err != nil {
+		return errors.Wrap(err, "failed to list the logins")
+	}
+	switch clicontext.String("format") {
+	case "table":
+		return table.RenderAuth(os.Stdout, auth)
+	case "json":
+		return json.PrintAuth(auth)
+	}
+	return nil
+}
+
+func logout(clicontext *cli.Context) error {
+	name := clicontext.Args().First()
+	if name == "" {
+		auth, err := home.GetManager().AuthList()
+		if err != nil {
+			return errors.Wrap(err, "failed to list the logins")
+		}
+		if auth.Current == "" {
+			return errors.New("not logged in to the envd server")
+		}
+		name = auth.Current
+	}
+	if err := home.GetManager().AuthRemove(name); err != nil {
+		return errors.Wrapf(err, "failed to logout %s", name)
+	}
+	fmt.Printf("Logged out %s\n", name)
+	return nil
+}
+
 func generateLoginName() (string, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -0,0 +1,227 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/config"
+	"github.com/tensorchord/envd/pkg/editor/vscode"
+	"github.com/tensorchord/envd/pkg/envd"
+	"github.com/tensorchord/envd/pkg/lang/version"
+	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
+	"github.com/tensorchord/envd/pkg/types"
+	"github.com/tensorchord/envd/pkg/util/osutil"
+)
+
+const (
+	serviceJupyter       = "jupyter"
+	serviceRStudio       = "rstudio"
+	serviceRStudioServer = "rstudio-server"
+	serviceVSCode        = "vscode"
+)
+
+var CommandOpen = &cli.Command{
+	Name:      "open",
+	Category:  CategoryBasic,
+	Usage:     "Open Jupyter, RStudio, VS Code or the exposed service of the environment",
+	ArgsUsage: "[jupyter|rstudio|vscode|<service>]",
+	Description: `The service is opened in the system browser, and vscode is opened with
+the Remote - SSH extension. Jupyter or RStudio is opened if the service is
+not specified.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "env",
+			Usage:   "Name of the environment",
+			Aliases: []string{"e"},
+			Value:   getCurrentDirOrPanic(),
+		},
+		&cli.BoolFlag{
+			Name:  "print",
+			Usage: "Print the URL instead of opening the browser",
+		},
+	},
+	Action: openService,
+}
+
+func openService(clicontext *cli.Context) error {
+	name := clicontext.String("env")
+	service := clicontext.Args().First()
+	engine, err := newEngine(clicontext)
+	if err != nil {
+		return err
+	}
+	if running, err := engine.IsRunning(clicontext.Context, name); err != nil {
+		return errors.Wrapf(err, "failed to check if the environment %s is running", name)
+	} else if !running {
+		return errors.Newf("the environment %s is not running", name)
+	}
+
+	if service == serviceVSCode {
+		return openVSCode(clicontext.Context, engine, name)
+	}
+
+	ports, err := engine.ListEnvPortBinding(clicontext.Context, name)
+	if err != nil {
+		return errors.Wrap(err, "failed to list port bindings")
+	}
+	binding, err := findPortBinding(ports, service)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open the environment %s", name)
+	}
+
+	var token, addr string
+	switch binding.Name {
+	case serviceJupyter:
+		token, err = jupyterToken(clicontext.Context, engine, name)
+	case config.WebTerminalService:
+		// the docker runner keeps the URL with the token in the container label
+		var res *envd.StartResult
+		if res, err = engine.GetStartResult(clicontext.Context, name); err == nil {
+			addr, token = res.WebTerminalAddr, res.WebTerminalToken
+		}
+	case config.VSCodeServerService:
+		// the connection token is only known by the address in the container label
+		var res *envd.StartResult
+		if res, err = engine.GetStartResult(clicontext.Context, name); err == nil {
+			addr = res.VSCodeServerAddr
+		}
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the token of %s", binding.Name)
+	}
+
+	u := serviceURL(binding, token)
+	if addr != "" {
+		u = addr
+	}
+	if clicontext.Bool("print") || !osutil.BrowserAvailable() {
+		fmt.Println(u)
+		return nil
+	}
+	if err := osutil.OpenBrowser(u); err != nil {
+		logrus.WithError(err).Warn("failed to open the browser, please open the URL manually")
+		fmt.Println(u)
+	}
+	return nil
+}
+
+// findPortBinding returns the binding of the service, or the binding of
+// Jupyter or RStudio if the service is empty.
+func findPortBinding(ports []types.PortBinding, service string) (types.PortBinding, error) {
+	candidates := []string{service}
+	switch service {
+	case "":
+		candidates = []string{serviceJupyter, serviceRStudioServer}
+	case serviceRStudio:
+		candidates = []string{serviceRStudioServer}
+	case "ssh":
+		return types.PortBinding{}, errors.New("ssh is not a web service, use `envd attach` or `ssh <env>.envd` instead")
+	}
+	for _, candidate := range candidates {
+		for _, p := range ports {
+			if p.Name == candidate && p.HostPort != "" {
+				return p, nil
+			}
+		}
+	}
+
+	var available []string
+	for _, p := range ports {
+		if p.Name != "" && p.Name != "ssh" && p.HostPort != "" {
+			available = append(available, p.Name)
+		}
+	}
+	available = append(available, serviceVSCode)
+	if service == "" {
+		return types.PortBinding{}, errors.Newf(
+			"neither jupyter nor rstudio is configured, the available services are: %s",
+			strings.Join(available, ", "))
+	}
+	return types.PortBinding{}, errors.Newf("the service %s is not exposed, the available services are: %s",
+		service, strings.Join(available, ", "))
+}
+
+// serviceURL returns the URL of the port binding on the host.
+func serviceURL(binding types.PortBinding, token string) string {
+	host := binding.HostIP
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	u := url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(host, binding.HostPort),
+		Path:   "/",
+	}
+	if token != "" {
+		u.RawQuery = url.Values{"token": []string{token}}.Encode()
+	}
+	return u.String()
+}
+
+// jupyterToken returns the token in the `config.jupyter` of the
+// environment, or empty if the token is not set.
+func jupyterToken(ctx context.Context, engine envd.Engine, name string) (string, error) {
+	env, err := engine.GetEnvironment(ctx, name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the environment")
+	}
+	img, err := engine.GetImage(ctx, env.Spec.Image)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the image")
+	}
+	code, ok := img.Labels[types.GeneralGraphCode]
+	if !ok {
+		logrus.Debugf("the image %s has no graph label, assume the jupyter token is not set", env.Spec.Image)
+		return "", nil
+	}
+	ver, ok := img.Labels[types.ImageLabelSyntaxVer]
+	if !ok {
+		ver = string(version.V1)
+	}
+	g, err := version.NewByVersion(ver).GetDefaultGraph().GeneralGraphFromLabel([]byte(code))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load the graph from the image")
+	}
+	cfg := g.GetJupyterConfig()
+	if cfg == nil || cfg.Token == "''" {
+		return "", nil
+	}
+	return cfg.Token, nil
+}
+
+// openVSCode opens the working directory of the environment with the
+// Remote - SSH extension, using the entry in the user's ssh config.
+func openVSCode(ctx context.Context, engine envd.Engine, name string) error {
+	if _, err := sshconfig.GetEntry(name); err != nil {
+		return errors.Wrapf(err, "run `envd up` to add the ssh config entry of %s", name)
+	}
+	var dir string
+	rg, err := engine.ListEnvRuntimeGraph(ctx, name)
+	if err != nil {
+		logrus.WithError(err).Debug("failed to get the working directory of the environment")
+	} else {
+		dir = rg.RuntimeEnviron[types.EnvdWorkDir]
+	}
+	return vscode.OpenRemote(ctx, sshconfig.BuildHostname(name), dir)
+}
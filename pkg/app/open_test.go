@@ -0,0 +1,68 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+func Test_findPortBinding(t *testing.T) {
+	ports := []types.PortBinding{
+		{Name: "ssh", Port: "2222", HostPort: "40000"},
+		{Name: "rstudio-server", Port: "8787", HostPort: "40001"},
+		{Name: "tensorboard", Port: "6006", HostPort: "40002"},
+	}
+	tests := []struct {
+		service  string
+		hostPort string
+		wantErr  bool
+	}{
+		{"", "40001", false},
+		{"rstudio", "40001", false},
+		{"tensorboard", "40002", false},
+		{"jupyter", "", true},
+		{"ssh", "", true},
+	}
+	for _, tt := range tests {
+		p, err := findPortBinding(ports, tt.service)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("findPortBinding(%q) error = %v, wantErr %v", tt.service, err, tt.wantErr)
+			continue
+		}
+		if p.HostPort != tt.hostPort {
+			t.Errorf("findPortBinding(%q) = %s, want %s", tt.service, p.HostPort, tt.hostPort)
+		}
+	}
+}
+
+func Test_serviceURL(t *testing.T) {
+	tests := []struct {
+		binding types.PortBinding
+		token   string
+		want    string
+	}{
+		{types.PortBinding{HostIP: "", HostPort: "8888"}, "", "http://localhost:8888/"},
+		{types.PortBinding{HostIP: "0.0.0.0", HostPort: "8888"}, "a b", "http://localhost:8888/?token=a+b"},
+		{types.PortBinding{HostIP: "192.168.1.2", HostPort: "8888"}, "", "http://192.168.1.2:8888/"},
+		{types.PortBinding{HostIP: "::1", HostPort: "8888"}, "", "http://[::1]:8888/"},
+	}
+	for _, tt := range tests {
+		if got := serviceURL(tt.binding, tt.token); got != tt.want {
+			t.Errorf("serviceURL(%+v, %q) = %s, want %s", tt.binding, tt.token, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/app/formatter"
+	jsonformatter "github.com/tensorchord/envd/pkg/app/formatter/json"
+	"github.com/tensorchord/envd/pkg/app/formatter/table"
+	"github.com/tensorchord/envd/pkg/ssh"
+	"github.com/tensorchord/envd/pkg/types"
+	"github.com/tensorchord/envd/pkg/util/fileutil"
+)
+
+var CommandPortForward = &cli.Command{
+	Name:      "port-forward",
+	Category:  CategoryBasic,
+	Usage:     "Forward the ports to the running environment over SSH",
+	ArgsUsage: "[[bind_address:]port:host:hostport ...]",
+	Description: `The local forward listens on the host and connects to the host:hostport
+in the environment, and the remote forward listens in the environment and
+connects to the host:hostport on the host, e.g.
+	envd port-forward --env mnist 8080:80 --remote 5432:db:5432
+The forwards run in the foreground and reconnect if the connection is dropped.`,
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    "env",
+			Usage:   "Name of the environment",
+			Aliases: []string{"e"},
+		},
+		&cli.StringSliceFlag{
+			Name:  "remote",
+			Usage: "Remote forward in the format of [bind_address:]port:host:hostport",
+		},
+	},
+	Action: portForward,
+	Subcommands: []*cli.Command{
+		{
+			Name:    "list",
+			Aliases: []string{"ls"},
+			Usage:   "List the active port forwards",
+			Flags: []cli.Flag{
+				&formatter.FormatFlag,
+			},
+			Action: listPortForwards,
+		},
+	},
+}
+
+func portForward(clicontext *cli.Context) error {
+	name := clicontext.String("env")
+	if name == "" {
+		return errors.New("the environment name is required, e.g. `envd port-forward --env mnist 8080:80`")
+	}
+	forwards, err := parsePortForwards(clicontext)
+	if err != nil {
+		return err
+	}
+
+	engine, err := newEngine(clicontext)
+	if err != nil {
+		return err
+	}
+	if running, err := engine.IsRunning(clicontext.Context, name); err != nil {
+		return errors.Wrapf(err, "failed to check if the environment %s is running", name)
+	} else if !running {
+		return errors.Newf("the environment %s is not running", name)
+	}
+	opt, err := ssh.GetOptions(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the ssh options")
+	}
+	opt.AgentForwarding = false
+
+	ctx, cancel := signal.NotifyContext(clicontext.Context, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	dir := portForwardDir()
+	session := types.PortForwardSession{
+		PID:      os.Getpid(),
+		Env:      name,
+		Started:  time.Now(),
+		Forwards: forwards,
+	}
+	forwarder := ssh.NewPortForwarder(*opt, forwards)
+	forwarder.Ready = func() {
+		if err := savePortForward(dir, session); err != nil {
+			logrus.WithError(err).Warn("failed to record the port forwards")
+		}
+		for _, f := range forwards {
+			if f.Remote {
+				fmt.Printf("Forwarding %s in the environment to %s\n", f.BindAddr(), f.TargetAddr())
+			} else {
+				fmt.Printf("Forwarding %s to %s in the environment\n", f.BindAddr(), f.TargetAddr())
+			}
+		}
+	}
+	defer removePortForward(dir, session.PID)
+	return forwarder.Run(ctx)
+}
+
+// parsePortForwards parses the local forwards in the args and the remote forwards.
+// The flag parsing stops at the first arg, thus `--remote` after the local forwards
+// is taken out of the args here.
+func parsePortForwards(clicontext *cli.Context) ([]types.PortForward, error) {
+	var local []string
+	remote := clicontext.StringSlice("remote")
+	args := clicontext.Args().Slice()
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--remote":
+			if i+1 == len(args) {
+				return nil, errors.New("the remote forward is required after --remote")
+			}
+			i++
+			remote = append(remote, args[i])
+		case strings.HasPrefix(arg, "--remote="):
+			remote = append(remote, strings.TrimPrefix(arg, "--remote="))
+		case strings.HasPrefix(arg, "-"):
+			return nil, errors.Newf("the flag %s must come before the port forwards", arg)
+		default:
+			local = append(local, arg)
+		}
+	}
+
+	var forwards []types.PortForward
+	for _, arg := range local {
+		f, err := types.ParsePortForward(arg, false)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, f)
+	}
+	for _, arg := range remote {
+		f, err := types.ParsePortForward(arg, true)
+		if err != nil {
+			return nil, err
+		}
+		forwards = append(forwards, f)
+	}
+	if len(forwards) == 0 {
+		return nil, errors.New("at least one port forward is required")
+	}
+	return forwards, nil
+}
+
+func listPortForwards(clicontext *cli.Context) error {
+	sessions, err := loadPortForwards(portForwardDir())
+	if err != nil {
+		return err
+	}
+	switch clicontext.String("format") {
+	case "table":
+		return table.RenderPortForwards(os.Stdout, sessions)
+	case "json":
+		return jsonformatter.PrintPortForwards(sessions)
+	}
+	return nil
+}
+
+func portForwardDir() string {
+	return filepath.Join(fileutil.DefaultCacheDir, "port-forwards")
+}
+
+// savePortForward records the forwards of the process in `<pid>.json`.
+func savePortForward(dir string, session types.PortForwardSession) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.Wrap(err, "failed to create the port forwards dir")
+	}
+	dat, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, strconv.Itoa(session.PID)+".json")
+	return errors.Wrap(os.WriteFile(path, dat, 0600), "failed to write the port forwards")
+}
+
+func removePortForward(dir string, pid int) {
+	path := filepath.Join(dir, strconv.Itoa(pid)+".json")
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logrus.WithError(err).Debug("failed to remove the port forwards record")
+	}
+}
+
+// loadPortForwards returns the forwards of the running processes. The
+// records left by the killed processes are removed.
+func loadPortForwards(dir string) ([]types.PortForwardSession, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to read the port forwards dir")
+	}
+	var sessions []types.PortForwardSession
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", path)
+		}
+		var session types.PortForwardSession
+		if err := json.Unmarshal(dat, &session); err != nil {
+			logrus.WithError(err).Debugf("remove the invalid port forwards record %s", path)
+			os.Remove(path)
+			continue
+		}
+		if !processRunning(session.PID) {
+			logrus.Debugf("remove the port forwards record of the exited process %d", session.PID)
+			os.Remove(path)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Started.Before(sessions[j].Started)
+	})
+	return sessions, nil
+}
+
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
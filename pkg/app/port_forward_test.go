@@ -0,0 +1,115 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+func Test_parsePortForwards(t *testing.T) {
+	tcs := []struct {
+		args    []string
+		local   int
+		remote  int
+		wantErr bool
+	}{
+		// The example in the description of the command.
+		{args: []string{"--env", "mnist", "8080:80", "--remote", "5432:db:5432"}, local: 1, remote: 1},
+		{args: []string{"--env", "mnist", "--remote", "5432:db:5432", "8080:80", "9090:90"}, local: 2, remote: 1},
+		{args: []string{"--env", "mnist", "8080:80", "--remote=5432:db:5432", "--remote", "6379:cache:6379"}, local: 1, remote: 2},
+		{args: []string{"--env", "mnist", "8080:80", "--remote"}, wantErr: true},
+		{args: []string{"--env", "mnist", "8080:80", "--env", "other"}, wantErr: true},
+		{args: []string{"--env", "mnist"}, wantErr: true},
+	}
+	for _, tc := range tcs {
+		var forwards []types.PortForward
+		var err error
+		cmd := *CommandPortForward
+		cmd.Subcommands = nil
+		cmd.Action = func(clicontext *cli.Context) error {
+			forwards, err = parsePortForwards(clicontext)
+			return nil
+		}
+		app := &cli.App{Name: "envd", Commands: []*cli.Command{&cmd}}
+		if runErr := app.Run(append([]string{"envd", "port-forward"}, tc.args...)); runErr != nil {
+			t.Fatalf("%v: failed to run the command: %v", tc.args, runErr)
+		}
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%v: parsePortForwards() error = %v, wantErr %v", tc.args, err, tc.wantErr)
+		}
+		var local, remote int
+		for _, f := range forwards {
+			if f.Remote {
+				remote++
+			} else {
+				local++
+			}
+		}
+		if local != tc.local || remote != tc.remote {
+			t.Errorf("%v: got %d local and %d remote forwards, want %d and %d",
+				tc.args, local, remote, tc.local, tc.remote)
+		}
+	}
+}
+
+func Test_loadPortForwards(t *testing.T) {
+	dir := t.TempDir()
+	running := types.PortForwardSession{
+		PID:     os.Getpid(),
+		Env:     "mnist",
+		Started: time.Now().Truncate(time.Second),
+		Forwards: []types.PortForward{{
+			BindAddress: "127.0.0.1", BindPort: 8080, TargetHost: "localhost", TargetPort: 80,
+		}},
+	}
+	if err := savePortForward(dir, running); err != nil {
+		t.Fatal(err)
+	}
+	// The pid is out of the range of the running processes.
+	exited := types.PortForwardSession{PID: 1 << 30, Env: "mnist"}
+	if err := savePortForward(dir, exited); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := loadPortForwards(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].PID != running.PID || !sessions[0].Started.Equal(running.Started) {
+		t.Fatalf("loadPortForwards() = %+v, want the running session only", sessions)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "1073741824.json")); !os.IsNotExist(err) {
+		t.Errorf("the record of the exited process is not removed: %v", err)
+	}
+
+	removePortForward(dir, running.PID)
+	if sessions, err = loadPortForwards(dir); err != nil || len(sessions) != 0 {
+		t.Errorf("loadPortForwards() = %+v, %v after removing the record", sessions, err)
+	}
+}
+
+func Test_loadPortForwardsMissingDir(t *testing.T) {
+	sessions, err := loadPortForwards(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || sessions != nil {
+		t.Errorf("loadPortForwards() = %+v, %v", sessions, err)
+	}
+}
@@ -117,6 +117,7 @@ var CommandCreate = &cli.Command{
 			Aliases: []string{"v"},
 		},
 	},
+	Before: selectProjectContext,
 	Action: run,
 }
 
@@ -202,7 +203,10 @@ func run(clicontext *cli.Context) error {
 		return errors.Wrap(err, "failed to get the ssh hostname")
 	}
 
-	privateKey := clicontext.Path("private-key")
+	privateKey, err := privateKeyPath(clicontext, res.Name)
+	if err != nil {
+		return err
+	}
 	var eo sshconfig.EntryOptions
 	switch c.Runner {
 	case types.RunnerTypeEnvdServer:
@@ -222,7 +226,7 @@ func run(clicontext *cli.Context) error {
 			EnableHostKeyCheck: false,
 			EnableAgentForward: false,
 			User:               username,
-		}
+		}.WithPinnedHostKey()
 	case types.RunnerTypeDocker:
 		eo, err = engine.GenerateSSHConfig(res.Name, hostname,
 			privateKey, res)
@@ -0,0 +1,153 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/app/formatter"
+	"github.com/tensorchord/envd/pkg/app/formatter/json"
+	"github.com/tensorchord/envd/pkg/app/formatter/table"
+	"github.com/tensorchord/envd/pkg/config"
+	"github.com/tensorchord/envd/pkg/envd"
+	"github.com/tensorchord/envd/pkg/types"
+	"github.com/tensorchord/envd/pkg/util/asciicast"
+)
+
+var sessionEnvFlag = &cli.StringFlag{
+	Name:     "env",
+	Usage:    "Name of the environment",
+	Aliases:  []string{"e"},
+	Required: true,
+}
+
+var CommandSessions = &cli.Command{
+	Name:     "sessions",
+	Category: CategoryManagement,
+	Usage:    "Inspect the SSH sessions in the audit log of the environment",
+	Subcommands: []*cli.Command{
+		{
+			Name:    "list",
+			Aliases: []string{"ls"},
+			Usage:   "List the SSH sessions of the environment",
+			Flags: []cli.Flag{
+				sessionEnvFlag,
+				&formatter.FormatFlag,
+			},
+			Action: listSessions,
+		},
+		{
+			Name:      "replay",
+			Usage:     "Replay the recorded PTY session",
+			ArgsUsage: "<session id>",
+			Flags: []cli.Flag{
+				sessionEnvFlag,
+				&cli.Float64Flag{
+					Name:  "speed",
+					Usage: "Playback speed, e.g. 2 for twice as fast",
+					Value: 1,
+				},
+				&cli.DurationFlag{
+					Name:  "idle-time-limit",
+					Usage: "Limit the idle time between the outputs, 0 to keep the recorded timing",
+					Value: 0,
+				},
+			},
+			Action: replaySession,
+		},
+	},
+}
+
+func listSessions(clicontext *cli.Context) error {
+	engine, err := newEngine(clicontext)
+	if err != nil {
+		return err
+	}
+	sessions, err := getSessions(clicontext, engine, clicontext.String("env"))
+	if err != nil {
+		return err
+	}
+	switch clicontext.String("format") {
+	case "table":
+		return table.RenderSessions(os.Stdout, sessions)
+	case "json":
+		return json.PrintSessions(sessions)
+	}
+	return nil
+}
+
+func replaySession(clicontext *cli.Context) error {
+	id := clicontext.Args().First()
+	if id == "" {
+		return errors.New("the session id is required")
+	}
+	name := clicontext.String("env")
+	engine, err := newEngine(clicontext)
+	if err != nil {
+		return err
+	}
+	sessions, err := getSessions(clicontext, engine, name)
+	if err != nil {
+		return err
+	}
+
+	var matched []types.EnvdSession
+	for _, s := range sessions {
+		if strings.HasPrefix(s.SessionID, id) {
+			matched = append(matched, s)
+		}
+	}
+	switch {
+	case len(matched) == 0:
+		return errors.Newf("cannot find the session %s in the environment %s", id, name)
+	case len(matched) > 1:
+		return errors.Newf("the session id %s is ambiguous, %d sessions are matched", id, len(matched))
+	}
+	session := matched[0]
+	if session.Recording == "" {
+		return errors.Newf("the session %s is not recorded, set ENVD_SSHD_RECORD=true in the environment to record the PTY sessions", session.SessionID)
+	}
+
+	dat, err := engine.ReadEnvFile(clicontext.Context, name, session.Recording)
+	if err != nil {
+		return err
+	}
+	if dat == nil {
+		return errors.Newf("cannot find the recording %s in the environment %s", session.Recording, name)
+	}
+	_, events, err := asciicast.Read(bytes.NewReader(dat))
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the recording %s", session.Recording)
+	}
+	return asciicast.Replay(clicontext.Context, clicontext.App.Writer, events,
+		clicontext.Float64("speed"), clicontext.Duration("idle-time-limit"))
+}
+
+func getSessions(clicontext *cli.Context, engine envd.Engine, name string) ([]types.EnvdSession, error) {
+	dat, err := engine.ReadEnvFile(clicontext.Context, name, config.ContainerAuditLogPath)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := types.NewSessionsFromAuditLog(dat)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the audit log of %s", name)
+	}
+	return sessions, nil
+}
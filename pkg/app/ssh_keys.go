@@ -0,0 +1,294 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	"github.com/tensorchord/envd/pkg/app/formatter"
+	"github.com/tensorchord/envd/pkg/app/formatter/json"
+	"github.com/tensorchord/envd/pkg/app/formatter/table"
+	"github.com/tensorchord/envd/pkg/envd"
+	"github.com/tensorchord/envd/pkg/home"
+	"github.com/tensorchord/envd/pkg/ssh"
+	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
+)
+
+var CommandSSHKeys = &cli.Command{
+	Name:     "ssh-keys",
+	Category: CategorySettings,
+	Usage:    "Manage the SSH keys to access the environments",
+	Subcommands: []*cli.Command{
+		{
+			Name:    "list",
+			Aliases: []string{"ls"},
+			Usage:   "List the default and the per-environment SSH key pairs",
+			Flags: []cli.Flag{
+				&formatter.FormatFlag,
+			},
+			Action: listSSHKeys,
+		},
+		{
+			Name:  "rotate",
+			Usage: "Generate a new key pair and update the running environments to use it",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "env",
+					Usage:   "Rotate the per-environment key pair of the environment, it is created if not exists",
+					Aliases: []string{"e"},
+				},
+				&cli.StringFlag{
+					Name:  "type",
+					Usage: "Type of the new key pair, ed25519 or rsa",
+					Value: string(sshconfig.DefaultKeyType),
+				},
+			},
+			Action: rotateSSHKeys,
+		},
+		{
+			Name:  "revoke",
+			Usage: "Revoke the per-environment key pair, the environment uses the default key pair again",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "env",
+					Usage:    "Name of the environment",
+					Aliases:  []string{"e"},
+					Required: true,
+				},
+			},
+			Action: revokeSSHKeys,
+		},
+	},
+}
+
+func listSSHKeys(clicontext *cli.Context) error {
+	pairs, err := sshconfig.ListKeyPairs()
+	if err != nil {
+		return errors.Wrap(err, "failed to list the ssh keys")
+	}
+	switch clicontext.String("format") {
+	case "table":
+		return table.RenderSSHKeys(os.Stdout, pairs)
+	case "json":
+		return json.PrintSSHKeys(pairs)
+	}
+	return nil
+}
+
+func newEngine(clicontext *cli.Context) (envd.Engine, error) {
+	c, err := home.GetManager().ContextGetCurrent()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the current context")
+	}
+	engine, err := envd.New(clicontext.Context, envd.Options{Context: c})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create envd engine")
+	}
+	return engine, nil
+}
+
+func rotateSSHKeys(clicontext *cli.Context) error {
+	keyType := sshconfig.KeyType(clicontext.String("type"))
+	name := clicontext.String("env")
+	engine, err := newEngine(clicontext)
+	if err != nil {
+		return err
+	}
+
+	var public, private, oldPublic, oldPrivate string
+	var envs []string
+	if name != "" {
+		public, private, err = sshconfig.GetEnvKeyPaths(name)
+		if err != nil {
+			return err
+		}
+		oldPublic, oldPrivate = public, private
+		envs = []string{name}
+	} else {
+		// The default key pair is named after the key type, the old pair is
+		// removed if the type changes, e.g. from the legacy id_rsa_envd.
+		public, private, err = sshconfig.GetDefaultKeyPathsForType(keyType)
+		if err != nil {
+			return err
+		}
+		oldPublic, err = sshconfig.GetPublicKey()
+		if err != nil {
+			return err
+		}
+		oldPrivate, err = sshconfig.GetPrivateKey()
+		if err != nil {
+			return err
+		}
+		envs, err = environmentsWithDefaultKey(clicontext, engine)
+		if err != nil {
+			return err
+		}
+	}
+
+	newPublic, newPrivate := public+".new", private+".new"
+	if err := sshconfig.GenerateKeyPair(newPublic, newPrivate, keyType); err != nil {
+		return err
+	}
+	defer func() {
+		os.Remove(newPublic)
+		os.Remove(newPrivate)
+	}()
+
+	return switchSSHKeys(clicontext, engine, envs, newPublic, private, func() error {
+		if err := os.Rename(newPublic, public); err != nil {
+			return errors.Wrap(err, "failed to install the public key")
+		}
+		if err := os.Rename(newPrivate, private); err != nil {
+			return errors.Wrap(err, "failed to install the private key")
+		}
+		if oldPrivate == private {
+			return nil
+		}
+		if err := os.Remove(oldPublic); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to remove the old public key")
+		}
+		if err := os.Remove(oldPrivate); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to remove the old private key")
+		}
+		return nil
+	})
+}
+
+func revokeSSHKeys(clicontext *cli.Context) error {
+	name := clicontext.String("env")
+	public, private, err := sshconfig.GetEnvKeyPaths(name)
+	if err != nil {
+		return err
+	}
+	if !sshconfig.KeyExists(public, private) {
+		return errors.Newf("the environment %s has no per-environment key pair", name)
+	}
+	defaultPublic, err := sshconfig.GetPublicKey()
+	if err != nil {
+		return err
+	}
+	defaultPrivate, err := sshconfig.GetPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	engine, err := newEngine(clicontext)
+	if err != nil {
+		return err
+	}
+	return switchSSHKeys(clicontext, engine, []string{name}, defaultPublic, defaultPrivate, func() error {
+		if err := os.Remove(public); err != nil {
+			return errors.Wrap(err, "failed to remove the public key")
+		}
+		return errors.Wrap(os.Remove(private), "failed to remove the private key")
+	})
+}
+
+// switchSSHKeys switches the environments to the new key pair without
+// losing the access to them:
+//  1. authorize both the current and the new public key in the running environments
+//  2. install the new key pair on the host
+//  3. rewrite the ssh config entries and authorize only the new public key
+func switchSSHKeys(clicontext *cli.Context, engine envd.Engine, envs []string,
+	newPublic, private string, install func() error) error {
+	newKey, err := ssh.AuthorizedKey(newPublic)
+	if err != nil {
+		return err
+	}
+
+	var updated []string
+	for _, name := range envs {
+		logger := logrus.WithField("env", name)
+		running, err := engine.IsRunning(clicontext.Context, name)
+		if err != nil || !running {
+			logger.Warnf("the environment %s is not running, rebuild it with `envd up --force` to use the new key", name)
+			continue
+		}
+		opt, err := authorizedKeysOptions(name)
+		if err != nil {
+			logger.WithError(err).Warn("failed to get the ssh options, skip the environment")
+			continue
+		}
+		currentPublic, _, err := sshconfig.GetEnvKeyPair(name)
+		if err != nil {
+			return err
+		}
+		currentKey, err := ssh.AuthorizedKey(currentPublic)
+		if err != nil {
+			return err
+		}
+		if err := ssh.SetAuthorizedKeys(*opt, []string{currentKey, newKey}); err != nil {
+			logger.WithError(err).Warnf("failed to authorize the new key in %s, rebuild it with `envd up --force` to use the new key", name)
+			continue
+		}
+		updated = append(updated, name)
+	}
+
+	if err := install(); err != nil {
+		return err
+	}
+
+	for _, name := range envs {
+		if err := sshconfig.SetIdentityFile(name, private); err != nil {
+			logrus.WithError(err).Warnf("failed to update the entry %s in your SSH config file", name)
+		}
+	}
+	for _, name := range updated {
+		opt, err := authorizedKeysOptions(name)
+		if err != nil {
+			return err
+		}
+		if err := ssh.SetAuthorizedKeys(*opt, []string{newKey}); err != nil {
+			// The sshd built by the old envd loads the keys only on startup.
+			logrus.WithError(err).Warnf("failed to revoke the old key in %s, restart the environment to use the new key", name)
+			continue
+		}
+		logrus.Infof("the environment %s is switched to the new key", name)
+	}
+	return nil
+}
+
+func authorizedKeysOptions(name string) (*ssh.Options, error) {
+	opt, err := ssh.GetOptions(name)
+	if err != nil {
+		return nil, err
+	}
+	opt.AgentForwarding = false
+	return opt, nil
+}
+
+// environmentsWithDefaultKey returns the environments without the
+// per-environment key pairs.
+func environmentsWithDefaultKey(clicontext *cli.Context, engine envd.Engine) ([]string, error) {
+	envs, err := engine.ListEnvironment(clicontext.Context)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the environments")
+	}
+	var names []string
+	for _, env := range envs {
+		public, private, err := sshconfig.GetEnvKeyPaths(env.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !sshconfig.KeyExists(public, private) {
+			names = append(names, env.Name)
+		}
+	}
+	return names, nil
+}
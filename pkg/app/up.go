@@ -16,7 +16,7 @@ package app
 
 import (
 	"fmt"
-	"path/filepath"
+	"net"
 	"strconv"
 	"time"
 
@@ -26,8 +26,12 @@ import (
 
 	buildutil "github.com/tensorchord/envd/pkg/app/build"
 	"github.com/tensorchord/envd/pkg/app/telemetry"
+	"github.com/tensorchord/envd/pkg/builder"
+	"github.com/tensorchord/envd/pkg/config"
+	"github.com/tensorchord/envd/pkg/editor"
 	"github.com/tensorchord/envd/pkg/envd"
 	"github.com/tensorchord/envd/pkg/home"
+	"github.com/tensorchord/envd/pkg/lang/ir"
 	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
 	"github.com/tensorchord/envd/pkg/types"
 	"github.com/tensorchord/envd/pkg/util/runtimeutil"
@@ -162,8 +166,19 @@ var CommandUp = &cli.Command{
 			Name:  "cap",
 			Usage: "Add capabilities to the environment (e.g. `SYS_PTRACE`)",
 		},
+		&cli.BoolFlag{
+			Name:  "watch",
+			Usage: "Watch build.envd, the loaded modules and the dependency files, rebuild and reapply the environment on change",
+			Value: false,
+		},
+		&cli.DurationFlag{
+			Name:  "debounce",
+			Usage: "Wait for the duration after the last change before rebuilding in the watch mode",
+			Value: time.Second,
+		},
 	},
 
+	Before: selectProjectContext,
 	Action: up,
 }
 
@@ -189,12 +204,14 @@ func up(clicontext *cli.Context) error {
 	}
 	start := time.Now()
 
-	ctr := filepath.Base(buildOpt.BuildContextDir)
-	detach := clicontext.Bool("detach")
+	name := buildOpt.EnvironmentName
+	watch := clicontext.Bool("watch")
+	// The terminal is used to show the watch status.
+	detach := clicontext.Bool("detach") || watch
 	logger := logrus.WithFields(logrus.Fields{
 		"cmd":             "up",
 		"builder-options": buildOpt,
-		"container-name":  ctr,
+		"container-name":  name,
 		"detach":          detach,
 	})
 	logger.Debug("starting up command")
@@ -209,13 +226,122 @@ func up(clicontext *cli.Context) error {
 	if !builder.GetGraph().IsDev() {
 		return errors.New("`envd up` only works for dev images. If you're using v1, please enable dev with `base(dev=True)`.")
 	}
-	if err = buildutil.DetectEnvironment(clicontext, buildOpt); err != nil {
+	fingerprint, err := ir.NewGraphFingerprint(builder.GetGraph())
+	if err != nil {
 		return err
 	}
-	if err = buildutil.BuildImage(clicontext, builder); err != nil {
+	res, hostname, err := reuseEnvironment(clicontext, c, buildOpt, builder)
+	if err != nil {
 		return err
 	}
+	if res == nil {
+		if err = buildutil.DetectEnvironment(clicontext, buildOpt); err != nil {
+			return err
+		}
+		if err = buildutil.BuildImage(clicontext, builder); err != nil {
+			return err
+		}
+
+		res, hostname, err = startEnvironment(clicontext, c, buildOpt, builder, clicontext.Bool("force"))
+		if err != nil {
+			return err
+		}
+	}
+	printWebTerminal(res)
+	printVSCodeServer(res)
+	printEditors(hostname, builder.GetGraph(), res)
+	telemetry.GetReporter().Telemetry(
+		"up",
+		telemetry.AddField("runner", c.Runner),
+		telemetry.AddField("duration", time.Since(start).Seconds()))
+
+	if watch {
+		return watchEnvironment(clicontext, c, buildOpt, builder, fingerprint)
+	}
+
+	if !detach {
+		engine, err := envd.New(clicontext.Context, envd.Options{Context: c})
+		if err != nil {
+			return errors.Wrap(err, "failed to create the docker client")
+		}
+		privateKey, err := privateKeyPath(clicontext, name)
+		if err != nil {
+			return err
+		}
+		if err := engine.Attach(name, hostname,
+			privateKey, res, builder.GetGraph()); err != nil {
+			return errors.Wrap(err, "failed to attach to the ssh target")
+		}
+		logrus.Infof("Detached successfully. You can attach to the container with command `ssh %s.envd`\n",
+			res.Name)
+	}
+
+	return nil
+}
+
+// reuseEnvironment applies the runtime graph to the running environment if
+// the image does not need to be rebuilt. It returns nil if the environment
+// should be built and started.
+func reuseEnvironment(clicontext *cli.Context, c *types.Context,
+	buildOpt builder.Options, builder builder.Builder) (*envd.StartResult, string, error) {
+	if clicontext.Bool("force") || c.Runner == types.RunnerTypeEnvdServer {
+		return nil, "", nil
+	}
+	// The build graph hash does not cover the content of the dependency
+	// files, e.g. requirements.txt.
+	if builder.NeedBuild(clicontext.Context) {
+		return nil, "", nil
+	}
+	engine, err := envd.New(clicontext.Context, envd.Options{Context: c})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to create the docker client")
+	}
+	name := buildOpt.EnvironmentName
+	change, err := engine.ApplyRuntimeGraph(clicontext.Context, name,
+		builder.GetGraph(), clicontext.Duration("timeout"))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to apply the runtime graph")
+	}
+	switch change {
+	case ir.GraphChangeBuild:
+		return nil, "", nil
+	case ir.GraphChangeRuntime:
+		logrus.Infof("the runtime graph is applied to the running environment %s", name)
+	default:
+		logrus.Infof("the environment %s is up to date", name)
+	}
+
+	res, err := engine.GetStartResult(clicontext.Context, name)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to get the running environment")
+	}
+	hostname, err := c.GetSSHHostname(clicontext.String("host"))
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to get the ssh hostname")
+	}
+	privateKey, err := privateKeyPath(clicontext, name)
+	if err != nil {
+		return nil, "", err
+	}
+	eo, err := engine.GenerateSSHConfig(name, hostname, privateKey, res)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to get the ssh entry")
+	}
+	if err = sshconfig.AddEntry(eo); err != nil {
+		return nil, "", errors.Wrap(err, "failed to add entry to your SSH config file")
+	}
+	return res, hostname, nil
+}
 
+// startEnvironment starts the environment with the built image and adds the
+// entry to the SSH config. It returns the start result and the SSH hostname.
+func startEnvironment(clicontext *cli.Context, c *types.Context,
+	buildOpt builder.Options, builder builder.Builder, forced bool) (*envd.StartResult, string, error) {
+	name := buildOpt.EnvironmentName
+	logger := logrus.WithFields(logrus.Fields{
+		"cmd":            "up",
+		"container-name": name,
+	})
 	logger.Debug("start running the environment")
 	// Do not attach GPU if the flag is set.
 	disableGPU := clicontext.Bool("no-gpu")
@@ -257,14 +383,7 @@ func up(clicontext *cli.Context) error {
 	}
 	engine, err := envd.New(clicontext.Context, opt)
 	if err != nil {
-		return errors.Wrap(err, "failed to create the docker client")
-	}
-	name := clicontext.String("name")
-	if name == "" {
-		name, err = buildutil.CreateEnvNameFromDir(buildOpt.BuildContextDir)
-		if err != nil {
-			return errors.Wrapf(err, "failed to create the env name from %s", buildOpt.BuildContextDir)
-		}
+		return nil, "", errors.Wrap(err, "failed to create the docker client")
 	}
 	startOptions := envd.StartOptions{
 		EnvironmentName: name,
@@ -272,7 +391,7 @@ func up(clicontext *cli.Context) error {
 		Image:           buildOpt.Tag,
 		NumGPU:          numGPU,
 		GPUSet:          gpuSet,
-		Forced:          clicontext.Bool("force"),
+		Forced:          forced,
 		Timeout:         clicontext.Duration("timeout"),
 		SshdHost:        clicontext.String("host"),
 		ShmSize:         shmSize,
@@ -282,7 +401,7 @@ func up(clicontext *cli.Context) error {
 		Capabilities:    clicontext.StringSlice("cap"),
 	}
 	if len(startOptions.NumCPU) > 0 && len(startOptions.CPUSet) > 0 {
-		return errors.New("`--cpus` and `--cpu-set` are mutually exclusive")
+		return nil, "", errors.New("`--cpus` and `--cpu-set` are mutually exclusive")
 	}
 
 	if c.Runner != types.RunnerTypeEnvdServer {
@@ -298,39 +417,88 @@ func up(clicontext *cli.Context) error {
 
 	res, err := engine.StartEnvd(clicontext.Context, startOptions)
 	if err != nil {
-		return errors.Wrap(err, "failed to start the envd environment")
+		return nil, "", errors.Wrap(err, "failed to start the envd environment")
 	}
 	logger.Debugf("container %s is running", res.Name)
 
-	logger.Debugf("add entry %s to SSH config.", ctr)
+	logger.Debugf("add entry %s to SSH config.", name)
 	hostname, err := c.GetSSHHostname(startOptions.SshdHost)
 	if err != nil {
-		return errors.Wrap(err, "failed to get the ssh hostname")
+		return nil, "", errors.Wrap(err, "failed to get the ssh hostname")
 	}
 
-	eo, err := engine.GenerateSSHConfig(ctr, hostname,
-		clicontext.Path("private-key"), res)
+	privateKey, err := privateKeyPath(clicontext, name)
+	if err != nil {
+		return nil, "", err
+	}
+	eo, err := engine.GenerateSSHConfig(name, hostname, privateKey, res)
 	if err != nil {
-		return errors.Wrap(err, "failed to get the ssh entry")
+		return nil, "", errors.Wrap(err, "failed to get the ssh entry")
 	}
 	if err = sshconfig.AddEntry(eo); err != nil {
 		logger.WithError(err).
-			Infof("failed to add entry %s to your SSH config file", ctr)
-		return errors.Wrap(err, "failed to add entry to your SSH config file")
+			Infof("failed to add entry %s to your SSH config file", name)
+		return nil, "", errors.Wrap(err, "failed to add entry to your SSH config file")
 	}
-	telemetry.GetReporter().Telemetry(
-		"up",
-		telemetry.AddField("runner", c.Runner),
-		telemetry.AddField("duration", time.Since(start).Seconds()))
+	return res, hostname, nil
+}
 
-	if !detach {
-		if err := engine.Attach(ctr, hostname,
-			clicontext.Path("private-key"), res, builder.GetGraph()); err != nil {
-			return errors.Wrap(err, "failed to attach to the ssh target")
-		}
-		logrus.Infof("Detached successfully. You can attach to the container with command `ssh %s.envd`\n",
-			startOptions.EnvironmentName)
+// privateKeyPath returns the private key to access the environment, the
+// per-environment key pair is preferred if the flag is not set.
+func privateKeyPath(clicontext *cli.Context, name string) (string, error) {
+	if clicontext.IsSet("private-key") {
+		return clicontext.Path("private-key"), nil
 	}
+	_, private, err := sshconfig.GetEnvKeyPair(name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the private key")
+	}
+	return private, nil
+}
 
-	return nil
+// printWebTerminal prints the URL of the web terminal if it is enabled.
+func printWebTerminal(res *envd.StartResult) {
+	switch {
+	case res.WebTerminalAddr != "":
+		logrus.Infof("the web terminal is available at %s", res.WebTerminalAddr)
+	case res.WebTerminalToken != "":
+		logrus.Infof("the web terminal is exposed as the port %s, open it with `?token=%s`",
+			config.WebTerminalService, res.WebTerminalToken)
+	}
+}
+
+// printVSCodeServer prints the URL of the vscode server if it is enabled.
+func printVSCodeServer(res *envd.StartResult) {
+	switch {
+	case res.VSCodeServerAddr != "":
+		logrus.Infof("the vscode server is available at %s", res.VSCodeServerAddr)
+	case res.VSCodeServerToken != "":
+		logrus.Infof("the vscode server is exposed as the port %s, open it with `?tkn=%s`",
+			config.VSCodeServerService, res.VSCodeServerToken)
+	}
+}
+
+// printEditors prints how to connect to the environment from the editors of
+// `install.editor`, e.g. the JetBrains Gateway link. The hostname is the one
+// in the SSH config entry.
+func printEditors(hostname string, g ir.Graph, res *envd.StartResult) {
+	host := hostname
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = envd.Localhost
+	}
+	user := g.GetUser()
+	if user == "" {
+		user = "envd"
+	}
+	for _, cfg := range g.GetEditors() {
+		e, err := editor.New(cfg.Name, cfg.Version, cfg.Options)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to get the editor %s", cfg.Name)
+			continue
+		}
+		hint := e.ConnectionHint(host, res.SSHPort, user, g.GetWorkingDir())
+		if hint != "" {
+			logrus.Info(hint)
+		}
+	}
 }
@@ -0,0 +1,189 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+
+	buildutil "github.com/tensorchord/envd/pkg/app/build"
+	"github.com/tensorchord/envd/pkg/builder"
+	"github.com/tensorchord/envd/pkg/envd"
+	"github.com/tensorchord/envd/pkg/lang/ir"
+	"github.com/tensorchord/envd/pkg/lang/version"
+	"github.com/tensorchord/envd/pkg/types"
+	"github.com/tensorchord/envd/pkg/util/fileutil"
+)
+
+// watchEnvironment watches the manifest and the dependency files of the
+// running environment, rebuilds the image and recreates the environment on
+// change. The build context and the volumes are mounted again, thus the data
+// in the workspace is preserved. The changes of the commands, daemons and init
+// scripts are applied to the running environment without rebuilding.
+// The fingerprint should be taken right after the interpretation, since the
+// compilation modifies the graph.
+func watchEnvironment(clicontext *cli.Context, c *types.Context,
+	buildOpt builder.Options, b builder.Builder, prev ir.GraphFingerprint) error {
+	ctx, cancel := signal.NotifyContext(clicontext.Context, os.Interrupt)
+	defer cancel()
+
+	watcher, err := fileutil.NewWatcher(clicontext.Duration("debounce"))
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	files := b.WatchedFiles()
+	if err := watcher.Watch(files); err != nil {
+		return err
+	}
+
+	for {
+		watchStatus("watching %d files for changes, press Ctrl+C to stop", len(files))
+		changed, err := watcher.Wait(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				watchStatus("stopped watching, the environment is still running")
+				return nil
+			}
+			return errors.Wrap(err, "failed to watch the files")
+		}
+		watchStatus("detected changes in %s", relativePaths(buildOpt.BuildContextDir, changed))
+
+		start := time.Now()
+		next, fingerprint, change, err := reinterpret(clicontext, buildOpt, prev, changed)
+		if err != nil {
+			watchStatus("failed to interpret the manifest: %v", err)
+			continue
+		}
+		files = next.WatchedFiles()
+		if err := watcher.Watch(files); err != nil {
+			return err
+		}
+		if change == ir.GraphChangeNone {
+			watchStatus("the environment is not changed, skip rebuilding")
+			continue
+		}
+
+		if change == ir.GraphChangeRuntime {
+			applied, err := applyRuntimeGraph(clicontext, c, buildOpt, next)
+			if err != nil {
+				watchStatus("failed to apply the runtime graph: %v", err)
+				continue
+			}
+			if applied {
+				prev = fingerprint
+				watchStatus("the runtime graph is applied in %s", time.Since(start).Round(time.Millisecond))
+				continue
+			}
+			change = ir.GraphChangeBuild
+		}
+
+		watchStatus("%s changes detected, rebuilding the environment", change)
+		if err := next.Build(ctx, true); err != nil {
+			watchStatus("failed to build the environment: %v", err)
+			continue
+		}
+		if _, _, err := startEnvironment(clicontext, c, buildOpt, next, true); err != nil {
+			watchStatus("failed to recreate the environment: %v", err)
+			continue
+		}
+		prev = fingerprint
+		watchStatus("the environment is updated in %s", time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// applyRuntimeGraph applies the runtime graph to the running environment
+// without rebuilding the image. It returns false if the image needs to be
+// rebuilt, e.g. the environment is run by envd-server.
+func applyRuntimeGraph(clicontext *cli.Context, c *types.Context,
+	buildOpt builder.Options, b builder.Builder) (bool, error) {
+	if c.Runner == types.RunnerTypeEnvdServer {
+		return false, nil
+	}
+	engine, err := envd.New(clicontext.Context, envd.Options{Context: c})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create the docker client")
+	}
+	name := buildOpt.EnvironmentName
+	change, err := engine.ApplyRuntimeGraph(clicontext.Context, name,
+		b.GetGraph(), clicontext.Duration("timeout"))
+	if err != nil {
+		return false, err
+	}
+	return change != ir.GraphChangeBuild, nil
+}
+
+// reinterpret interprets the manifest again with a fresh graph, and returns
+// the new builder, the fingerprint of the new graph and the kind of changes.
+func reinterpret(clicontext *cli.Context, buildOpt builder.Options,
+	prev ir.GraphFingerprint, changed []string) (builder.Builder, ir.GraphFingerprint, ir.GraphChange, error) {
+	vc, err := version.New(buildOpt.ManifestFilePath)
+	if err != nil {
+		return nil, ir.GraphFingerprint{}, ir.GraphChangeNone, err
+	}
+	vc.ResetDefaultGraph()
+
+	b, err := buildutil.GetBuilder(clicontext, buildOpt)
+	if err != nil {
+		return nil, ir.GraphFingerprint{}, ir.GraphChangeNone, err
+	}
+	if err = buildutil.InterpretEnvdDef(b); err != nil {
+		return nil, ir.GraphFingerprint{}, ir.GraphChangeNone, err
+	}
+	if !b.GetGraph().IsDev() {
+		return nil, ir.GraphFingerprint{}, ir.GraphChangeNone, errors.New("`envd up` only works for dev images")
+	}
+	next, err := ir.NewGraphFingerprint(b.GetGraph())
+	if err != nil {
+		return nil, ir.GraphFingerprint{}, ir.GraphChangeNone, err
+	}
+	change := prev.Diff(next)
+	// The content of the dependency files (e.g. requirements.txt) is not
+	// recorded in the graph.
+	for _, f := range changed {
+		if filepath.Ext(f) != ".envd" {
+			change = ir.GraphChangeBuild
+			break
+		}
+	}
+	return b, next, change, nil
+}
+
+func watchStatus(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	logrus.Debug(msg)
+	fmt.Fprintf(os.Stderr, "[envd watch %s] %s\n", time.Now().Format("15:04:05"), msg)
+}
+
+func relativePaths(base string, files []string) string {
+	res := make([]string, 0, len(files))
+	for _, f := range files {
+		if rel, err := filepath.Rel(base, f); err == nil && !strings.HasPrefix(rel, "..") {
+			f = rel
+		}
+		res = append(res, f)
+	}
+	return strings.Join(res, ", ")
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/cockroachdb/errors"
@@ -26,6 +27,7 @@ import (
 	"github.com/moby/buildkit/client/llb"
 	"github.com/moby/buildkit/session"
 	"github.com/moby/buildkit/session/auth/authprovider"
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
 	ocispecs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -36,9 +38,11 @@ import (
 	"github.com/tensorchord/envd/pkg/home"
 	"github.com/tensorchord/envd/pkg/lang/ir"
 	"github.com/tensorchord/envd/pkg/lang/version"
+	"github.com/tensorchord/envd/pkg/progress/progressui"
 	"github.com/tensorchord/envd/pkg/progress/progresswriter"
 	"github.com/tensorchord/envd/pkg/types"
 	"github.com/tensorchord/envd/pkg/util/buildkitutil"
+	"github.com/tensorchord/envd/pkg/util/fileutil"
 )
 
 func New(ctx context.Context, opt Options) (Builder, error) {
@@ -111,6 +115,34 @@ func (b generalBuilder) GetGraph() ir.Graph {
 	return b.graph
 }
 
+func (b generalBuilder) WatchedFiles() []string {
+	files := []string{b.ManifestFilePath}
+	if b.ConfigFilePath != "" {
+		files = append(files, b.ConfigFilePath)
+	}
+	files = append(files, b.LoadedModules()...)
+	for _, dep := range b.GetDepsFilesHandler(nil) {
+		if !filepath.IsAbs(dep) {
+			dep = filepath.Join(b.BuildContextDir, dep)
+		}
+		if ok, err := fileutil.FileExists(dep); err != nil || !ok {
+			continue
+		}
+		files = append(files, dep)
+	}
+
+	seen := make(map[string]struct{}, len(files))
+	res := make([]string, 0, len(files))
+	for _, f := range files {
+		if _, ok := seen[f]; ok {
+			continue
+		}
+		seen[f] = struct{}{}
+		res = append(res, f)
+	}
+	return res
+}
+
 // GPUEnabled returns true if cuda is enabled.
 func (b generalBuilder) GPUEnabled() bool {
 	return b.graph.GPUEnabled()
@@ -130,7 +162,8 @@ func (b generalBuilder) IsDev() bool {
 }
 
 func (b generalBuilder) Build(ctx context.Context, force bool) error {
-	if !force && !b.checkIfNeedBuild(ctx) {
+	if !force && !b.NeedBuild(ctx) {
+		b.logger.Infof("manifest is not updated, skip building")
 		return nil
 	}
 
@@ -140,14 +173,53 @@ func (b generalBuilder) Build(ctx context.Context, force bool) error {
 	}
 	b.definition = def
 
-	pw, err := progresswriter.NewPrinter(ctx, os.Stdout, b.ProgressMode)
+	var out io.Writer = os.Stdout
+	if b.ProgressOutput != nil {
+		out = b.ProgressOutput
+	}
+	pw, err := progresswriter.NewPrinter(ctx, out, b.ProgressMode)
 	if err != nil {
 		return errors.Wrap(err, "failed to create progress writer")
 	}
 
-	if err = b.build(ctx, pw); err != nil {
-		return errors.Wrap(err, "failed to build")
+	if b.ProfilePath == "" {
+		if err = b.build(ctx, pw); err != nil {
+			return errors.Wrap(err, "failed to build")
+		}
+		return nil
+	}
+
+	profiler := progressui.NewProfiler()
+	profileCh := make(chan *client.SolveStatus)
+	profileDone := make(chan struct{})
+	go func() {
+		profiler.Consume(profileCh)
+		close(profileDone)
+	}()
+	buildErr := b.build(ctx, progresswriter.Tee(pw, profileCh))
+	// The profile channel is closed on every path, wait for the profiler to
+	// consume all the status updates before writing it.
+	<-profileDone
+	// Write the profile even if the build fails, it helps to find the failed step.
+	if err := b.writeProfile(profiler); err != nil {
+		b.logger.WithError(err).Error("failed to write the build profile")
+	}
+	if buildErr != nil {
+		return errors.Wrap(buildErr, "failed to build")
+	}
+	return nil
+}
+
+func (b generalBuilder) writeProfile(profiler *progressui.Profiler) error {
+	f, err := os.Create(b.ProfilePath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create the profile file %s", b.ProfilePath)
 	}
+	defer f.Close()
+	if err := profiler.WriteChromeTrace(f); err != nil {
+		return errors.Wrap(err, "failed to write the chrome trace")
+	}
+	b.logger.Debugf("build profile is written to %s", b.ProfilePath)
 	return nil
 }
 
@@ -226,6 +298,9 @@ func (b generalBuilder) build(ctx context.Context, pw progresswriter.Writer) err
 	b.logger.Debug("building envd image")
 	ce, err := ParseExportCache([]string{b.ExportCache}, nil)
 	if err != nil {
+		// The status channel is closed by buildkit after solving, close it here
+		// since nothing is solved, otherwise the progress writer never finishes.
+		close(pw.Status())
 		return errors.Wrap(err, "failed to parse export cache")
 	}
 	ctx, cancel := context.WithCancel(ctx)
@@ -246,6 +321,16 @@ func (b generalBuilder) build(ctx context.Context, pw progresswriter.Writer) err
 				},
 			),
 		}
+		// Forward the ssh agent only if the graph clones the private git
+		// repositories with ssh, e.g. dotfiles.
+		if b.graph != nil && b.graph.SSHAgentRequired() && os.Getenv("SSH_AUTH_SOCK") != "" {
+			agent, err := sshprovider.NewSSHAgentProvider([]sshprovider.AgentConfig{{}})
+			if err != nil {
+				b.logger.WithError(err).Warn("failed to forward the ssh agent")
+			} else {
+				attachable = append(attachable, agent)
+			}
+		}
 		b.logger.WithFields(logrus.Fields{
 			"type": entry.Type,
 		}).Debug("build image with buildkit")
@@ -52,8 +52,17 @@ func (b generalBuilder) BuildFunc() func(ctx context.Context, c client.Client) (
 			sreq.CacheImports = append(sreq.CacheImports, ci...)
 		}
 
+		// Evaluate the definition in the build func so that the failed
+		// step can be debugged with the gateway client.
+		if b.OnFailure == OnFailureShell {
+			sreq.Evaluate = true
+		}
+
 		res, err := c.Solve(ctx, sreq)
 		if err != nil {
+			if b.OnFailure == OnFailureShell {
+				b.debugOnFailure(ctx, c, err)
+			}
 			return nil, errors.Wrap(err, "failed to solve")
 		}
 
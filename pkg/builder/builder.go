@@ -24,6 +24,9 @@ import (
 
 type Builder interface {
 	Build(ctx context.Context, force bool) error
+	// NeedBuild returns true if the manifest or the dependency files are
+	// updated after the image is built. It should be called after Interpret.
+	NeedBuild(ctx context.Context) bool
 	Interpret() error
 	// Compile compiles envd IR to LLB.
 	Compile(ctx context.Context) (*llb.Definition, error)
@@ -31,4 +34,8 @@ type Builder interface {
 	NumGPUs() int
 	ShmSize() int
 	GetGraph() ir.Graph
+	// WatchedFiles returns the manifest, config, loaded modules and the
+	// dependency files, which trigger a rebuild when changed.
+	// It should be called after Interpret.
+	WatchedFiles() []string
 }
@@ -21,6 +21,7 @@ import (
 	"github.com/cockroachdb/errors"
 	"github.com/golang/mock/gomock"
 	"github.com/moby/buildkit/client"
+	gatewayclient "github.com/moby/buildkit/frontend/gateway/client"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sirupsen/logrus"
@@ -95,6 +96,34 @@ var _ = Describe("Builder", func() {
 				})
 			})
 
+			When("the graph is not interpreted", func() {
+				It("should not forward the ssh agent", func() {
+					DeferCleanup(os.Setenv, "SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+					Expect(os.Setenv("SSH_AUTH_SOCK", "/tmp/envd-test-agent.sock")).To(Succeed())
+					b.entries = []client.ExportEntry{
+						{
+							Type: client.ExporterDocker,
+						},
+					}
+
+					var attachable int
+					b.Client.(*mockbuildkitd.MockClient).EXPECT().Build(gomock.Any(),
+						gomock.Any(), gomock.Eq("envd"), gomock.Any(), gomock.Any()).
+						DoAndReturn(func(_ context.Context, opt client.SolveOpt, _ string,
+							_ gatewayclient.BuildFunc, _ chan *client.SolveStatus) (*client.SolveResponse, error) {
+							attachable = len(opt.Session)
+							return nil, errors.New("build error")
+						})
+
+					pw, err := progresswriter.NewPrinter(context.TODO(), os.Stdout, b.ProgressMode)
+					Expect(err).NotTo(HaveOccurred())
+
+					close(pw.Status())
+					Expect(b.build(context.TODO(), pw)).To(HaveOccurred())
+					Expect(attachable).To(Equal(1))
+				})
+			})
+
 			It("should build successfully", func() {
 				err := home.Initialize()
 				Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,190 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/moby/buildkit/frontend/gateway/client"
+	"github.com/moby/buildkit/solver/errdefs"
+	"github.com/moby/buildkit/solver/pb"
+	"golang.org/x/term"
+)
+
+const (
+	// OnFailureError returns the build error directly.
+	OnFailureError = "error"
+	// OnFailureShell launches an interactive shell in the failed step.
+	OnFailureShell = "shell"
+)
+
+// debugShell is the shell launched in the container of the failed step.
+var debugShell = []string{"/bin/sh", "-c", "if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi"}
+
+// ValidateOnFailure checks the value of `--on-failure`.
+func ValidateOnFailure(onFailure string) error {
+	switch onFailure {
+	case "", OnFailureError, OnFailureShell:
+		return nil
+	default:
+		return errors.Newf("invalid on-failure value %s, expected %s or %s",
+			onFailure, OnFailureError, OnFailureShell)
+	}
+}
+
+// failedExec extracts the failed exec op from the solve error.
+// It returns false if the error is not caused by an exec op, e.g. an image pull failure.
+func failedExec(err error) (*errdefs.SolveError, *pb.ExecOp, bool) {
+	var se *errdefs.SolveError
+	if !errors.As(err, &se) || se.Solve == nil || se.Solve.Op == nil {
+		return nil, nil, false
+	}
+	exec, ok := se.Solve.Op.Op.(*pb.Op_Exec)
+	if !ok || exec.Exec == nil || exec.Exec.Meta == nil {
+		return nil, nil, false
+	}
+	return se, exec.Exec, true
+}
+
+// debugMounts converts the mounts of the failed exec op to the container mounts.
+// The input states of the op are used, thus the filesystem is the one
+// before the failed command is executed.
+func debugMounts(exec *pb.ExecOp, inputIDs []string) ([]client.Mount, error) {
+	mounts := make([]client.Mount, 0, len(exec.Mounts))
+	for _, m := range exec.Mounts {
+		mount := client.Mount{
+			Dest:      m.Dest,
+			Selector:  m.Selector,
+			Readonly:  m.Readonly,
+			MountType: m.MountType,
+			CacheOpt:  m.CacheOpt,
+			SecretOpt: m.SecretOpt,
+			SSHOpt:    m.SSHOpt,
+		}
+		if m.Input >= 0 {
+			if int(m.Input) >= len(inputIDs) {
+				return nil, errors.Newf("failed to find the input %d of mount %s", m.Input, m.Dest)
+			}
+			mount.ResultID = inputIDs[m.Input]
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+// debugOnFailure launches an interactive container on the input state of the
+// failed step, with the same mounts and environment variables.
+func (b generalBuilder) debugOnFailure(ctx context.Context, c client.Client, solveErr error) {
+	se, exec, ok := failedExec(solveErr)
+	if !ok {
+		b.logger.Debug("the failed step is not a command, skip the debug shell")
+		return
+	}
+	if err := b.runDebugShell(ctx, c, se, exec); err != nil {
+		b.logger.WithError(err).Error("failed to launch the debug shell")
+	}
+}
+
+func (b generalBuilder) runDebugShell(ctx context.Context, c client.Client,
+	se *errdefs.SolveError, exec *pb.ExecOp) error {
+	mounts, err := debugMounts(exec, se.Solve.InputIDs)
+	if err != nil {
+		return err
+	}
+	ctr, err := c.NewContainer(ctx, client.NewContainerRequest{
+		Mounts:     mounts,
+		NetMode:    exec.Network,
+		Platform:   se.Solve.Op.Platform,
+		ExtraHosts: exec.Meta.ExtraHosts,
+		Hostname:   exec.Meta.Hostname,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create the debug container")
+	}
+	defer func() {
+		if err := ctr.Release(context.Background()); err != nil {
+			b.logger.WithError(err).Debug("failed to release the debug container")
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "\nBuild step failed: %s\n", strings.Join(exec.Meta.Args, " "))
+	fmt.Fprintf(os.Stderr, "Launching a shell in the failed step (cwd: %s), the failed command is stored in $ENVD_FAILED_COMMAND.\n", exec.Meta.Cwd)
+	fmt.Fprintf(os.Stderr, "Retry it with `eval \"$ENVD_FAILED_COMMAND\"`, exit the shell to finish the build.\n\n")
+
+	tty := false
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		tty = true
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return errors.Wrap(err, "failed to make the terminal raw")
+		}
+		defer func() {
+			if err := term.Restore(fd, state); err != nil {
+				b.logger.WithError(err).Debug("failed to restore the terminal")
+			}
+		}()
+	}
+
+	env := append([]string{}, exec.Meta.Env...)
+	env = append(env, fmt.Sprintf("ENVD_FAILED_COMMAND=%s", shellJoin(exec.Meta.Args)))
+	proc, err := ctr.Start(ctx, client.StartRequest{
+		Args:         debugShell,
+		Env:          env,
+		User:         exec.Meta.User,
+		Cwd:          exec.Meta.Cwd,
+		Tty:          tty,
+		Stdin:        io.NopCloser(os.Stdin),
+		Stdout:       nopWriteCloser{os.Stdout},
+		Stderr:       nopWriteCloser{os.Stderr},
+		SecurityMode: exec.Security,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to start the debug shell")
+	}
+	if tty {
+		if width, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			if err := proc.Resize(ctx, client.WinSize{Rows: uint32(height), Cols: uint32(width)}); err != nil {
+				b.logger.WithError(err).Debug("failed to resize the debug shell")
+			}
+		}
+	}
+	if err := proc.Wait(); err != nil {
+		b.logger.WithError(err).Debug("debug shell exited with error")
+	}
+	return nil
+}
+
+// shellJoin quotes the args so that they can be evaluated by the shell.
+func shellJoin(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'"'"'`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/moby/buildkit/solver/errdefs"
+	"github.com/moby/buildkit/solver/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugMounts(t *testing.T) {
+	exec := &pb.ExecOp{
+		Meta: &pb.Meta{Args: []string{"pip", "install", "foo"}},
+		Mounts: []*pb.Mount{
+			{Input: 0, Dest: "/"},
+			{Input: 1, Dest: "/data", Selector: "/sub", Readonly: true},
+			{Input: -1, Dest: "/root/.cache/pip", MountType: pb.MountType_CACHE,
+				CacheOpt: &pb.CacheOpt{ID: "pip"}},
+		},
+	}
+	mounts, err := debugMounts(exec, []string{"root-id", "data-id"})
+	require.NoError(t, err)
+	require.Len(t, mounts, 3)
+	require.Equal(t, "root-id", mounts[0].ResultID)
+	require.Equal(t, "data-id", mounts[1].ResultID)
+	require.Equal(t, "/sub", mounts[1].Selector)
+	require.True(t, mounts[1].Readonly)
+	require.Empty(t, mounts[2].ResultID)
+	require.Equal(t, pb.MountType_CACHE, mounts[2].MountType)
+	require.Equal(t, "pip", mounts[2].CacheOpt.ID)
+
+	_, err = debugMounts(exec, []string{"root-id"})
+	require.Error(t, err)
+}
+
+func TestFailedExec(t *testing.T) {
+	exec := &pb.ExecOp{Meta: &pb.Meta{Args: []string{"false"}}}
+	err := errors.Wrap(&errdefs.SolveError{
+		Err: errors.New("exit code 1"),
+		Solve: &errdefs.Solve{
+			InputIDs: []string{"root-id"},
+			Op:       &pb.Op{Op: &pb.Op_Exec{Exec: exec}},
+		},
+	}, "failed to solve")
+	se, got, ok := failedExec(err)
+	require.True(t, ok)
+	require.Equal(t, exec, got)
+	require.Equal(t, []string{"root-id"}, se.Solve.InputIDs)
+
+	_, _, ok = failedExec(errors.New("failed to pull image"))
+	require.False(t, ok)
+}
+
+func TestShellJoin(t *testing.T) {
+	require.Equal(t, `'bash' '-c' 'echo '"'"'hi'"'"''`,
+		shellJoin([]string{"bash", "-c", "echo 'hi'"}))
+}
+
+func TestValidateOnFailure(t *testing.T) {
+	require.NoError(t, ValidateOnFailure(""))
+	require.NoError(t, ValidateOnFailure(OnFailureError))
+	require.NoError(t, ValidateOnFailure(OnFailureShell))
+	require.Error(t, ValidateOnFailure("retry"))
+}
@@ -21,7 +21,7 @@ import (
 	"github.com/tensorchord/envd/pkg/driver/docker"
 )
 
-func (b generalBuilder) checkIfNeedBuild(ctx context.Context) bool {
+func (b generalBuilder) NeedBuild(ctx context.Context) bool {
 	if b.graph.GetHTTP() != nil {
 		return true
 	}
@@ -33,11 +33,7 @@ func (b generalBuilder) checkIfNeedBuild(ctx context.Context) bool {
 	if err != nil {
 		b.logger.WithError(err).Debug("failed to check manifest update")
 	}
-	if !isUpdated {
-		b.logger.Infof("manifest is not updated, skip building")
-		return false
-	}
-	return true
+	return isUpdated
 }
 
 // nolint:unparam
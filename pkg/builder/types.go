@@ -15,6 +15,8 @@
 package builder
 
 import (
+	"io"
+
 	"github.com/moby/buildkit/client"
 	"github.com/moby/buildkit/client/llb"
 	"github.com/sirupsen/logrus"
@@ -29,7 +31,7 @@ type Options struct {
 	ManifestFilePath string
 	// ConfigFilePath is the path to the config file `config.envd`.
 	ConfigFilePath string
-	// ProgressMode is the output mode (auto, plain).
+	// ProgressMode is the output mode (auto, plain, json).
 	ProgressMode string
 	// Tag is the name of the image.
 	Tag string
@@ -37,6 +39,9 @@ type Options struct {
 	BuildContextDir string
 	// BuildFuncName is the name of the build func.
 	BuildFuncName string
+	// EnvironmentName is the name of the environment, which selects the
+	// per-environment key pair.
+	EnvironmentName string
 	// PubKeyPath is the path to the ssh public key.
 	PubKeyPath string
 	// OutputOpts is the output options.
@@ -52,6 +57,12 @@ type Options struct {
 	// Specify the target platform for the build output.
 	// e.g. platform=linux/arm64,linux/amd64
 	Platform string
+	// OnFailure is the action when a build step fails (error, shell).
+	OnFailure string
+	// ProfilePath is the path to write the build profile in Chrome trace format.
+	ProfilePath string
+	// ProgressOutput is where the progress is written, os.Stdout by default.
+	ProgressOutput io.Writer
 }
 
 type generalBuilder struct {
@@ -19,8 +19,21 @@ type UpState string
 const (
 	PrivateKeyFile               = "id_rsa_envd"
 	PublicKeyFile                = "id_rsa_envd.pub"
+	ED25519PrivateKeyFile        = "id_ed25519_envd"
+	ED25519PublicKeyFile         = "id_ed25519_envd.pub"
+	EnvKeysDir                   = "keys"
+	KnownHostsFile               = "known_hosts"
+	HostKeysDir                  = "hostkeys"
 	ContainerAuthorizedKeysPath  = "/var/envd/authorized_keys"
+	ContainerHostKeyPath         = "/var/envd/ssh_host_key"
+	ContainerSessionsDir         = "/var/envd/sessions"
+	ContainerAuditLogPath        = "/var/envd/sessions/audit.jsonl"
+	ContainerRuntimeGraphPath    = "/var/envd/runtime_graph.json"
 	SSHPortInContainer           = 2222
 	JupyterPortInContainer       = 8888
 	RStudioServerPortInContainer = 8787
+	WebTerminalPortInContainer   = 2223
+	WebTerminalService           = "web-terminal"
+	VSCodeServerPortInContainer  = 2224
+	VSCodeServerService          = "vscode-server"
 )
@@ -0,0 +1,138 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+
+	buildutil "github.com/tensorchord/envd/pkg/app/build"
+	"github.com/tensorchord/envd/pkg/builder"
+	"github.com/tensorchord/envd/pkg/lang/version"
+	progressmode "github.com/tensorchord/envd/pkg/progress/mode"
+	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
+)
+
+const defaultBuildFunc = "build.envd:build"
+
+// build builds the environment and streams the progress events in the json
+// progress mode, one event per line. The last line is the BuildResult.
+func (s *Server) build(w http.ResponseWriter, r *http.Request) {
+	var req BuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Wrap(err, "failed to decode the build request"))
+		return
+	}
+	opt, err := newBuildOptions(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.buildMu.Lock()
+	defer s.buildMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	out := &flushWriter{w: w, rc: http.NewResponseController(w)}
+	opt.ProgressOutput = out
+
+	result := BuildResult{Type: buildResultType, Tag: opt.Tag}
+	if err := runBuild(r.Context(), opt, req.Force); err != nil {
+		s.logger.WithError(err).Debug("failed to build the environment")
+		result.Error = err.Error()
+	}
+	if err := json.NewEncoder(out).Encode(result); err != nil {
+		s.logger.WithError(err).Debug("failed to write the build result")
+	}
+}
+
+func newBuildOptions(req BuildRequest) (builder.Options, error) {
+	if !filepath.IsAbs(req.Path) {
+		return builder.Options{}, errors.Newf("the build context should be an absolute path: %q", req.Path)
+	}
+	from := req.From
+	if from == "" {
+		from = defaultBuildFunc
+	}
+	opt, err := buildutil.ResolveBuildContext(req.Path, from, req.Tag)
+	if err != nil {
+		return builder.Options{}, err
+	}
+	opt.EnvironmentName = req.Name
+	if opt.EnvironmentName == "" {
+		opt.EnvironmentName, err = buildutil.CreateEnvNameFromDir(opt.BuildContextDir)
+		if err != nil {
+			return builder.Options{}, errors.Wrapf(err, "failed to create the env name from %s", opt.BuildContextDir)
+		}
+	}
+	pub, _, err := sshconfig.GetEnvKeyPair(opt.EnvironmentName)
+	if err != nil {
+		return builder.Options{}, errors.Wrap(err, "failed to get the public key")
+	}
+	opt.PubKeyPath = pub
+	opt.OutputOpts = req.Output
+	opt.ExportCache = req.ExportCache
+	opt.ImportCache = req.ImportCache
+	opt.UseHTTPProxy = req.UseProxy
+	opt.Platform = req.Platform
+	opt.ProgressMode = progressmode.JSON
+	return opt, nil
+}
+
+func runBuild(ctx context.Context, opt builder.Options, force bool) error {
+	// The graph is a global singleton, reset it to interpret the manifest again.
+	vc, err := version.New(opt.ManifestFilePath)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the language version")
+	}
+	vc.ResetDefaultGraph()
+
+	b, err := builder.New(ctx, opt)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the builder")
+	}
+	if err := b.Interpret(); err != nil {
+		return errors.Wrap(err, "failed to interpret")
+	}
+	if err := b.Build(ctx, force); err != nil {
+		return errors.Wrap(err, "failed to build the image")
+	}
+	return nil
+}
+
+// flushWriter flushes every write, so that the client gets the progress
+// events in time. The progress printer writes in its own goroutine.
+type flushWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+	rc *http.ResponseController
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, f.rc.Flush()
+}
@@ -0,0 +1,139 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+
+	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
+	"github.com/tensorchord/envd/pkg/version"
+)
+
+func (s *Server) getVersion(w http.ResponseWriter, r *http.Request) {
+	v := version.GetVersion()
+	writeJSON(w, http.StatusOK, VersionResponse{
+		Version:   v.Version,
+		GitCommit: v.GitCommit,
+		BuildDate: v.BuildDate,
+		Platform:  v.Platform,
+	})
+}
+
+func (s *Server) listEnvironments(w http.ResponseWriter, r *http.Request) {
+	engine, err := s.newEngine(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	envs, err := engine.ListEnvironment(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to list the environments"))
+		return
+	}
+	writeJSON(w, http.StatusOK, envs)
+}
+
+func (s *Server) describeEnvironment(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	engine, err := s.newEngine(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	dep, err := engine.ListEnvDependency(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to list the dependencies"))
+		return
+	}
+	ports, err := engine.ListEnvPortBinding(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to list the port bindings"))
+		return
+	}
+	rg, err := engine.ListEnvRuntimeGraph(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to list the runtime graph"))
+		return
+	}
+	desc := EnvironmentDescription{
+		Name:         name,
+		Dependencies: dep,
+		Runtime:      rg,
+	}
+	for _, port := range ports {
+		desc.Ports = append(desc.Ports, PortBinding{
+			Name:          port.Name,
+			ContainerPort: port.Port,
+			Protocol:      port.Protocol,
+			HostIP:        port.HostIP,
+			HostPort:      port.HostPort,
+		})
+	}
+	writeJSON(w, http.StatusOK, desc)
+}
+
+func (s *Server) pauseEnvironment(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	engine, err := s.newEngine(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	paused, err := engine.PauseEnvironment(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to pause the environment"))
+		return
+	}
+	writeJSON(w, http.StatusOK, NameResponse{Name: paused})
+}
+
+func (s *Server) resumeEnvironment(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	engine, err := s.newEngine(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resumed, err := engine.ResumeEnvironment(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to resume the environment"))
+		return
+	}
+	writeJSON(w, http.StatusOK, NameResponse{Name: resumed})
+}
+
+func (s *Server) destroyEnvironment(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	engine, err := s.newEngine(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	destroyed, err := engine.Destroy(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to destroy the environment"))
+		return
+	}
+	if destroyed == "" {
+		writeError(w, http.StatusNotFound, errors.Newf("cannot find the environment %s", name))
+		return
+	}
+	if err := sshconfig.RemoveEntry(destroyed); err != nil {
+		s.logger.WithError(err).Infof("failed to remove entry %s from your SSH config file", destroyed)
+	}
+	writeJSON(w, http.StatusOK, NameResponse{Name: destroyed})
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"net/http"
+
+	"github.com/cockroachdb/errors"
+)
+
+func (s *Server) listImages(w http.ResponseWriter, r *http.Request) {
+	engine, err := s.newEngine(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	images, err := engine.ListImage(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to list the images"))
+		return
+	}
+	writeJSON(w, http.StatusOK, images)
+}
+
+func (s *Server) pruneImages(w http.ResponseWriter, r *http.Request) {
+	engine, err := s.newEngine(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	report, err := engine.PruneImage(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, errors.Wrap(err, "failed to prune the images"))
+		return
+	}
+	res := PruneImagesResponse{SpaceReclaimed: report.SpaceReclaimed}
+	for _, img := range report.ImagesDeleted {
+		if img.Untagged != "" {
+			res.ImagesDeleted = append(res.ImagesDeleted, img.Untagged)
+		} else {
+			res.ImagesDeleted = append(res.ImagesDeleted, img.Deleted)
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,33 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPISchema is the OpenAPI 3 schema of the API, keep it in sync with the
+// routes.
+//
+//go:embed openapi.json
+var OpenAPISchema []byte
+
+func (s *Server) getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(OpenAPISchema); err != nil {
+		s.logger.WithError(err).Debug("failed to write the openapi schema")
+	}
+}
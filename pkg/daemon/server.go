@@ -0,0 +1,206 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package daemon implements the local envd API server, which exposes the
+// envd engine over HTTP+JSON on a unix socket. There is no authentication,
+// the access is controlled by the permission of the socket.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tensorchord/envd/pkg/envd"
+	"github.com/tensorchord/envd/pkg/home"
+	"github.com/tensorchord/envd/pkg/util/fileutil"
+)
+
+const (
+	// SocketFile is the default socket file name in the envd cache directory.
+	SocketFile = "envd.sock"
+	// APIPrefix is the prefix of all the API routes.
+	APIPrefix = "/v1"
+)
+
+// EngineFactory creates the envd engine for every request, thus the changes
+// of the current context take effect without restarting the daemon.
+type EngineFactory func(ctx context.Context) (envd.Engine, error)
+
+type Server struct {
+	newEngine EngineFactory
+	// buildMu serializes the builds since the graph of the manifest is a
+	// global singleton.
+	buildMu sync.Mutex
+	logger  *logrus.Entry
+}
+
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+}
+
+func NewServer(newEngine EngineFactory) *Server {
+	if newEngine == nil {
+		newEngine = defaultEngine
+	}
+	return &Server{
+		newEngine: newEngine,
+		logger:    logrus.WithField("component", "daemon"),
+	}
+}
+
+func defaultEngine(ctx context.Context) (envd.Engine, error) {
+	c, err := home.GetManager().ContextGetCurrent()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the current context")
+	}
+	return envd.New(ctx, envd.Options{Context: c})
+}
+
+// DefaultSocketPath returns the socket path in the envd cache directory.
+func DefaultSocketPath() (string, error) {
+	return fileutil.CacheFile(SocketFile)
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		{http.MethodGet, "/version", s.getVersion},
+		{http.MethodGet, "/openapi.json", s.getOpenAPI},
+		{http.MethodGet, "/environments", s.listEnvironments},
+		{http.MethodGet, "/environments/{name}", s.describeEnvironment},
+		{http.MethodDelete, "/environments/{name}", s.destroyEnvironment},
+		{http.MethodPost, "/environments/{name}/pause", s.pauseEnvironment},
+		{http.MethodPost, "/environments/{name}/resume", s.resumeEnvironment},
+		{http.MethodGet, "/images", s.listImages},
+		{http.MethodPost, "/images/prune", s.pruneImages},
+		{http.MethodPost, "/builds", s.build},
+	}
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, r := range s.routes() {
+		mux.HandleFunc(r.method+" "+APIPrefix+r.path, r.handler)
+	}
+	return s.logRequest(mux)
+}
+
+// Serve listens on the unix socket and serves the API until the context is
+// done. The socket is only accessible by the current user.
+func (s *Server) Serve(ctx context.Context, socketPath string) error {
+	l, err := listenUnix(socketPath)
+	if err != nil {
+		return err
+	}
+	// The listener only removes the socket at the path it is created.
+	defer os.Remove(socketPath)
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			s.logger.WithError(err).Debug("failed to shutdown the server")
+		}
+	}()
+	s.logger.Infof("envd daemon is listening on %s", socketPath)
+	if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errors.Wrap(err, "failed to serve the API")
+	}
+	return nil
+}
+
+func listenUnix(socketPath string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+		return nil, errors.Wrapf(err, "failed to create the directory of %s", socketPath)
+	}
+	// Remove the stale socket left by the previous daemon. The other files
+	// are kept since the path may be mistyped.
+	if fi, err := os.Lstat(socketPath); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, errors.Newf("%s exists and is not a socket", socketPath)
+		}
+		if conn, err := net.Dial("unix", socketPath); err == nil {
+			conn.Close()
+			return nil, errors.Newf("envd daemon is already listening on %s", socketPath)
+		}
+		if err := os.Remove(socketPath); err != nil {
+			return nil, errors.Wrapf(err, "failed to remove the stale socket %s", socketPath)
+		}
+	}
+	// The socket is created in a private directory and then moved to the
+	// path, thus the other users can not connect to it before the chmod.
+	dir, err := os.MkdirTemp(filepath.Dir(socketPath), ".envd-daemon-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create the private directory of %s", socketPath)
+	}
+	defer os.RemoveAll(dir)
+	tmpPath := filepath.Join(dir, SocketFile)
+	l, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen on %s", socketPath)
+	}
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		l.Close()
+		return nil, errors.Wrapf(err, "failed to change the permission of %s", socketPath)
+	}
+	if err := os.Rename(tmpPath, socketPath); err != nil {
+		l.Close()
+		return nil, errors.Wrapf(err, "failed to move the socket to %s", socketPath)
+	}
+	return l, nil
+}
+
+func (s *Server) logRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		s.logger.WithFields(logrus.Fields{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"duration": time.Since(start),
+		}).Debug("request served")
+	})
+}
+
+// ErrorResponse is the body of the failed requests.
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithError(err).Debug("failed to write the response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Message: err.Error()})
+}
@@ -0,0 +1,211 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	dockerimage "github.com/docker/docker/api/types/image"
+	"github.com/stretchr/testify/require"
+	servertypes "github.com/tensorchord/envd-server/api/types"
+
+	"github.com/tensorchord/envd/pkg/envd"
+	"github.com/tensorchord/envd/pkg/lang/ir"
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+// fakeEngine implements the methods used by the server, the others panic.
+type fakeEngine struct {
+	envd.Engine
+	paused []string
+}
+
+func (e *fakeEngine) ListEnvironment(ctx context.Context) ([]types.EnvdEnvironment, error) {
+	env := types.EnvdEnvironment{}
+	env.Name = "mnist"
+	env.Spec.Image = "mnist:dev"
+	return []types.EnvdEnvironment{env}, nil
+}
+
+func (e *fakeEngine) ListEnvDependency(ctx context.Context, env string) (*types.Dependency, error) {
+	return &types.Dependency{PyPIPackages: []string{"numpy"}}, nil
+}
+
+func (e *fakeEngine) ListEnvPortBinding(ctx context.Context, env string) ([]types.PortBinding, error) {
+	return []types.PortBinding{{Name: "ssh", Port: "2222", Protocol: "tcp", HostIP: "127.0.0.1", HostPort: "41234"}}, nil
+}
+
+func (e *fakeEngine) ListEnvRuntimeGraph(ctx context.Context, env string) (*ir.RuntimeGraph, error) {
+	return &ir.RuntimeGraph{RuntimeDaemon: [][]string{{"python3", "-m", "http.server"}}}, nil
+}
+
+func (e *fakeEngine) PauseEnvironment(ctx context.Context, env string) (string, error) {
+	if env != "mnist" {
+		return "", errors.Newf("cannot find %s", env)
+	}
+	e.paused = append(e.paused, env)
+	return env, nil
+}
+
+func (e *fakeEngine) PruneImage(ctx context.Context) (dockerimage.PruneReport, error) {
+	return dockerimage.PruneReport{
+		ImagesDeleted:  []dockerimage.DeleteResponse{{Untagged: "mnist:dev"}, {Deleted: "sha256:abc"}},
+		SpaceReclaimed: 42,
+	}, nil
+}
+
+func (e *fakeEngine) ListImage(ctx context.Context) ([]types.EnvdImage, error) {
+	return []types.EnvdImage{{ImageMeta: servertypes.ImageMeta{Name: "mnist:dev"}}}, nil
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *fakeEngine) {
+	engine := &fakeEngine{}
+	s := NewServer(func(ctx context.Context) (envd.Engine, error) {
+		return engine, nil
+	})
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return ts, engine
+}
+
+func TestEnvironments(t *testing.T) {
+	ts, engine := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/v1/environments")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var envs []types.EnvdEnvironment
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&envs))
+	require.Len(t, envs, 1)
+	require.Equal(t, "mnist", envs[0].Name)
+
+	resp, err = http.Get(ts.URL + "/v1/environments/mnist")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var desc EnvironmentDescription
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&desc))
+	require.Equal(t, []string{"numpy"}, desc.Dependencies.PyPIPackages)
+	require.Equal(t, "41234", desc.Ports[0].HostPort)
+	require.Equal(t, [][]string{{"python3", "-m", "http.server"}}, desc.Runtime.RuntimeDaemon)
+
+	resp, err = http.Post(ts.URL+"/v1/environments/mnist/pause", "", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"mnist"}, engine.paused)
+
+	resp, err = http.Post(ts.URL+"/v1/environments/unknown/pause", "", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	require.Contains(t, errResp.Message, "cannot find unknown")
+
+	resp, err = http.Get(ts.URL + "/v1/environments/mnist/pause")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestImages(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	resp, err := http.Post(ts.URL+"/v1/images/prune", "", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var report PruneImagesResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	require.Equal(t, []string{"mnist:dev", "sha256:abc"}, report.ImagesDeleted)
+	require.Equal(t, uint64(42), report.SpaceReclaimed)
+}
+
+func TestBuildBadRequest(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	resp, err := http.Post(ts.URL+"/v1/builds", "application/json", strings.NewReader(`{"path": "relative"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var schema struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(OpenAPISchema, &schema))
+	for _, r := range NewServer(nil).routes() {
+		methods, ok := schema.Paths[r.path]
+		require.Truef(t, ok, "path %s is not in the openapi schema", r.path)
+		_, ok = methods[strings.ToLower(r.method)]
+		require.Truef(t, ok, "%s %s is not in the openapi schema", r.method, r.path)
+	}
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "envd.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(nil).Serve(ctx, socket)
+	}()
+
+	client := http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", socket)
+		},
+	}}
+	require.Eventually(t, func() bool {
+		resp, err := client.Get("http://localhost/v1/version")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+	info, err := os.Stat(socket)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// The second daemon should not take over the socket.
+	require.Error(t, NewServer(nil).Serve(context.Background(), socket))
+
+	cancel()
+	require.NoError(t, <-done)
+	entries, err := os.ReadDir(filepath.Dir(socket))
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestServeKeepsRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("keep me"), 0644))
+
+	require.Error(t, NewServer(nil).Serve(context.Background(), path))
+	dat, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "keep me", string(dat))
+}
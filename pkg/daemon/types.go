@@ -0,0 +1,84 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package daemon
+
+import (
+	"github.com/tensorchord/envd/pkg/lang/ir"
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildDate string `json:"build_date"`
+	Platform  string `json:"platform"`
+}
+
+// NameResponse is the response of the operations on an environment.
+type NameResponse struct {
+	Name string `json:"name"`
+}
+
+type PortBinding struct {
+	Name          string `json:"name"`
+	ContainerPort string `json:"container_port"`
+	Protocol      string `json:"protocol"`
+	HostIP        string `json:"host_ip"`
+	HostPort      string `json:"host_port"`
+}
+
+type EnvironmentDescription struct {
+	Name         string            `json:"name"`
+	Dependencies *types.Dependency `json:"dependencies,omitempty"`
+	Ports        []PortBinding     `json:"ports,omitempty"`
+	Runtime      *ir.RuntimeGraph  `json:"runtime,omitempty"`
+}
+
+type PruneImagesResponse struct {
+	ImagesDeleted  []string `json:"images_deleted,omitempty"`
+	SpaceReclaimed uint64   `json:"space_reclaimed"`
+}
+
+// BuildRequest is the request to build the environment in the build context.
+// The fields follow the flags of `envd build`.
+type BuildRequest struct {
+	// Path is the absolute path of the build context.
+	Path string `json:"path"`
+	// From is the function to execute, in the `file:func` format.
+	From string `json:"from,omitempty"`
+	// Tag is the name and optionally a tag in the `name:tag` format.
+	Tag string `json:"tag,omitempty"`
+	// Name is the name of the environment, which selects the per-environment
+	// key pair. It is created from the build context directory by default.
+	Name string `json:"name,omitempty"`
+	// Output is the output options, e.g. `type=image,name=<image>,push=true`.
+	Output string `json:"output,omitempty"`
+	// Force rebuilds the image even if the manifest is not changed.
+	Force       bool   `json:"force,omitempty"`
+	UseProxy    bool   `json:"use_proxy,omitempty"`
+	Platform    string `json:"platform,omitempty"`
+	ExportCache string `json:"export_cache,omitempty"`
+	ImportCache string `json:"import_cache,omitempty"`
+}
+
+// BuildResult is the last line of the build stream.
+type BuildResult struct {
+	// Type is always `result`, to tell it from the progress events.
+	Type  string `json:"type"`
+	Tag   string `json:"tag,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+const buildResultType = "result"
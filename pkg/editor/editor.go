@@ -0,0 +1,76 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package editor installs the editors into the environment by `install.editor`.
+// VS Code is not one of them since it is supported by `install.vscode_extensions`.
+package editor
+
+import (
+	"slices"
+
+	"github.com/cockroachdb/errors"
+
+	"github.com/tensorchord/envd/pkg/editor/jetbrains"
+	"github.com/tensorchord/envd/pkg/editor/neovim"
+)
+
+// Editor is installed into the image in two steps: the release is downloaded and
+// extracted into `InstallDir`, then `SetupScript` runs as the user of the environment.
+type Editor interface {
+	// Name of the editor, e.g. `jetbrains` or `neovim`.
+	Name() string
+	Version() string
+	// DownloadScript downloads and extracts the release into the directory,
+	// it runs in an image with `sh`, `wget` and `tar`.
+	DownloadScript(dir string) string
+	// InstallDir is the directory of the editor in the image.
+	InstallDir() string
+	// BinDir is added to PATH if it's not empty.
+	BinDir() string
+	// SetupScript configures the editor for the user, it's empty if not needed.
+	SetupScript() string
+	// ConnectionHint tells how to connect to the running environment by ssh
+	// from the editor, it's empty if the editor is used in the terminal.
+	ConnectionHint(host string, sshPort int, user, workDir string) string
+}
+
+type factory func(version string, options map[string]string) (Editor, error)
+
+var editors = map[string]factory{
+	jetbrains.Name: func(version string, options map[string]string) (Editor, error) {
+		return jetbrains.New(version, options)
+	},
+	neovim.Name: func(version string, options map[string]string) (Editor, error) {
+		return neovim.New(version, options)
+	},
+}
+
+// New returns the editor with the version and the editor specific options.
+func New(name, version string, options map[string]string) (Editor, error) {
+	f, ok := editors[name]
+	if !ok {
+		return nil, errors.Newf("editor %s is not supported, expect one of %v", name, Supported())
+	}
+	return f(version, options)
+}
+
+// Supported returns the names of the supported editors.
+func Supported() []string {
+	names := make([]string, 0, len(editors))
+	for name := range editors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tcs := []struct {
+		name    string
+		version string
+		options map[string]string
+		wantErr bool
+	}{
+		{name: "jetbrains", version: "2025.2.4", options: map[string]string{"product": "PY"}},
+		{name: "jetbrains", version: "", wantErr: true},
+		{name: "jetbrains", version: "2025.2.4", options: map[string]string{"product": "XX"}, wantErr: true},
+		{name: "jetbrains", version: "2025.2.4", options: map[string]string{"config": "repo"}, wantErr: true},
+		{name: "neovim"},
+		{name: "neovim", options: map[string]string{"sync": "PackerSync"}, wantErr: true},
+		{name: "emacs", wantErr: true},
+	}
+	for _, tc := range tcs {
+		_, err := New(tc.name, tc.version, tc.options)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("New(%s, %s, %v) error = %v, wantErr %v", tc.name, tc.version, tc.options, err, tc.wantErr)
+		}
+	}
+}
+
+func TestJetBrainsConnectionHint(t *testing.T) {
+	e, err := New("jetbrains", "2025.2.4", map[string]string{"product": "GO"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir := e.InstallDir(); dir != "/opt/jetbrains/goland-2025.2.4" {
+		t.Errorf("unexpected install dir %s", dir)
+	}
+	if script := e.DownloadScript("/tmp/editor"); !strings.Contains(script, "https://download.jetbrains.com/go/goland-2025.2.4") {
+		t.Errorf("unexpected download script %s", script)
+	}
+	hint := e.ConnectionHint("127.0.0.1", 2222, "envd", "/home/envd/mnist")
+	expected := "jetbrains-gateway://connect#deploy=false&host=127.0.0.1&idePath=%2Fopt%2Fjetbrains%2Fgoland-2025.2.4" +
+		"&port=2222&projectPath=%2Fhome%2Fenvd%2Fmnist&type=ssh&user=envd"
+	if !strings.HasSuffix(hint, expected) {
+		t.Errorf("expected the gateway link %s in %s", expected, hint)
+	}
+}
+
+func TestNeovimSetupScript(t *testing.T) {
+	e, err := New("neovim", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script := e.SetupScript(); script != "" {
+		t.Errorf("expected no setup script without the config, got %s", script)
+	}
+	if e.Version() != "0.11.4" {
+		t.Errorf("unexpected default version %s", e.Version())
+	}
+
+	e, err = New("neovim", "0.10.4", map[string]string{"config": "https://github.com/LazyVim/starter"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "git clone --depth 1 https://github.com/LazyVim/starter $HOME/.config/nvim && /opt/nvim/bin/nvim --headless '+Lazy! sync' +qa"
+	if script := e.SetupScript(); script != expected {
+		t.Errorf("expected %s, got %s", expected, script)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jetbrains pre-installs the IDE backend for JetBrains Gateway, thus
+// Gateway connects to the environment without downloading the backend.
+package jetbrains
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	Name           = "jetbrains"
+	defaultProduct = "IU"
+	installPrefix  = "/opt/jetbrains"
+	downloadURL    = "https://download.jetbrains.com/%s/%s-%s$([ $(uname -m) = aarch64 ] && echo -aarch64).tar.gz"
+)
+
+type product struct {
+	// path and prefix of the release archive in the download site.
+	path   string
+	prefix string
+}
+
+// products maps the product code to the release of the IDE.
+var products = map[string]product{
+	"IU": {"idea", "ideaIU"},
+	"IC": {"idea", "ideaIC"},
+	"PY": {"python", "pycharm-professional"},
+	"PC": {"python", "pycharm-community"},
+	"GO": {"go", "goland"},
+	"CL": {"cpp", "CLion"},
+	"WS": {"webstorm", "WebStorm"},
+	"RR": {"rustrover", "RustRover"},
+}
+
+type Editor struct {
+	code    string
+	version string
+}
+
+// New returns the IDE of the product code (`product` option, defaults to IU)
+// with the release version such as `2025.2.4`.
+func New(version string, options map[string]string) (*Editor, error) {
+	if version == "" {
+		return nil, errors.New("the version of the JetBrains IDE is required, e.g. 2025.2.4")
+	}
+	code := defaultProduct
+	for k, v := range options {
+		if k != "product" {
+			return nil, errors.Newf("option %s is not supported by the JetBrains IDE", k)
+		}
+		code = v
+	}
+	if _, ok := products[code]; !ok {
+		codes := make([]string, 0, len(products))
+		for c := range products {
+			codes = append(codes, c)
+		}
+		slices.Sort(codes)
+		return nil, errors.Newf("JetBrains product %s is not supported, expect one of %v", code, codes)
+	}
+	return &Editor{code: code, version: version}, nil
+}
+
+func (e Editor) Name() string {
+	return Name
+}
+
+func (e Editor) Version() string {
+	return e.version
+}
+
+func (e Editor) DownloadScript(dir string) string {
+	p := products[e.code]
+	return fmt.Sprintf("mkdir -p %[1]s && wget -qO- %[2]s | tar -xz --strip-components=1 -C %[1]s",
+		dir, fmt.Sprintf(downloadURL, p.path, p.prefix, e.version))
+}
+
+func (e Editor) InstallDir() string {
+	return filepath.Join(installPrefix, fmt.Sprintf("%s-%s", products[e.code].prefix, e.version))
+}
+
+func (e Editor) BinDir() string {
+	return ""
+}
+
+func (e Editor) SetupScript() string {
+	return ""
+}
+
+// ConnectionHint returns the Gateway link that opens the working directory
+// with the pre-installed IDE backend.
+func (e Editor) ConnectionHint(host string, sshPort int, user, workDir string) string {
+	params := url.Values{}
+	params.Set("type", "ssh")
+	params.Set("deploy", "false")
+	params.Set("host", host)
+	params.Set("port", strconv.Itoa(sshPort))
+	params.Set("user", user)
+	params.Set("projectPath", workDir)
+	params.Set("idePath", e.InstallDir())
+	return fmt.Sprintf("open the environment in JetBrains Gateway: jetbrains-gateway://connect#%s", params.Encode())
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package neovim installs the Neovim release with the configuration repository,
+// and the plugins are synced at build time.
+package neovim
+
+import (
+	"fmt"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	Name           = "neovim"
+	defaultVersion = "0.11.4"
+	// defaultSync is the command of lazy.nvim, which is used by most of the distributions.
+	defaultSync = "Lazy! sync"
+	installDir  = "/opt/nvim"
+	downloadURL = "https://github.com/neovim/neovim/releases/download/v%s/nvim-linux-$(uname -m | sed -e s/aarch64/arm64/).tar.gz"
+)
+
+type Editor struct {
+	version string
+	// config is the git repository cloned to `~/.config/nvim`.
+	config string
+	// sync is the Ex command that installs the plugins.
+	sync string
+}
+
+// New returns Neovim of the version, the options are `config` (git repository
+// of the configuration) and `sync` (Ex command to sync the plugins).
+func New(version string, options map[string]string) (*Editor, error) {
+	e := &Editor{version: version}
+	if e.version == "" {
+		e.version = defaultVersion
+	}
+	for k, v := range options {
+		switch k {
+		case "config":
+			e.config = v
+		case "sync":
+			e.sync = v
+		default:
+			return nil, errors.Newf("option %s is not supported by Neovim", k)
+		}
+	}
+	if e.sync != "" && e.config == "" {
+		return nil, errors.New("the config repository is required to sync the Neovim plugins")
+	}
+	if e.config != "" && e.sync == "" {
+		e.sync = defaultSync
+	}
+	return e, nil
+}
+
+func (e Editor) Name() string {
+	return Name
+}
+
+func (e Editor) Version() string {
+	return e.version
+}
+
+func (e Editor) DownloadScript(dir string) string {
+	return fmt.Sprintf("mkdir -p %[1]s && wget -qO- %[2]s | tar -xz --strip-components=1 -C %[1]s",
+		dir, fmt.Sprintf(downloadURL, e.version))
+}
+
+func (e Editor) InstallDir() string {
+	return installDir
+}
+
+func (e Editor) BinDir() string {
+	return installDir + "/bin"
+}
+
+func (e Editor) SetupScript() string {
+	if e.config == "" {
+		return ""
+	}
+	return fmt.Sprintf("git clone --depth 1 %s $HOME/.config/nvim && %s/bin/nvim --headless '+%s' +qa",
+		e.config, installDir, e.sync)
+}
+
+func (e Editor) ConnectionHint(host string, sshPort int, user, workDir string) string {
+	return ""
+}
@@ -0,0 +1,42 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vscode
+
+import (
+	"context"
+	"os"
+	"os/exec"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// OpenRemote opens the directory on the ssh host with the Remote - SSH
+// extension. The directory is not opened if it is empty.
+func OpenRemote(ctx context.Context, host, dir string) error {
+	code, err := exec.LookPath("code")
+	if err != nil {
+		return errors.New("cannot find the `code` command, install it with `Shell Command: Install 'code' command in PATH` in VS Code")
+	}
+	args := []string{"--remote", "ssh-remote+" + host}
+	if dir != "" {
+		args = append(args, dir)
+	}
+	logrus.WithField("args", args).Debug("open vscode")
+	cmd := exec.CommandContext(ctx, code, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return errors.Wrap(cmd.Run(), "failed to open vscode")
+}
@@ -15,9 +15,13 @@
 package envd
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -32,6 +36,7 @@ import (
 	dockerimage "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	dockerutils "github.com/docker/go-units"
 	"github.com/sirupsen/logrus"
@@ -221,24 +226,265 @@ func (e dockerEngine) listEnvGeneralGraph(ctx context.Context, env string, g ir.
 	return newg, err
 }
 
+// ListEnvRuntimeGraph gets the active runtime graph of the given environment.
+// The runtime graph applied by `ApplyRuntimeGraph` takes precedence over the
+// one in the image label.
 func (e dockerEngine) ListEnvRuntimeGraph(ctx context.Context, env string) (*ir.RuntimeGraph, error) {
 	ctr, err := e.ContainerInspect(ctx, env)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to inspect container: %s", env)
 	}
-	code, ok := ctr.Config.Labels[types.RuntimeGraphCode]
-	if !ok {
-		return nil, errors.Newf("failed to get runtime graph label from container: %s", env)
+	code, err := e.readContainerFile(ctx, env, envdconfig.ContainerRuntimeGraphPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the runtime graph from container: %s", env)
+	}
+	if code == nil {
+		label, ok := ctr.Config.Labels[types.RuntimeGraphCode]
+		if !ok {
+			return nil, errors.Newf("failed to get runtime graph label from container: %s", env)
+		}
+		code = []byte(label)
 	}
 	logrus.WithField("env", env).Debugf("runtime graph: %s", code)
 	rg := ir.RuntimeGraph{}
-	err = rg.Load([]byte(code))
+	err = rg.Load(code)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create runtime graph from the container: %s", env)
 	}
 	return &rg, err
 }
 
+func (e dockerEngine) ApplyRuntimeGraph(ctx context.Context, env string,
+	g ir.Graph, timeout time.Duration) (ir.GraphChange, error) {
+	logger := logrus.WithField("env", env)
+	ctr, err := e.ContainerInspect(ctx, env)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return ir.GraphChangeBuild, nil
+		}
+		return ir.GraphChangeNone, errors.Wrapf(err, "failed to inspect container: %s", env)
+	}
+	if !ctr.State.Running {
+		logger.Debug("the environment is not running")
+		return ir.GraphChangeBuild, nil
+	}
+	hash, err := g.GetBuildGraphHash()
+	if err != nil {
+		return ir.GraphChangeNone, errors.Wrap(err, "failed to get the build graph hash")
+	}
+	if hash != ctr.Config.Labels[types.ImageLabelBuildGraphHash] {
+		logger.Debug("the build graph is changed")
+		return ir.GraphChangeBuild, nil
+	}
+
+	active, err := e.ListEnvRuntimeGraph(ctx, env)
+	if err != nil {
+		return ir.GraphChangeNone, err
+	}
+	next := active.WithReloadable(*g.GetRuntimeGraph())
+	prevCode, err := ir.NewReloadableCode(*active)
+	if err != nil {
+		return ir.GraphChangeNone, err
+	}
+	nextCode, err := ir.NewReloadableCode(next)
+	if err != nil {
+		return ir.GraphChangeNone, err
+	}
+	if prevCode == nextCode {
+		return ir.GraphChangeNone, nil
+	}
+
+	code, err := next.Dump()
+	if err != nil {
+		return ir.GraphChangeNone, errors.Wrap(err, "failed to dump the runtime graph")
+	}
+	files := map[string][]byte{
+		envdconfig.ContainerRuntimeGraphPath: []byte(code),
+	}
+	for _, service := range g.GetRuntimeServices() {
+		files[filepath.Join(types.HorustServiceDir, service.Name+".toml")] = []byte(service.Content)
+		if service.Script != "" {
+			files[ir.ServiceScriptPath(service.Name)] = []byte(service.Script)
+		}
+	}
+
+	// horust only loads the services on start, thus the environment is
+	// restarted instead of recreated if the daemons are added or removed, or
+	// the changed daemons are not running to be restarted by the signal, which
+	// keeps the container filesystem.
+	_, daemons := active.ChangedServices(next)
+	running, err := e.daemonsRunning(ctx, env, daemons)
+	if err != nil {
+		return ir.GraphChangeNone, err
+	}
+	if len(active.RuntimeDaemon) != len(next.RuntimeDaemon) || !running {
+		if err := e.execInContainer(ctx, env, "root", []string{"sh", "-c", fmt.Sprintf(
+			"rm -f %[1]s/init_*.toml %[1]s/daemon_*.toml %[2]s/init_*.sh %[2]s/daemon_*.sh",
+			types.HorustServiceDir, types.HorustScriptDir)}); err != nil {
+			return ir.GraphChangeNone, errors.Wrap(err, "failed to remove the stale services")
+		}
+		if err := e.copyFilesToContainer(ctx, env, files, 0644); err != nil {
+			return ir.GraphChangeNone, errors.Wrap(err, "failed to copy the services to the container")
+		}
+		logger.Debug("restarting the environment to load the services")
+		if err := e.ContainerRestart(ctx, env, container.StopOptions{}); err != nil {
+			return ir.GraphChangeNone, errors.Wrap(err, "failed to restart the environment")
+		}
+		if err := e.WaitUntilRunning(ctx, env, timeout); err != nil {
+			return ir.GraphChangeNone, errors.Wrap(err, "failed to wait until the environment is running")
+		}
+		return ir.GraphChangeRuntime, nil
+	}
+
+	if err := e.copyFilesToContainer(ctx, env, files, 0644); err != nil {
+		return ir.GraphChangeNone, errors.Wrap(err, "failed to copy the services to the container")
+	}
+	if err := e.reloadRuntimeServices(ctx, env, ctr.Config.User, *active, next); err != nil {
+		return ir.GraphChangeNone, errors.Wrap(err, "failed to reload the services")
+	}
+	return ir.GraphChangeRuntime, nil
+}
+
+// reloadRuntimeServices runs the changed init scripts again and signals the
+// changed daemons, which are restarted with the new scripts. The other
+// processes in the environment, e.g. the ssh sessions, are kept.
+func (e dockerEngine) reloadRuntimeServices(ctx context.Context, env, user string,
+	prev, next ir.RuntimeGraph) error {
+	inits, daemons := prev.ChangedServices(next)
+	for _, name := range inits {
+		logrus.WithFields(logrus.Fields{"env": env, "service": name}).Debug("running the init script")
+		if err := e.execInContainer(ctx, env, user, []string{"/bin/bash", ir.ServiceScriptPath(name)}); err != nil {
+			return errors.Wrapf(err, "failed to run the init script %s", name)
+		}
+	}
+	if len(daemons) == 0 {
+		return nil
+	}
+	cmd := []string{"sh", "-c", `for f in "$@"; do kill -HUP "$(cat "$f")"; done`, "sh"}
+	for _, name := range daemons {
+		cmd = append(cmd, ir.ServicePIDPath(name))
+	}
+	logrus.WithFields(logrus.Fields{"env": env, "services": daemons}).Debug("restarting the daemons")
+	if err := e.execInContainer(ctx, env, "root", cmd); err != nil {
+		return errors.Wrap(err, "failed to signal the daemons")
+	}
+	return nil
+}
+
+// daemonsRunning returns true if the launchers of the daemons are running. The
+// launcher exits with the daemon, and its pid file is removed.
+func (e dockerEngine) daemonsRunning(ctx context.Context, env string, daemons []string) (bool, error) {
+	if len(daemons) == 0 {
+		return true, nil
+	}
+	cmd := []string{"sh", "-c", `for f in "$@"; do kill -0 "$(cat "$f" 2>/dev/null)" 2>/dev/null || exit 3; done`, "sh"}
+	for _, name := range daemons {
+		cmd = append(cmd, ir.ServicePIDPath(name))
+	}
+	code, output, err := e.runInContainer(ctx, env, "root", cmd)
+	if err != nil {
+		return false, err
+	}
+	switch code {
+	case 0:
+		return true, nil
+	case 3:
+		return false, nil
+	}
+	return false, errors.Newf("failed to check the daemons with code %d: %s", code, output)
+}
+
+// execInContainer runs the command as the user in the container and waits for it.
+func (e dockerEngine) execInContainer(ctx context.Context, env, user string, cmd []string) error {
+	code, output, err := e.runInContainer(ctx, env, user, cmd)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return errors.Newf("command %v exited with code %d: %s", cmd, code, output)
+	}
+	return nil
+}
+
+// runInContainer runs the command and returns the exit code and the output.
+func (e dockerEngine) runInContainer(ctx context.Context, env, user string, cmd []string) (int, string, error) {
+	resp, err := e.ContainerExecCreate(ctx, env, container.ExecOptions{
+		User:         user,
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, "", errors.Wrap(err, "failed to create the exec")
+	}
+	hijacked, err := e.ContainerExecAttach(ctx, resp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, "", errors.Wrap(err, "failed to attach the exec")
+	}
+	defer hijacked.Close()
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, hijacked.Reader); err != nil {
+		return 0, "", errors.Wrap(err, "failed to read the exec output")
+	}
+	inspect, err := e.ContainerExecInspect(ctx, resp.ID)
+	if err != nil {
+		return 0, "", errors.Wrap(err, "failed to inspect the exec")
+	}
+	return inspect.ExitCode, output.String(), nil
+}
+
+func (e dockerEngine) copyFilesToContainer(ctx context.Context, env string,
+	files map[string][]byte, mode int64) error {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for path, content := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    strings.TrimPrefix(path, "/"),
+			Mode:    mode,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	// The files are owned by the user of the environment instead of root.
+	return e.CopyToContainer(ctx, env, "/", &buf, container.CopyToContainerOptions{CopyUIDGID: true})
+}
+
+// ReadEnvFile returns the content of the file in the environment, or nil if
+// the file does not exist.
+func (e dockerEngine) ReadEnvFile(ctx context.Context, env, path string) ([]byte, error) {
+	dat, err := e.readContainerFile(ctx, env, path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s in the environment %s", path, env)
+	}
+	return dat, nil
+}
+
+// readContainerFile returns the content of the file in the container, or nil
+// if the file does not exist.
+func (e dockerEngine) readContainerFile(ctx context.Context, env, path string) ([]byte, error) {
+	rc, _, err := e.CopyFromContainer(ctx, env, path)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	if _, err := tr.Next(); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(tr)
+}
+
 // ListEnvDependency gets the dependencies of the given environment.
 func (e dockerEngine) ListEnvDependency(
 	ctx context.Context, env string) (*types.Dependency, error) {
@@ -325,11 +571,12 @@ func (e dockerEngine) GenerateSSHConfig(name, iface, privateKeyPath string,
 		EnableHostKeyCheck: false,
 		EnableAgentForward: true,
 	}
-	return eo, nil
+	return eo.WithPinnedHostKey(), nil
 }
 
-func (e dockerEngine) newSSHClient(server string, port int, privateKeyPath string) (ssh.Client, error) {
+func (e dockerEngine) newSSHClient(name, server string, port int, privateKeyPath string) (ssh.Client, error) {
 	opt := ssh.DefaultOptions()
+	opt.HostKeyAlias = sshconfig.BuildHostname(name)
 	opt.Server = server
 	opt.PrivateKeyPath = privateKeyPath
 	opt.Port = port
@@ -342,7 +589,7 @@ func (e dockerEngine) newSSHClient(server string, port int, privateKeyPath strin
 
 func (e dockerEngine) Attach(name, iface, privateKeyPath string,
 	startResult *StartResult, g ir.Graph) error {
-	sshClient, err := e.newSSHClient(iface, startResult.SSHPort, privateKeyPath)
+	sshClient, err := e.newSSHClient(startResult.Name, iface, startResult.SSHPort, privateKeyPath)
 	if err != nil {
 		return err
 	}
@@ -355,7 +602,7 @@ func (e dockerEngine) Attach(name, iface, privateKeyPath string,
 }
 
 func (e dockerEngine) LocalForward(iface, privateKeyPath string, startResult *StartResult, localAddress, targetAddress string) error {
-	sshClient, err := e.newSSHClient(iface, startResult.SSHPort, privateKeyPath)
+	sshClient, err := e.newSSHClient(startResult.Name, iface, startResult.SSHPort, privateKeyPath)
 	if err != nil {
 		return err
 	}
@@ -368,7 +615,7 @@ func (e dockerEngine) LocalForward(iface, privateKeyPath string, startResult *St
 }
 
 func (e dockerEngine) RemoteForward(iface, privateKeyPath string, startResult *StartResult, localAddress, targetAddress string) error {
-	sshClient, err := e.newSSHClient(iface, startResult.SSHPort, privateKeyPath)
+	sshClient, err := e.newSSHClient(startResult.Name, iface, startResult.SSHPort, privateKeyPath)
 	if err != nil {
 		return err
 	}
@@ -577,6 +824,7 @@ func (e dockerEngine) StartEnvd(ctx context.Context, so StartOptions) (*StartRes
 		config.ExposedPorts[natPort] = struct{}{}
 	}
 
+	var webTerminalAddr, vscodeServerAddr string
 	if len(g.GetExposedPorts()) > 0 {
 
 		for _, item := range g.GetExposedPorts() {
@@ -587,6 +835,24 @@ func (e dockerEngine) StartEnvd(ctx context.Context, so StartOptions) (*StartRes
 					return nil, errors.Wrap(err, "failed to get a free port")
 				}
 			}
+			if item.ServiceName == envdconfig.WebTerminalService {
+				token, err := newWebTerminalToken()
+				if err != nil {
+					return nil, err
+				}
+				config.Env = append(config.Env, fmt.Sprintf("%s=%s", types.EnvdWebTerminalToken, token))
+				webTerminalAddr = fmt.Sprintf("http://%s/?token=%s",
+					net.JoinHostPort(webTerminalHost(item.ListeningAddr), strconv.Itoa(item.HostPort)), token)
+			}
+			if item.ServiceName == envdconfig.VSCodeServerService {
+				token, err := newWebTerminalToken()
+				if err != nil {
+					return nil, err
+				}
+				config.Env = append(config.Env, fmt.Sprintf("%s=%s", types.EnvdVSCodeServerToken, token))
+				vscodeServerAddr = fmt.Sprintf("http://%s/?tkn=%s",
+					net.JoinHostPort(webTerminalHost(item.ListeningAddr), strconv.Itoa(item.HostPort)), token)
+			}
 			natPort := nat.Port(fmt.Sprintf("%d/tcp", item.EnvdPort))
 			hostConfig.PortBindings[natPort] = []nat.PortBinding{
 				{
@@ -609,7 +875,12 @@ func (e dockerEngine) StartEnvd(ctx context.Context, so StartOptions) (*StartRes
 
 	config.Labels = e.labels(g, so.EnvironmentName,
 		sshPortInHost, jupyterPortInHost, rStudioPortInHost)
-
+	if webTerminalAddr != "" {
+		config.Labels[types.ContainerLabelWebTerminalAddr] = webTerminalAddr
+	}
+	if vscodeServerAddr != "" {
+		config.Labels[types.ContainerLabelVSCodeServerAddr] = vscodeServerAddr
+	}
 	logger = logger.WithFields(logrus.Fields{
 		"entrypoint":  config.Entrypoint,
 		"working-dir": config.WorkingDir,
@@ -626,6 +897,19 @@ func (e dockerEngine) StartEnvd(ctx context.Context, so StartOptions) (*StartRes
 		logger.Warnf("run with warnings: %s", w)
 	}
 
+	if g.IsDev() {
+		// The host key is copied when the environment starts instead of being
+		// stored in the image, which may be pushed and pulled by the others.
+		hostKey, err := sshconfig.GetOrCreateHostKey(so.EnvironmentName)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the ssh host key")
+		}
+		if err := e.copyFilesToContainer(ctx, resp.ID,
+			map[string][]byte{envdconfig.ContainerHostKeyPath: hostKey}, 0600); err != nil {
+			return nil, errors.Wrap(err, "failed to copy the ssh host key to the container")
+		}
+	}
+
 	bar.UpdateTitle("start the environment")
 	if err := e.ContainerStart(
 		ctx, resp.ID, container.StartOptions{}); err != nil {
@@ -653,11 +937,30 @@ func (e dockerEngine) StartEnvd(ctx context.Context, so StartOptions) (*StartRes
 	result := &StartResult{
 		SSHPort: sshPortInHost,
 		// https://github.com/moby/moby/issues/6705#issuecomment-47298276
-		Name: strings.TrimPrefix(container.Name, "/"),
+		Name:             strings.TrimPrefix(container.Name, "/"),
+		WebTerminalAddr:  webTerminalAddr,
+		VSCodeServerAddr: vscodeServerAddr,
 	}
 	return result, nil
 }
 
+func (e dockerEngine) GetStartResult(ctx context.Context, name string) (*StartResult, error) {
+	ctr, err := e.ContainerInspect(ctx, name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to inspect container: %s", name)
+	}
+	sshPort, err := strconv.Atoi(ctr.Config.Labels[types.ContainerLabelSSHPort])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the ssh port of container: %s", name)
+	}
+	return &StartResult{
+		SSHPort:          sshPort,
+		Name:             strings.TrimPrefix(ctr.Name, "/"),
+		WebTerminalAddr:  ctr.Config.Labels[types.ContainerLabelWebTerminalAddr],
+		VSCodeServerAddr: ctr.Config.Labels[types.ContainerLabelVSCodeServerAddr],
+	}, nil
+}
+
 func (e dockerEngine) Destroy(ctx context.Context, name string) (string, error) {
 	logger := logrus.WithField("container", name)
 
@@ -811,3 +1114,11 @@ func (e dockerEngine) labels(g ir.Graph, name string,
 
 	return res
 }
+
+// webTerminalHost returns the host in the web terminal URL.
+func webTerminalHost(listeningAddr string) string {
+	if listeningAddr == "" || net.ParseIP(listeningAddr).IsUnspecified() {
+		return Localhost
+	}
+	return listeningAddr
+}
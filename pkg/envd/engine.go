@@ -16,8 +16,11 @@ package envd
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"time"
 
+	"github.com/cockroachdb/errors"
 	dockerimage "github.com/docker/docker/api/types/image"
 
 	"github.com/tensorchord/envd/pkg/lang/ir"
@@ -41,10 +44,19 @@ type EnvironmentClient interface {
 	ListEnvRuntimeGraph(ctx context.Context, env string) (*ir.RuntimeGraph, error)
 	ListEnvDependency(ctx context.Context, env string) (*types.Dependency, error)
 	ListEnvPortBinding(ctx context.Context, env string) ([]types.PortBinding, error)
+	// ReadEnvFile reads the file in the environment, it returns nil if the
+	// file does not exist.
+	ReadEnvFile(ctx context.Context, env, path string) ([]byte, error)
+	// ApplyRuntimeGraph applies the reloadable runtime graph of the given graph
+	// to the running environment if the build graph is not changed. It
+	// returns GraphChangeBuild if the environment needs to be rebuilt.
+	ApplyRuntimeGraph(ctx context.Context, env string, g ir.Graph, timeout time.Duration) (ir.GraphChange, error)
 
 	CleanEnvdIfExists(ctx context.Context, name string, force bool) error
 	// StartEnvd creates the container for the given tag and container name.
 	StartEnvd(ctx context.Context, so StartOptions) (*StartResult, error)
+	// GetStartResult gets the start result of the running environment.
+	GetStartResult(ctx context.Context, name string) (*StartResult, error)
 
 	IsRunning(ctx context.Context, name string) (bool, error)
 	Exists(ctx context.Context, name string) (bool, error)
@@ -72,3 +84,12 @@ type VersionClient interface {
 	GetInfo(ctx context.Context) (*types.EnvdInfo, error)
 	GPUEnabled(ctx context.Context) (bool, error)
 }
+
+// newWebTerminalToken returns a random token for the web terminal.
+func newWebTerminalToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "failed to generate the web terminal token")
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/tensorchord/envd-server/errdefs"
 	"github.com/tensorchord/envd-server/sshname"
 
+	envdconfig "github.com/tensorchord/envd/pkg/config"
 	"github.com/tensorchord/envd/pkg/lang/ir"
 	"github.com/tensorchord/envd/pkg/ssh"
 	sshconfig "github.com/tensorchord/envd/pkg/ssh/config"
@@ -149,11 +150,12 @@ func (e envdServerEngine) GenerateSSHConfig(name, iface, privateKeyPath string,
 		EnableAgentForward: false,
 		User:               username,
 	}
-	return eo, nil
+	return eo.WithPinnedHostKey(), nil
 }
 
-func (e envdServerEngine) newSSHClient(username, server string, port int, privateKeyPath string) (ssh.Client, error) {
+func (e envdServerEngine) newSSHClient(name, username, server string, port int, privateKeyPath string) (ssh.Client, error) {
 	opt := ssh.DefaultOptions()
+	opt.HostKeyAlias = sshconfig.BuildHostname(name)
 	opt.PrivateKeyPath = privateKeyPath
 	opt.Port = port
 	opt.AgentForwarding = false
@@ -173,7 +175,7 @@ func (e envdServerEngine) Attach(name, iface, privateKeyPath string, startResult
 	}
 
 	outputChannel := make(chan error)
-	sshClient, err := e.newSSHClient(username, iface, startResult.SSHPort, privateKeyPath)
+	sshClient, err := e.newSSHClient(startResult.Name, username, iface, startResult.SSHPort, privateKeyPath)
 	if err != nil {
 		outputChannel <- err
 	}
@@ -220,7 +222,7 @@ func (e *envdServerEngine) LocalForward(iface, privateKeyPath string, startResul
 	if err != nil {
 		return errors.Wrap(err, "failed to get the username")
 	}
-	sshClient, err := e.newSSHClient(username, iface, startResult.SSHPort, privateKeyPath)
+	sshClient, err := e.newSSHClient(startResult.Name, username, iface, startResult.SSHPort, privateKeyPath)
 	if err != nil {
 		return err
 	}
@@ -238,7 +240,7 @@ func (e *envdServerEngine) RemoteForward(iface, privateKeyPath string, startResu
 		return errors.Wrap(err, "failed to get the username")
 	}
 
-	sshClient, err := e.newSSHClient(username, iface, startResult.SSHPort, privateKeyPath)
+	sshClient, err := e.newSSHClient(startResult.Name, username, iface, startResult.SSHPort, privateKeyPath)
 	if err != nil {
 		return err
 	}
@@ -267,6 +269,19 @@ func (e *envdServerEngine) ListEnvRuntimeGraph(ctx context.Context, env string)
 	return &rg, nil
 }
 
+func (e *envdServerEngine) ApplyRuntimeGraph(ctx context.Context, env string, g ir.Graph, timeout time.Duration) (ir.GraphChange, error) {
+	// The environment is always rebuilt and recreated by envd-server.
+	return ir.GraphChangeBuild, nil
+}
+
+func (e *envdServerEngine) GetStartResult(ctx context.Context, name string) (*StartResult, error) {
+	return nil, errors.New("getting the start result is not supported by envd-server runner")
+}
+
+func (e *envdServerEngine) ReadEnvFile(ctx context.Context, env, path string) ([]byte, error) {
+	return nil, errors.New("reading files in the environment is not supported by envd-server runner")
+}
+
 func (e *envdServerEngine) ListEnvDependency(
 	ctx context.Context, name string) (*types.Dependency, error) {
 	logger := logrus.WithFields(logrus.Fields{
@@ -321,6 +336,21 @@ func (e *envdServerEngine) StartEnvd(ctx context.Context, so StartOptions) (*Sta
 		return nil, errors.New("failed to get the envd server specific options")
 	}
 
+	// The tokens are unused if the web terminal or the vscode server is
+	// not enabled in the image.
+	token, err := newWebTerminalToken()
+	if err != nil {
+		return nil, err
+	}
+	vscodeToken, err := newWebTerminalToken()
+	if err != nil {
+		return nil, err
+	}
+	// The host key cannot be copied into the environment run by envd-server,
+	// thus envd-sshd generates one and the stale pinned key is removed.
+	if err := sshconfig.UnpinHostKey(so.EnvironmentName); err != nil {
+		return nil, errors.Wrap(err, "failed to unpin the ssh host key")
+	}
 	req := servertypes.EnvironmentCreateRequest{
 		Environment: servertypes.Environment{
 			ObjectMeta: servertypes.ObjectMeta{
@@ -329,6 +359,10 @@ func (e *envdServerEngine) StartEnvd(ctx context.Context, so StartOptions) (*Sta
 			Spec: servertypes.EnvironmentSpec{
 				Image: so.Image,
 				Sync:  so.EngineSource.EnvdServerSource.Sync,
+				Env: []servertypes.EnvVar{
+					{Name: types.EnvdWebTerminalToken, Value: token},
+					{Name: types.EnvdVSCodeServerToken, Value: vscodeToken},
+				},
 			},
 			Resources: servertypes.ResourceSpec{
 				CPU:    so.NumCPU,
@@ -338,7 +372,11 @@ func (e *envdServerEngine) StartEnvd(ctx context.Context, so StartOptions) (*Sta
 			},
 		},
 	}
-	logrus.WithField("req", req).Debug("send request to create new env")
+	// The request is not logged since the env contains the tokens.
+	logrus.WithFields(logrus.Fields{
+		"name":  so.EnvironmentName,
+		"image": so.Image,
+	}).Debug("send request to create new env")
 
 	bar := InitProgressBar(3)
 	defer bar.Finish()
@@ -360,6 +398,14 @@ func (e *envdServerEngine) StartEnvd(ctx context.Context, so StartOptions) (*Sta
 		Name:    resp.Created.Name,
 		Ports:   resp.Created.Spec.Ports,
 	}
+	for _, port := range result.Ports {
+		switch port.Name {
+		case envdconfig.WebTerminalService:
+			result.WebTerminalToken = token
+		case envdconfig.VSCodeServerService:
+			result.VSCodeServerToken = vscodeToken
+		}
+	}
 	return result, nil
 }
 
@@ -71,6 +71,17 @@ type StartResult struct {
 	Name    string
 
 	Ports []types.EnvironmentPort
+
+	// WebTerminalAddr is the URL of the web terminal with the token.
+	WebTerminalAddr string
+	// WebTerminalToken is the token of the web terminal, it is set if
+	// the address is unknown, e.g. the environment is behind the gateway.
+	WebTerminalToken string
+	// VSCodeServerAddr is the URL of the OpenVSCode Server with the token.
+	VSCodeServerAddr string
+	// VSCodeServerToken is the connection token of the OpenVSCode Server, it is
+	// set if the address is unknown, e.g. the environment is behind the gateway.
+	VSCodeServerToken string
 }
 
 type ProgressBar struct {
@@ -30,6 +30,9 @@ type authManager interface {
 	AuthGetCurrent() (types.AuthConfig, error)
 	AuthCreate(ac types.AuthConfig, use bool) error
 	AuthUse(name string) error
+	// AuthList returns the auth configs without the tokens.
+	AuthList() (types.EnvdAuth, error)
+	AuthRemove(name string) error
 }
 
 func (m *generalManager) initAuth() error {
@@ -40,6 +43,12 @@ func (m *generalManager) initAuth() error {
 	}
 
 	m.authFile = auth
+	if m.credentialFile, err = fileutil.ConfigFile("credentials"); err != nil {
+		return errors.Wrap(err, "failed to get credentials file")
+	}
+	if m.credentialKey, err = fileutil.ConfigFile("credentials.key"); err != nil {
+		return errors.Wrap(err, "failed to get credentials key file")
+	}
 
 	_, err = os.Stat(m.authFile)
 	if err != nil {
@@ -69,9 +78,42 @@ func (m *generalManager) initAuth() error {
 	if err := e.Decode(&m.auth); err != nil {
 		return errors.Wrap(err, "failed to decode auth file")
 	}
+	if err := m.migrateAuth(); err != nil {
+		logrus.WithError(err).Warn("failed to migrate the auth file, the tokens are kept in it")
+	}
 	return nil
 }
 
+// migrateAuth moves the tokens saved by the old envd in the auth file to
+// the credential store. The token is kept in the auth file if it fails to be
+// stored, e.g. the credential helper is not installed on this machine.
+func (m *generalManager) migrateAuth() error {
+	migrated := false
+	for i, a := range m.auth.Auth {
+		if a.JWTToken == "" {
+			continue
+		}
+		if err := m.credentialStore(a.CredentialHelper).Store(a.Name, a.JWTToken); err != nil {
+			logrus.WithError(err).Warnf("failed to migrate the token of %s to the credential store", a.Name)
+			continue
+		}
+		m.auth.Auth[i].JWTToken = ""
+		migrated = true
+	}
+	if !migrated {
+		return nil
+	}
+	logrus.Debug("the tokens in the auth file are moved to the credential store")
+	return m.dumpAuth()
+}
+
+func (m *generalManager) credentialStore(helper string) credentialStore {
+	if helper != "" {
+		return newHelperCredentialStore(helper)
+	}
+	return fileCredentialStore{path: m.credentialFile, keyPath: m.credentialKey}
+}
+
 func (m *generalManager) AuthFile() string {
 	return m.authFile
 }
@@ -79,6 +121,15 @@ func (m *generalManager) AuthFile() string {
 func (m *generalManager) AuthGetCurrent() (types.AuthConfig, error) {
 	for _, c := range m.auth.Auth {
 		if c.Name == m.auth.Current {
+			// The token is only left in the auth file if it is not migrated.
+			if c.JWTToken != "" {
+				return c, nil
+			}
+			token, err := m.credentialStore(c.CredentialHelper).Get(c.Name)
+			if err != nil {
+				return types.AuthConfig{}, errors.Wrapf(err, "failed to get the token of %s, please login again", c.Name)
+			}
+			c.JWTToken = token
 			return c, nil
 		}
 	}
@@ -86,15 +137,26 @@ func (m *generalManager) AuthGetCurrent() (types.AuthConfig, error) {
 }
 
 func (m *generalManager) AuthCreate(ac types.AuthConfig, use bool) error {
+	if err := m.credentialStore(ac.CredentialHelper).Store(ac.Name, ac.JWTToken); err != nil {
+		return err
+	}
+	entry := ac
+	entry.JWTToken = ""
 	exist := false
-	for _, a := range m.auth.Auth {
+	for i, a := range m.auth.Auth {
 		if a.Name == ac.Name {
 			// Auth should be idempotent. Thus do not return error here.
 			exist = true
+			if a.CredentialHelper != ac.CredentialHelper {
+				if err := m.credentialStore(a.CredentialHelper).Erase(a.Name); err != nil {
+					logrus.WithError(err).Warnf("failed to erase the old token of %s", a.Name)
+				}
+			}
+			m.auth.Auth[i] = entry
 		}
 	}
 	if !exist {
-		m.auth.Auth = append(m.auth.Auth, ac)
+		m.auth.Auth = append(m.auth.Auth, entry)
 	}
 	if use {
 		return m.AuthUse(ac.Name)
@@ -112,6 +174,26 @@ func (m *generalManager) AuthUse(name string) error {
 	return errors.Newf("auth config \"%s\" does not exist", name)
 }
 
+func (m *generalManager) AuthList() (types.EnvdAuth, error) {
+	return m.auth, nil
+}
+
+func (m *generalManager) AuthRemove(name string) error {
+	for i, a := range m.auth.Auth {
+		if a.Name == name {
+			if err := m.credentialStore(a.CredentialHelper).Erase(a.Name); err != nil {
+				return err
+			}
+			m.auth.Auth = append(m.auth.Auth[:i], m.auth.Auth[i+1:]...)
+			if m.auth.Current == name {
+				m.auth.Current = ""
+			}
+			return m.dumpAuth()
+		}
+	}
+	return errors.Newf("auth config \"%s\" does not exist", name)
+}
+
 func (m *generalManager) dumpAuth() error {
 	file, err := os.Create(m.authFile)
 	if err != nil {
@@ -15,6 +15,8 @@
 package home
 
 import (
+	"path/filepath"
+
 	"github.com/tensorchord/envd/pkg/types"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -54,6 +56,24 @@ var _ = Describe("auth test", Ordered, func() {
 		})
 	})
 
+	Describe("migrate with a missing credential helper", func() {
+		It("should keep the legacy token in the auth file", func() {
+			legacy := types.AuthConfig{
+				Name:             "legacy",
+				JWTToken:         "legacy_token",
+				CredentialHelper: "envd-missing-helper",
+			}
+			m := &generalManager{
+				authFile: filepath.Join(GinkgoT().TempDir(), "auth"),
+				auth:     types.EnvdAuth{Current: legacy.Name, Auth: []types.AuthConfig{legacy}},
+			}
+			Expect(m.migrateAuth()).To(Succeed())
+			auth, err := m.AuthGetCurrent()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(auth.JWTToken).To(Equal(legacy.JWTToken))
+		})
+	})
+
 	Describe("create without use", func() {
 		BeforeAll(func() {
 			err := GetManager().AuthCreate(ac, false)
@@ -17,6 +17,9 @@ package home
 import (
 	"encoding/gob"
 	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/cockroachdb/errors"
 	"github.com/sirupsen/logrus"
@@ -32,6 +35,36 @@ type contextManager interface {
 	ContextGetCurrent() (*types.Context, error)
 	ContextCreate(c types.Context, use bool) error
 	ContextRemove(name string) error
+	// ContextSelect selects the context from the source instead of the one
+	// set by `envd context use`, until the global source is selected.
+	ContextSelect(source ContextSource) error
+	// ContextGetSource returns the source of the current context.
+	ContextGetSource() ContextSource
+}
+
+// ContextSourceKind is the kind of the source selecting the context, in the
+// order of precedence.
+type ContextSourceKind string
+
+const (
+	ContextSourceFlag    ContextSourceKind = "flag"
+	ContextSourceEnv     ContextSourceKind = "env"
+	ContextSourceProject ContextSourceKind = "project"
+	ContextSourceGlobal  ContextSourceKind = "global"
+
+	// EnvContext is the environment variable to select the context.
+	EnvContext = "ENVD_CONTEXT"
+	// ProjectContextFile is the file in the project to select the context.
+	ProjectContextFile = ".envd/context"
+)
+
+// ContextSource is where the context is selected.
+type ContextSource struct {
+	Kind ContextSourceKind
+	// Origin is the flag, the environment variable or the file selecting
+	// the context.
+	Origin string
+	Name   string
 }
 
 func (m *generalManager) initContext() error {
@@ -79,14 +112,80 @@ func (m *generalManager) ContextFile() string {
 }
 
 func (m *generalManager) ContextGetCurrent() (*types.Context, error) {
+	current := m.ContextGetSource().Name
 	for _, c := range m.context.Contexts {
-		if m.context.Current == c.Name {
+		if current == c.Name {
 			return &c, nil
 		}
 	}
 	return nil, errors.New("no current context")
 }
 
+func (m *generalManager) ContextSelect(source ContextSource) error {
+	if source.Kind == ContextSourceGlobal {
+		m.selected = nil
+		return nil
+	}
+	for _, c := range m.context.Contexts {
+		if c.Name == source.Name {
+			m.selected = &source
+			return nil
+		}
+	}
+	return errors.Newf("context \"%s\" selected by %s does not exist", source.Name, source.Origin)
+}
+
+func (m *generalManager) ContextGetSource() ContextSource {
+	if m.selected != nil {
+		return *m.selected
+	}
+	return ContextSource{
+		Kind:   ContextSourceGlobal,
+		Origin: m.contextFile,
+		Name:   m.context.Current,
+	}
+}
+
+// FindProjectContext finds the project context file in the dir and its
+// parents. It returns nil if there is no such file.
+func FindProjectContext(dir string) (*ContextSource, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the absolute path")
+	}
+	for {
+		path := filepath.Join(dir, ProjectContextFile)
+		dat, err := os.ReadFile(path)
+		if err == nil {
+			name := parseProjectContext(dat)
+			if name == "" {
+				return nil, errors.Newf("the project context file %s is empty", path)
+			}
+			return &ContextSource{Kind: ContextSourceProject, Origin: path, Name: name}, nil
+		}
+		if !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+			return nil, errors.Wrapf(err, "failed to read the project context file %s", path)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// parseProjectContext returns the first line except the blank lines and
+// the comments.
+func parseProjectContext(dat []byte) string {
+	for _, line := range strings.Split(string(dat), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line
+		}
+	}
+	return ""
+}
+
 func (m *generalManager) ContextCreate(ctx types.Context, use bool) error {
 	for _, c := range m.context.Contexts {
 		if c.Name == ctx.Name {
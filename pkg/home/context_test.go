@@ -15,6 +15,9 @@
 package home
 
 import (
+	"os"
+	"path/filepath"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -93,4 +96,51 @@ var _ = Describe("context test", func() {
 			Expect(GetManager().ContextRemove(testContext)).To(Succeed())
 		})
 	})
+
+	Describe("select", Ordered, func() {
+		BeforeAll(func() {
+			Expect(GetManager().ContextCreate(c, false)).To(Succeed())
+		})
+
+		It("should select the context from the source", func() {
+			source := ContextSource{Kind: ContextSourceEnv, Origin: EnvContext, Name: testContext}
+			Expect(GetManager().ContextSelect(source)).To(Succeed())
+			Expect(GetManager().ContextGetSource()).To(Equal(source))
+			current, err := GetManager().ContextGetCurrent()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(current.Name).To(Equal(testContext))
+			// The global current context is not changed.
+			contexts, err := GetManager().ContextList()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contexts.Current).To(Equal(defaultContext))
+		})
+
+		It("should reject the missing context", func() {
+			err := GetManager().ContextSelect(ContextSource{Kind: ContextSourceFlag, Origin: "--context", Name: "missing"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should find the project context in the parents", func() {
+			dir := GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(dir, ".envd"), 0755)).To(Succeed())
+			path := filepath.Join(dir, ProjectContextFile)
+			Expect(os.WriteFile(path, []byte("# team server\n\n"+testContext+"\n"), 0644)).To(Succeed())
+			sub := filepath.Join(dir, "a", "b")
+			Expect(os.MkdirAll(sub, 0755)).To(Succeed())
+
+			source, err := FindProjectContext(sub)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*source).To(Equal(ContextSource{Kind: ContextSourceProject, Origin: path, Name: testContext}))
+
+			source, err = FindProjectContext(GinkgoT().TempDir())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(source).To(BeNil())
+		})
+
+		AfterAll(func() {
+			Expect(GetManager().ContextSelect(ContextSource{Kind: ContextSourceGlobal})).To(Succeed())
+			Expect(GetManager().ContextGetSource().Name).To(Equal(defaultContext))
+			Expect(GetManager().ContextRemove(testContext)).To(Succeed())
+		})
+	})
 })
@@ -0,0 +1,202 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package home
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/docker/docker-credential-helpers/client"
+	"github.com/docker/docker-credential-helpers/credentials"
+)
+
+const credentialKeySize = 32
+
+// credentialStore keeps the secrets of the auth configs out of the auth
+// file.
+type credentialStore interface {
+	Store(name, secret string) error
+	Get(name string) (string, error)
+	Erase(name string) error
+}
+
+// helperCredentialStore talks to `docker-credential-<helper>` with the
+// docker credential-helper protocol, e.g. osxkeychain, wincred or pass.
+type helperCredentialStore struct {
+	program client.ProgramFunc
+}
+
+func newHelperCredentialStore(helper string) helperCredentialStore {
+	return helperCredentialStore{
+		program: client.NewShellProgramFunc("docker-credential-" + helper),
+	}
+}
+
+// credentialServerURL returns the key of the auth config in the helper.
+func credentialServerURL(name string) string {
+	return "envd://auth/" + url.PathEscape(name)
+}
+
+func (s helperCredentialStore) Store(name, secret string) error {
+	err := client.Store(s.program, &credentials.Credentials{
+		ServerURL: credentialServerURL(name),
+		Username:  name,
+		Secret:    secret,
+	})
+	return errors.Wrap(err, "failed to store the credential with the helper")
+}
+
+func (s helperCredentialStore) Get(name string) (string, error) {
+	creds, err := client.Get(s.program, credentialServerURL(name))
+	if err != nil {
+		if credentials.IsErrCredentialsNotFound(err) {
+			return "", errors.Newf("cannot find the credential of %s in the helper", name)
+		}
+		return "", errors.Wrap(err, "failed to get the credential from the helper")
+	}
+	return creds.Secret, nil
+}
+
+func (s helperCredentialStore) Erase(name string) error {
+	// The helpers do not report the missing credential in erase with the
+	// well-known message, thus check it with get first.
+	if _, err := client.Get(s.program, credentialServerURL(name)); credentials.IsErrCredentialsNotFound(err) {
+		return nil
+	}
+	err := client.Erase(s.program, credentialServerURL(name))
+	return errors.Wrap(err, "failed to erase the credential with the helper")
+}
+
+// fileCredentialStore is the fallback without the credential helper. The
+// secrets are encrypted with AES-GCM, and the key is kept in another file
+// readable only by the user, thus the secrets are not leaked by the copies
+// of the credentials file alone.
+type fileCredentialStore struct {
+	path    string
+	keyPath string
+}
+
+func (s fileCredentialStore) Store(name, secret string) error {
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return errors.Wrap(err, "failed to generate the nonce")
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), []byte(name))
+	secrets[name] = base64.StdEncoding.EncodeToString(sealed)
+	return s.dump(secrets)
+}
+
+func (s fileCredentialStore) Get(name string) (string, error) {
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	encoded, ok := secrets[name]
+	if !ok {
+		return "", errors.Newf("cannot find the credential of %s", name)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to decode the credential of %s", name)
+	}
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.Newf("the credential of %s is corrupted", name)
+	}
+	secret, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to decrypt the credential of %s", name)
+	}
+	return string(secret), nil
+}
+
+func (s fileCredentialStore) Erase(name string) error {
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return nil
+	}
+	delete(secrets, name)
+	return s.dump(secrets)
+}
+
+func (s fileCredentialStore) load() (map[string]string, error) {
+	secrets := make(map[string]string)
+	dat, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return secrets, nil
+		}
+		return nil, errors.Wrap(err, "failed to read the credentials file")
+	}
+	if err := json.Unmarshal(dat, &secrets); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the credentials file")
+	}
+	return secrets, nil
+}
+
+func (s fileCredentialStore) dump(secrets map[string]string) error {
+	dat, err := json.Marshal(secrets)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the credentials file")
+	}
+	return errors.Wrap(os.WriteFile(s.path, dat, 0600), "failed to write the credentials file")
+}
+
+// cipher returns the AES-GCM cipher with the key file, the key is
+// generated if it does not exist.
+func (s fileCredentialStore) cipher() (cipher.AEAD, error) {
+	key, err := os.ReadFile(s.keyPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, errors.Wrap(err, "failed to read the credentials key")
+		}
+		key = make([]byte, credentialKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, errors.Wrap(err, "failed to generate the credentials key")
+		}
+		if err := os.WriteFile(s.keyPath, key, 0600); err != nil {
+			return nil, errors.Wrap(err, "failed to write the credentials key")
+		}
+	}
+	if len(key) != credentialKeySize {
+		return nil, errors.Newf("invalid credentials key %s, remove it and login again", s.keyPath)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package home
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker-credential-helpers/credentials"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+const fakeCredentialHelperDirEnv = "ENVD_FAKE_CREDENTIAL_HELPER_DIR"
+
+// fakeCredentialHelper keeps the credentials in a JSON file.
+type fakeCredentialHelper struct {
+	dir string
+}
+
+func (h fakeCredentialHelper) path() string {
+	return filepath.Join(h.dir, "store.json")
+}
+
+func (h fakeCredentialHelper) load() map[string]credentials.Credentials {
+	creds := make(map[string]credentials.Credentials)
+	if dat, err := os.ReadFile(h.path()); err == nil {
+		_ = json.Unmarshal(dat, &creds)
+	}
+	return creds
+}
+
+func (h fakeCredentialHelper) dump(creds map[string]credentials.Credentials) error {
+	dat, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(h.path(), dat, 0600)
+}
+
+func (h fakeCredentialHelper) Add(c *credentials.Credentials) error {
+	creds := h.load()
+	creds[c.ServerURL] = *c
+	return h.dump(creds)
+}
+
+func (h fakeCredentialHelper) Delete(serverURL string) error {
+	creds := h.load()
+	if _, ok := creds[serverURL]; !ok {
+		return credentials.NewErrCredentialsNotFound()
+	}
+	delete(creds, serverURL)
+	return h.dump(creds)
+}
+
+func (h fakeCredentialHelper) Get(serverURL string) (string, string, error) {
+	c, ok := h.load()[serverURL]
+	if !ok {
+		return "", "", credentials.NewErrCredentialsNotFound()
+	}
+	return c.Username, c.Secret, nil
+}
+
+func (h fakeCredentialHelper) List() (map[string]string, error) {
+	res := make(map[string]string)
+	for url, c := range h.load() {
+		res[url] = c.Username
+	}
+	return res, nil
+}
+
+// installFakeCredentialHelper links `docker-credential-fake` to the test
+// binary in the PATH, and returns the dir of the stored credentials.
+func installFakeCredentialHelper() string {
+	bin, err := os.Executable()
+	Expect(err).NotTo(HaveOccurred())
+	dir := GinkgoT().TempDir()
+	Expect(os.Symlink(bin, filepath.Join(dir, "docker-credential-fake"))).To(Succeed())
+	GinkgoT().Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	GinkgoT().Setenv(fakeCredentialHelperDirEnv, dir)
+	return dir
+}
+
+var _ = Describe("credential store", func() {
+	It("Should encrypt the credentials in the file", func() {
+		dir := GinkgoT().TempDir()
+		store := fileCredentialStore{
+			path:    filepath.Join(dir, "credentials"),
+			keyPath: filepath.Join(dir, "credentials.key"),
+		}
+		Expect(store.Store("alice", "secret-token")).To(Succeed())
+		token, err := store.Get("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(Equal("secret-token"))
+
+		dat, err := os.ReadFile(store.path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dat)).NotTo(ContainSubstring("secret-token"))
+
+		// The credentials cannot be decrypted with another key.
+		Expect(os.WriteFile(store.keyPath, make([]byte, credentialKeySize), 0600)).To(Succeed())
+		_, err = store.Get("alice")
+		Expect(err).To(HaveOccurred())
+
+		Expect(store.Erase("alice")).To(Succeed())
+		_, err = store.Get("alice")
+		Expect(err).To(HaveOccurred())
+		Expect(store.Erase("alice")).To(Succeed())
+	})
+
+	It("Should store the credentials with the helper", func() {
+		dir := installFakeCredentialHelper()
+		store := newHelperCredentialStore("fake")
+		Expect(store.Store("alice", "secret-token")).To(Succeed())
+		Expect(filepath.Join(dir, "store.json")).To(BeAnExistingFile())
+		token, err := store.Get("alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).To(Equal("secret-token"))
+
+		Expect(store.Erase("alice")).To(Succeed())
+		_, err = store.Get("alice")
+		Expect(err).To(HaveOccurred())
+		Expect(store.Erase("alice")).To(Succeed())
+	})
+
+	It("Should keep the token out of the auth file", func() {
+		installFakeCredentialHelper()
+		Expect(Initialize()).To(Succeed())
+		m := GetManager()
+		ac := types.AuthConfig{
+			Name:             "credential_helper_user",
+			JWTToken:         "helper-token",
+			CredentialHelper: "fake",
+		}
+		Expect(m.AuthCreate(ac, true)).To(Succeed())
+		current, err := m.AuthGetCurrent()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(current).To(Equal(ac))
+
+		dat, err := os.ReadFile(m.AuthFile())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dat)).NotTo(ContainSubstring("helper-token"))
+
+		Expect(m.AuthRemove(ac.Name)).To(Succeed())
+		auth, err := m.AuthList()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(auth.Current).To(BeEmpty())
+		_, err = newHelperCredentialStore("fake").Get(ac.Name)
+		Expect(err).To(HaveOccurred())
+		Expect(m.AuthRemove(ac.Name)).To(HaveOccurred())
+	})
+})
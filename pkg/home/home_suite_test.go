@@ -15,12 +15,24 @@
 package home
 
 import (
+	"os"
 	"testing"
 
+	"github.com/docker/docker-credential-helpers/credentials"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
+// TestMain runs the test binary as the fake credential helper if it is
+// invoked as `docker-credential-fake`, see fakeCredentialHelper.
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(fakeCredentialHelperDirEnv); dir != "" {
+		credentials.Serve(fakeCredentialHelper{dir: dir})
+		return
+	}
+	os.Exit(m.Run())
+}
+
 func TestManager(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Home Suite")
@@ -38,10 +38,15 @@ type generalManager struct {
 	configFile      string
 	contextFile     string
 	authFile        string
+	credentialFile  string
+	credentialKey   string
 
 	// TODO(gaocegege): Abstract CacheManager.
 	cacheMap map[string]bool
 	context  types.EnvdContext
+	// selected is the context selected by the flag, the environment
+	// variable or the project instead of `context.Current`.
+	selected *ContextSource
 	auth     types.EnvdAuth
 
 	logger *logrus.Entry
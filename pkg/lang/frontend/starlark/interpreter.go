@@ -17,4 +17,6 @@ package starlark
 type Interpreter interface {
 	Eval(script string) (interface{}, error)
 	ExecFile(filename string, funcname string) (interface{}, error)
+	// LoadedModules returns the local files loaded by `load()`.
+	LoadedModules() []string
 }
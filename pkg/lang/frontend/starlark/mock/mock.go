@@ -62,3 +62,17 @@ func (mr *MockInterpreterMockRecorder) ExecFile(filename, funcname interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExecFile", reflect.TypeOf((*MockInterpreter)(nil).ExecFile), filename, funcname)
 }
+
+// LoadedModules mocks base method.
+func (m *MockInterpreter) LoadedModules() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadedModules")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// LoadedModules indicates an expected call of LoadedModules.
+func (mr *MockInterpreterMockRecorder) LoadedModules() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadedModules", reflect.TypeOf((*MockInterpreter)(nil).LoadedModules))
+}
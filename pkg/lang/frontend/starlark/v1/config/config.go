@@ -15,11 +15,15 @@
 package config
 
 import (
+	"net"
+	"time"
+
 	"github.com/cockroachdb/errors"
 	"github.com/sirupsen/logrus"
 	"go.starlark.net/starlark"
 	"go.starlark.net/starlarkstruct"
 
+	irtypes "github.com/tensorchord/envd/pkg/lang/ir"
 	ir "github.com/tensorchord/envd/pkg/lang/ir/v1"
 	"github.com/tensorchord/envd/pkg/util/starlarkutil"
 )
@@ -47,6 +51,10 @@ var Module = &starlarkstruct.Module{
 		"repo":           starlark.NewBuiltin(ruleRepo, ruleFuncRepo),
 		"owner":          starlark.NewBuiltin(ruleOwner, ruleFuncOwner),
 		"shm_size":       starlark.NewBuiltin(ruleShmSize, ruleFuncShmSize),
+		"ssh":            starlark.NewBuiltin(ruleSSH, ruleFuncSSH),
+		"web_terminal":   starlark.NewBuiltin(ruleWebTerminal, ruleFuncWebTerminal),
+		"vscode_server":  starlark.NewBuiltin(ruleVSCodeServer, ruleFuncVSCodeServer),
+		"dotfiles":       starlark.NewBuiltin(ruleDotfiles, ruleFuncDotfiles),
 	},
 }
 
@@ -92,9 +100,12 @@ func ruleFuncJupyter(thread *starlark.Thread, _ *starlark.Builtin,
 	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var token starlark.String
 	var port starlark.Int
+	var kernels, extensions *starlark.List
+	var settings *starlark.Dict
 
 	if err := starlark.UnpackArgs(ruleJupyter, args, kwargs,
-		"token?", &token, "port?", &port); err != nil {
+		"token?", &token, "port?", &port, "kernels?", &kernels,
+		"extensions?", &extensions, "settings?", &settings); err != nil {
 		return nil, err
 	}
 
@@ -104,9 +115,24 @@ func ruleFuncJupyter(thread *starlark.Thread, _ *starlark.Builtin,
 	if !ok {
 		return nil, errors.New("port must be an integer")
 	}
-	logger.Debugf("rule `%s` is invoked, password=%s, port=%d",
-		ruleJupyter, pwdStr, portInt)
-	if err := ir.Jupyter(pwdStr, portInt); err != nil {
+	kernelList, err := starlarkutil.ToStringSlice(kernels)
+	if err != nil {
+		return nil, err
+	}
+	extensionList, err := starlarkutil.ToStringSlice(extensions)
+	if err != nil {
+		return nil, err
+	}
+	var settingsJSON string
+	if settings != nil {
+		settingsJSON, err = starlarkutil.ToJSON(thread, settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+	logger.Debugf("rule `%s` is invoked, password=%s, port=%d, kernels=%v, extensions=%v, settings=%s",
+		ruleJupyter, pwdStr, portInt, kernelList, extensionList, settingsJSON)
+	if err := ir.Jupyter(pwdStr, portInt, kernelList, extensionList, settingsJSON); err != nil {
 		return nil, err
 	}
 
@@ -266,3 +292,155 @@ func ruleFuncOwner(thread *starlark.Thread, _ *starlark.Builtin,
 	ir.Owner(uid, gid)
 	return starlark.None, nil
 }
+
+func ruleFuncSSH(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		portForward        = true
+		sftp               = true
+		maxSessions        int
+		idleTimeout        string
+		localForwardPorts  *starlark.List
+		localForwardHosts  *starlark.List
+		remoteForwardPorts *starlark.List
+		remoteForwardHosts *starlark.List
+	)
+
+	if err := starlark.UnpackArgs(ruleSSH, args, kwargs,
+		"port_forward?", &portForward,
+		"allow_local_forward_ports?", &localForwardPorts,
+		"allow_local_forward_hosts?", &localForwardHosts,
+		"allow_remote_forward_ports?", &remoteForwardPorts,
+		"allow_remote_forward_hosts?", &remoteForwardHosts,
+		"sftp?", &sftp,
+		"max_sessions?", &maxSessions,
+		"idle_timeout?", &idleTimeout); err != nil {
+		return nil, err
+	}
+
+	config := irtypes.SSHConfig{
+		DisablePortForward: !portForward,
+		DisableSFTP:        !sftp,
+		MaxSessions:        maxSessions,
+	}
+	var err error
+	if config.LocalForwardPorts, err = toPortSlice(localForwardPorts); err != nil {
+		return nil, err
+	}
+	if config.RemoteForwardPorts, err = toPortSlice(remoteForwardPorts); err != nil {
+		return nil, err
+	}
+	if config.LocalForwardHosts, err = starlarkutil.ToStringSlice(localForwardHosts); err != nil {
+		return nil, err
+	}
+	if config.RemoteForwardHosts, err = starlarkutil.ToStringSlice(remoteForwardHosts); err != nil {
+		return nil, err
+	}
+	if idleTimeout != "" {
+		if config.IdleTimeout, err = time.ParseDuration(idleTimeout); err != nil {
+			return nil, errors.Wrapf(err, "invalid idle_timeout %s", idleTimeout)
+		}
+	}
+
+	logger.Debugf("rule `%s` is invoked, config=%+v", ruleSSH, config)
+	if err := ir.SSH(config); err != nil {
+		return nil, err
+	}
+	return starlark.None, nil
+}
+
+func ruleFuncWebTerminal(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		hostPort      = 0 // 0 means envd can randomly choose a free port
+		listeningAddr = "127.0.0.1"
+	)
+
+	if err := starlark.UnpackArgs(ruleWebTerminal, args, kwargs,
+		"host_port?", &hostPort, "listen_addr?", &listeningAddr); err != nil {
+		return nil, err
+	}
+	if hostPort < 0 || hostPort > 65535 {
+		return nil, errors.New("host_port must be a positive integer less than 65535")
+	}
+	if net.ParseIP(listeningAddr) == nil {
+		return nil, errors.New("listen_addr must be a valid IP address")
+	}
+
+	logger.Debugf("rule `%s` is invoked, host_port=%d, listen_addr=%s",
+		ruleWebTerminal, hostPort, listeningAddr)
+	if err := ir.WebTerminal(hostPort, listeningAddr); err != nil {
+		return nil, err
+	}
+	return starlark.None, nil
+}
+
+func ruleFuncVSCodeServer(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		hostPort      = 0 // 0 means envd can randomly choose a free port
+		listeningAddr = "127.0.0.1"
+		extensions    *starlark.List
+	)
+
+	if err := starlark.UnpackArgs(ruleVSCodeServer, args, kwargs,
+		"port?", &hostPort, "extensions?", &extensions, "listen_addr?", &listeningAddr); err != nil {
+		return nil, err
+	}
+	if hostPort < 0 || hostPort > 65535 {
+		return nil, errors.New("port must be a positive integer less than 65535")
+	}
+	if net.ParseIP(listeningAddr) == nil {
+		return nil, errors.New("listen_addr must be a valid IP address")
+	}
+	extensionList, err := starlarkutil.ToStringSlice(extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("rule `%s` is invoked, port=%d, extensions=%v, listen_addr=%s",
+		ruleVSCodeServer, hostPort, extensionList, listeningAddr)
+	if err := ir.VSCodeServer(hostPort, listeningAddr, extensionList); err != nil {
+		return nil, err
+	}
+	return starlark.None, nil
+}
+
+func ruleFuncDotfiles(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var repo, ref, installScript string
+	var onStart bool
+
+	if err := starlark.UnpackArgs(ruleDotfiles, args, kwargs,
+		"repo", &repo, "ref?", &ref, "install_script?", &installScript,
+		"on_start?", &onStart); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("rule `%s` is invoked, repo=%s, ref=%s, install_script=%s, on_start=%t",
+		ruleDotfiles, repo, ref, installScript, onStart)
+	if err := ir.Dotfiles(repo, ref, installScript, onStart); err != nil {
+		return nil, err
+	}
+	return starlark.None, nil
+}
+
+// toPortSlice converts the list of the ports, e.g. `[8888, "8000-9000"]`.
+// The empty list is kept non-nil to tell it from None, it denies all the ports.
+func toPortSlice(v *starlark.List) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	ports := []string{}
+	for i := 0; i < v.Len(); i++ {
+		switch p := v.Index(i).(type) {
+		case starlark.Int:
+			ports = append(ports, p.String())
+		case starlark.String:
+			ports = append(ports, p.GoString())
+		default:
+			return nil, errors.Newf("invalid port %s, expected int or string", p.String())
+		}
+	}
+	return ports, nil
+}
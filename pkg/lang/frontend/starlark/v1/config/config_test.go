@@ -0,0 +1,43 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"go.starlark.net/starlark"
+)
+
+func TestToPortSlice(t *testing.T) {
+	ports, err := toPortSlice(nil)
+	if err != nil || ports != nil {
+		t.Errorf("expected nil for None, got %v, %v", ports, err)
+	}
+
+	ports, err = toPortSlice(starlark.NewList(nil))
+	if err != nil || ports == nil || len(ports) != 0 {
+		t.Errorf("expected an empty non-nil list, got %#v, %v", ports, err)
+	}
+
+	ports, err = toPortSlice(starlark.NewList([]starlark.Value{starlark.MakeInt(8888), starlark.String("8000-9000")}))
+	if err != nil || !slices.Equal(ports, []string{"8888", "8000-9000"}) {
+		t.Errorf("unexpected ports %v, %v", ports, err)
+	}
+
+	if _, err = toPortSlice(starlark.NewList([]starlark.Value{starlark.None})); err == nil {
+		t.Errorf("expected an error for the invalid port")
+	}
+}
@@ -27,4 +27,8 @@ const (
 	ruleRepo               = "config.repo"
 	ruleOwner              = "config.owner"
 	ruleShmSize            = "config.shm_size"
+	ruleSSH                = "config.ssh"
+	ruleWebTerminal        = "config.web_terminal"
+	ruleVSCodeServer       = "config.vscode_server"
+	ruleDotfiles           = "config.dotfiles"
 )
@@ -25,17 +25,24 @@ const (
 	ruleRust   = "install.rust"
 	ruleGo     = "install.go"
 	ruleNodeJS = "install.nodejs"
+	ruleJava   = "install.java"
 
 	// packages
 	ruleSystemPackage = "install.apt_packages"
 	rulePyPIPackage   = "install.python_packages"
+	rulePythonProject = "install.python_project"
 	ruleCondaPackages = "install.conda_packages"
 	ruleRPackage      = "install.r_packages"
 	ruleJuliaPackages = "install.julia_packages"
+	ruleNodePackages  = "install.node_packages"
+	ruleNodeProject   = "install.node_project"
+	ruleCargoPackages = "install.cargo_packages"
+	ruleGoTools       = "install.go_tools"
 
 	// others
 	ruleCUDA   = "install.cuda"
 	ruleVSCode = "install.vscode_extensions"
+	ruleEditor = "install.editor"
 
 	// agents
 	ruleCodex = "install.codex"
@@ -41,15 +41,22 @@ var Module = &starlarkstruct.Module{
 		"rust":   starlark.NewBuiltin(ruleRust, ruleFuncRust),
 		"go":     starlark.NewBuiltin(ruleGo, ruleFuncGo),
 		"nodejs": starlark.NewBuiltin(ruleNodeJS, ruleFuncNodeJS),
+		"java":   starlark.NewBuiltin(ruleJava, ruleFuncJava),
 		// packages
 		"apt_packages":    starlark.NewBuiltin(ruleSystemPackage, ruleFuncSystemPackage),
 		"python_packages": starlark.NewBuiltin(rulePyPIPackage, ruleFuncPyPIPackage),
+		"python_project":  starlark.NewBuiltin(rulePythonProject, ruleFuncPythonProject),
 		"conda_packages":  starlark.NewBuiltin(ruleCondaPackages, ruleFuncCondaPackage),
 		"r_packages":      starlark.NewBuiltin(ruleRPackage, ruleFuncRPackage),
 		"julia_packages":  starlark.NewBuiltin(ruleJuliaPackages, ruleFuncJuliaPackage),
+		"node_packages":   starlark.NewBuiltin(ruleNodePackages, ruleFuncNodePackage),
+		"node_project":    starlark.NewBuiltin(ruleNodeProject, ruleFuncNodeProject),
+		"cargo_packages":  starlark.NewBuiltin(ruleCargoPackages, ruleFuncCargoPackage),
+		"go_tools":        starlark.NewBuiltin(ruleGoTools, ruleFuncGoTools),
 		// others
 		"cuda":              starlark.NewBuiltin(ruleCUDA, ruleFuncCUDA),
 		"vscode_extensions": starlark.NewBuiltin(ruleVSCode, ruleFuncVSCode),
+		"editor":            starlark.NewBuiltin(ruleEditor, ruleFuncEditor),
 		// code agents
 		"codex": starlark.NewBuiltin(ruleCodex, ruleFuncCodex),
 	},
@@ -89,14 +96,18 @@ func ruleFuncPixi(thread *starlark.Thread, _ *starlark.Builtin,
 
 	usePixiMirror := false
 	var pypiIndex string
+	var manifest string
+	environment := "default"
 
-	if err := starlark.UnpackArgs(rulePixi, args, kwargs, "use_pixi_mirror?", &usePixiMirror, "pypi_index?", &pypiIndex); err != nil {
+	if err := starlark.UnpackArgs(rulePixi, args, kwargs, "use_pixi_mirror?", &usePixiMirror, "pypi_index?", &pypiIndex,
+		"manifest?", &manifest, "environment?", &environment); err != nil {
 		return nil, err
 	}
 
-	logger.Debugf("rule `%s` is invoked: use_pixi_mirror=%t, pypi_index=%v", rulePixi, usePixiMirror, pypiIndex)
-	ir.Pixi(usePixiMirror, pypiIndex)
-	return starlark.None, nil
+	logger.Debugf("rule `%s` is invoked: use_pixi_mirror=%t, pypi_index=%v, manifest=%s, environment=%s",
+		rulePixi, usePixiMirror, pypiIndex, manifest, environment)
+	err := ir.Pixi(usePixiMirror, pypiIndex, manifest, environment)
+	return starlark.None, err
 }
 
 func ruleFuncUV(thread *starlark.Thread, _ *starlark.Builtin,
@@ -132,11 +143,22 @@ func ruleFuncRust(thread *starlark.Thread, _ *starlark.Builtin,
 	logger.Debugf("rule `%s` is invoked", ruleRust)
 
 	var version string
-	if err := starlark.UnpackArgs(ruleRust, args, kwargs, "version?", &version); err != nil {
+	var components, targets *starlark.List
+	if err := starlark.UnpackArgs(ruleRust, args, kwargs, "version?", &version,
+		"components?", &components, "targets?", &targets); err != nil {
 		return nil, err
 	}
 
-	ir.Rust(version)
+	componentList, err := starlarkutil.ToStringSlice(components)
+	if err != nil {
+		return nil, err
+	}
+	targetList, err := starlarkutil.ToStringSlice(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	ir.Rust(version, componentList, targetList)
 	return starlark.None, nil
 }
 
@@ -166,6 +188,31 @@ func ruleFuncNodeJS(thread *starlark.Thread, _ *starlark.Builtin,
 	return starlark.None, nil
 }
 
+func ruleFuncJava(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var version, kernel string
+	distribution := "temurin"
+	var buildTools *starlark.List
+	var cacheDependencies bool
+	if err := starlark.UnpackArgs(ruleJava, args, kwargs, "version?", &version,
+		"distribution?", &distribution, "build_tools?", &buildTools, "kernel?", &kernel,
+		"cache_dependencies?", &cacheDependencies); err != nil {
+		return nil, err
+	}
+
+	toolList, err := starlarkutil.ToStringSlice(buildTools)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("rule `%s` is invoked, version=%s, distribution=%s, build_tools=%v, kernel=%s, cache_dependencies=%t",
+		ruleJava, version, distribution, toolList, kernel, cacheDependencies)
+
+	if err := ir.Java(version, distribution, toolList, kernel, cacheDependencies); err != nil {
+		return nil, err
+	}
+	return starlark.None, nil
+}
+
 func ruleFuncPyPIPackage(thread *starlark.Thread, _ *starlark.Builtin,
 	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var name *starlark.List
@@ -196,12 +243,37 @@ func ruleFuncPyPIPackage(thread *starlark.Thread, _ *starlark.Builtin,
 	return starlark.None, err
 }
 
+func ruleFuncPythonProject(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	path := "."
+	lock := "uv.lock"
+	var groups *starlark.List
+	editable := true
+
+	if err := starlark.UnpackArgs(rulePythonProject, args, kwargs,
+		"path?", &path, "lock?", &lock, "groups?", &groups, "editable?", &editable); err != nil {
+		return nil, err
+	}
+
+	groupList, err := starlarkutil.ToStringSlice(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("rule `%s` is invoked, path=%s, lock=%s, groups=%v, editable=%t",
+		rulePythonProject, path, lock, groupList, editable)
+
+	err = ir.PythonProject(path, lock, groupList, editable)
+	return starlark.None, err
+}
+
 func ruleFuncRPackage(thread *starlark.Thread, _ *starlark.Builtin,
 	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var name *starlark.List
+	var lockfile string
 
 	if err := starlark.UnpackArgs(ruleRPackage,
-		args, kwargs, "name", &name); err != nil {
+		args, kwargs, "name?", &name, "lockfile?", &lockfile); err != nil {
 		return nil, err
 	}
 
@@ -210,8 +282,8 @@ func ruleFuncRPackage(thread *starlark.Thread, _ *starlark.Builtin,
 		return nil, err
 	}
 
-	logger.Debugf("rule `%s` is invoked, name=%v", ruleRPackage, nameList)
-	err = ir.RPackage(nameList)
+	logger.Debugf("rule `%s` is invoked, name=%v, lockfile=%s", ruleRPackage, nameList, lockfile)
+	err = ir.RPackage(nameList, lockfile)
 
 	return starlark.None, err
 }
@@ -219,8 +291,84 @@ func ruleFuncRPackage(thread *starlark.Thread, _ *starlark.Builtin,
 func ruleFuncJuliaPackage(thread *starlark.Thread, _ *starlark.Builtin,
 	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var name *starlark.List
+	var project, manifest string
 
 	if err := starlark.UnpackArgs(ruleJuliaPackages,
+		args, kwargs, "name?", &name, "project?", &project, "manifest?", &manifest); err != nil {
+		return nil, err
+	}
+
+	nameList, err := starlarkutil.ToStringSlice(name)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("rule `%s` is invoked, name=%v, project=%s, manifest=%s",
+		ruleJuliaPackages, nameList, project, manifest)
+	err = ir.JuliaPackage(nameList, project, manifest)
+
+	return starlark.None, err
+}
+
+func ruleFuncNodePackage(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name *starlark.List
+	global := true
+
+	if err := starlark.UnpackArgs(ruleNodePackages,
+		args, kwargs, "name", &name, "global?", &global); err != nil {
+		return nil, err
+	}
+
+	nameList, err := starlarkutil.ToStringSlice(name)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("rule `%s` is invoked, name=%v, global=%t", ruleNodePackages, nameList, global)
+	err = ir.NodePackage(nameList, global)
+
+	return starlark.None, err
+}
+
+func ruleFuncNodeProject(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	path := "."
+	lock := "package-lock.json"
+
+	if err := starlark.UnpackArgs(ruleNodeProject,
+		args, kwargs, "path?", &path, "lock?", &lock); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("rule `%s` is invoked, path=%s, lock=%s", ruleNodeProject, path, lock)
+	err := ir.NodeProject(path, lock)
+
+	return starlark.None, err
+}
+
+func ruleFuncCargoPackage(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name *starlark.List
+
+	if err := starlark.UnpackArgs(ruleCargoPackages,
+		args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+
+	nameList, err := starlarkutil.ToStringSlice(name)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("rule `%s` is invoked, name=%v", ruleCargoPackages, nameList)
+	err = ir.CargoPackage(nameList)
+
+	return starlark.None, err
+}
+
+func ruleFuncGoTools(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name *starlark.List
+
+	if err := starlark.UnpackArgs(ruleGoTools,
 		args, kwargs, "name", &name); err != nil {
 		return nil, err
 	}
@@ -229,8 +377,8 @@ func ruleFuncJuliaPackage(thread *starlark.Thread, _ *starlark.Builtin,
 	if err != nil {
 		return nil, err
 	}
-	logger.Debugf("rule `%s` is invoked, name=%v", ruleJuliaPackages, nameList)
-	err = ir.JuliaPackage(nameList)
+	logger.Debugf("rule `%s` is invoked, name=%v", ruleGoTools, nameList)
+	err = ir.GoTools(nameList)
 
 	return starlark.None, err
 }
@@ -293,6 +441,30 @@ func ruleFuncVSCode(thread *starlark.Thread, _ *starlark.Builtin,
 	return starlark.None, nil
 }
 
+func ruleFuncEditor(thread *starlark.Thread, _ *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name, version, product, config, sync string
+
+	if err := starlark.UnpackArgs(ruleEditor, args, kwargs,
+		"name", &name, "version?", &version, "product?", &product,
+		"config?", &config, "sync?", &sync); err != nil {
+		return nil, err
+	}
+
+	options := map[string]string{}
+	for k, v := range map[string]string{"product": product, "config": config, "sync": sync} {
+		if v != "" {
+			options[k] = v
+		}
+	}
+	logger.Debugf("rule `%s` is invoked, name=%s, version=%s, options=%v",
+		ruleEditor, name, version, options)
+	if err := ir.Editor(name, version, options); err != nil {
+		return nil, err
+	}
+	return starlark.None, nil
+}
+
 func ruleFuncCondaPackage(thread *starlark.Thread, _ *starlark.Builtin,
 	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	var name, channel *starlark.List
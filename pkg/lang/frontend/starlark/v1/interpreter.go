@@ -20,6 +20,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -193,6 +194,18 @@ func (s generalInterpreter) ExecFile(filename string, funcname string) (interfac
 	return globals, nil
 }
 
+func (s generalInterpreter) LoadedModules() []string {
+	modules := []string{}
+	for module := range s.cache {
+		if strings.HasPrefix(module, universe.GitPrefix) {
+			continue
+		}
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
 func (s generalInterpreter) Eval(script string) (interface{}, error) {
 	thread := s.NewThread(script)
 	return starlark.ExecFileOptions(envdStarlarkResolveOptions(), thread, "", script, s.predeclared)
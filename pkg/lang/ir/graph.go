@@ -42,6 +42,7 @@ type graphDebugger interface {
 type graphVisitor interface {
 	GetDepsFiles(deps []string) []string
 	GPUEnabled() bool
+	SSHAgentRequired() bool
 	GetNumGPUs() int
 	GetShmSize() int
 	IsDev() bool
@@ -53,11 +54,15 @@ type graphVisitor interface {
 	GetMount() []MountInfo
 	GetJupyterConfig() *JupyterConfig
 	GetRStudioServerConfig() *RStudioServerConfig
+	GetEditors() []EditorConfig
 	GetExposedPorts() []ExposeItem
 	DefaultCacheImporter() (*string, error)
 	GetEnviron() []string
 	GetHTTP() []HTTPInfo
 	GetRuntimeCommands() map[string]string
+	GetRuntimeGraph() *RuntimeGraph
+	GetRuntimeServices() []ServiceFile
+	GetBuildGraphHash() (string, error)
 	GetUser() string
 	GetPlatform() *specs.Platform
 	GetWorkingDir() string
@@ -15,7 +15,13 @@
 package ir
 
 import (
+	"fmt"
+	"path/filepath"
+	"time"
+
 	"github.com/opencontainers/go-digest"
+
+	"github.com/tensorchord/envd/pkg/types"
 )
 
 // The results during runtime should be maintained here
@@ -28,6 +34,35 @@ type RuntimeGraph struct {
 	RuntimeExpose     []ExposeItem      `json:"expose,omitempty"`
 }
 
+// ServiceFile is the supervisor service definition of the runtime process.
+type ServiceFile struct {
+	Name    string
+	Content string
+	// Script is run by the service, it is read again when the service is
+	// restarted, thus it can be changed without restarting the supervisor.
+	Script string
+}
+
+// InitServiceName returns the service name of the i-th init script.
+func InitServiceName(i int) string {
+	return fmt.Sprintf("init_%d", i)
+}
+
+// DaemonServiceName returns the service name of the i-th daemon.
+func DaemonServiceName(i int) string {
+	return fmt.Sprintf("daemon_%d", i)
+}
+
+// ServiceScriptPath returns the path of the script run by the service.
+func ServiceScriptPath(name string) string {
+	return filepath.Join(types.HorustScriptDir, name+".sh")
+}
+
+// ServicePIDPath returns the path of the pid file written by the daemon launcher.
+func ServicePIDPath(name string) string {
+	return filepath.Join(types.HorustSocketDir, name+".pid")
+}
+
 type CopyInfo struct {
 	Source      string
 	Destination string
@@ -48,6 +83,26 @@ type HTTPInfo struct {
 type RStudioServerConfig struct {
 }
 
+// DotfilesConfig is the dotfiles repository installed for the user of the environment.
+type DotfilesConfig struct {
+	Repo string
+	// Ref is the branch, tag or commit, the default branch is used if it's empty.
+	Ref string
+	// InstallScript is relative to the repository, the common installers like
+	// `install.sh` are detected if it's empty.
+	InstallScript string
+	// OnStart clones the repository at the first start instead of the build.
+	OnStart bool
+}
+
+// EditorConfig is the editor installed by `install.editor`, the options are
+// specific to the editor, see `pkg/editor`.
+type EditorConfig struct {
+	Name    string
+	Version string
+	Options map[string]string
+}
+
 type Language struct {
 	Name    string
 	Version *string
@@ -70,9 +125,45 @@ type UVConfig struct {
 	PythonVersion string
 }
 
+type PythonProjectConfig struct {
+	// Path is the project directory relative to the build context.
+	Path string
+	// Lock is the lock file relative to Path. Its name selects the tool.
+	Lock     string
+	Groups   []string
+	Editable bool
+}
+
+type NodePackageConfig struct {
+	Name   []string
+	Global bool
+}
+
+type NodeProjectConfig struct {
+	// Dir is the project directory relative to the build context. It's not named
+	// `Path` to keep it out of the dependency files of the graph.
+	Dir string
+	// Lock is the lock file relative to Dir. Its name selects the package manager.
+	Lock string
+}
+
+type JavaConfig struct {
+	Distribution string
+	BuildTools   []string
+	// Kernel is the JVM language of the Jupyter kernel, e.g. scala or kotlin.
+	Kernel string
+	// CacheDependencies mounts the dependency caches of the build tools in the
+	// `run` commands, thus the downloaded dependencies are not in the image.
+	CacheDependencies bool
+}
+
 type PixiConfig struct {
 	UsePixiMirror bool
 	PyPIIndex     *string
+	// Manifest is the pixi.toml or pyproject.toml relative to the build context.
+	// The pixi.lock next to it is installed with `pixi install --frozen`.
+	Manifest    string
+	Environment string
 }
 
 type GitConfig struct {
@@ -91,6 +182,27 @@ type ExposeItem struct {
 type JupyterConfig struct {
 	Token string
 	Port  int64
+	// Kernels restricts the registered kernels, all the kernels of the
+	// installed languages are registered if it's empty.
+	Kernels []string
+	// Extensions are the JupyterLab extensions installed from PyPI.
+	Extensions []string
+	// Settings is the JSON content of the JupyterLab `overrides.json`.
+	Settings string
+}
+
+// SSHConfig restricts the envd-sshd in the environment.
+type SSHConfig struct {
+	DisablePortForward bool
+	// LocalForwardPorts and RemoteForwardPorts allow only the ports if they
+	// are not nil, thus an empty list denies all the ports.
+	LocalForwardPorts  []string
+	LocalForwardHosts  []string
+	RemoteForwardPorts []string
+	RemoteForwardHosts []string
+	DisableSFTP        bool
+	MaxSessions        int
+	IdleTimeout        time.Duration
 }
 
 type RunBuildCommand struct {
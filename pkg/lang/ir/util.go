@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/cockroachdb/errors"
 	"github.com/containers/image/v5/docker"
@@ -72,3 +73,96 @@ func (rg *RuntimeGraph) Load(code []byte) error {
 	}
 	return nil
 }
+
+// Reloadable returns the part of the runtime graph that can be applied to the
+// running environment without rebuilding the image. The environ and the
+// exposed ports are fixed when the container is created.
+func (rg RuntimeGraph) Reloadable() RuntimeGraph {
+	return RuntimeGraph{
+		RuntimeCommands:   rg.RuntimeCommands,
+		RuntimeDaemon:     rg.RuntimeDaemon,
+		RuntimeInitScript: rg.RuntimeInitScript,
+	}
+}
+
+// WithReloadable returns a copy of the runtime graph with the reloadable part
+// replaced by the one from the other graph.
+func (rg RuntimeGraph) WithReloadable(other RuntimeGraph) RuntimeGraph {
+	rg.RuntimeCommands = other.RuntimeCommands
+	rg.RuntimeDaemon = other.RuntimeDaemon
+	rg.RuntimeInitScript = other.RuntimeInitScript
+	return rg
+}
+
+// ChangedServices returns the names of the init scripts and the daemons whose
+// commands are changed in the other graph. All the daemons are changed if any
+// init script is changed, since the daemons start after the init scripts.
+func (rg RuntimeGraph) ChangedServices(other RuntimeGraph) (inits []string, daemons []string) {
+	for i, command := range other.RuntimeInitScript {
+		if i >= len(rg.RuntimeInitScript) || !slices.Equal(rg.RuntimeInitScript[i], command) {
+			inits = append(inits, InitServiceName(i))
+		}
+	}
+	for i, command := range other.RuntimeDaemon {
+		if len(inits) != 0 || i >= len(rg.RuntimeDaemon) || !slices.Equal(rg.RuntimeDaemon[i], command) {
+			daemons = append(daemons, DaemonServiceName(i))
+		}
+	}
+	return inits, daemons
+}
+
+// NewReloadableCode dumps the reloadable part of the runtime graph.
+func NewReloadableCode(rg RuntimeGraph) (string, error) {
+	reloadable := rg.Reloadable()
+	code, err := reloadable.Dump()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to dump the runtime graph")
+	}
+	return code, nil
+}
+
+// GraphChange is the kind of the changes between two graphs.
+type GraphChange string
+
+const (
+	// GraphChangeNone means the graphs are the same.
+	GraphChangeNone GraphChange = "none"
+	// GraphChangeRuntime means only the reloadable part of the runtime graph
+	// is changed, thus it can be applied to the running environment.
+	GraphChangeRuntime GraphChange = "runtime"
+	// GraphChangeBuild means the image needs to be rebuilt.
+	GraphChangeBuild GraphChange = "build"
+)
+
+// GraphFingerprint identifies the build part and the reloadable runtime part
+// of the graph.
+type GraphFingerprint struct {
+	BuildHash   string
+	RuntimeCode string
+}
+
+func NewGraphFingerprint(g Graph) (GraphFingerprint, error) {
+	buildHash, err := g.GetBuildGraphHash()
+	if err != nil {
+		return GraphFingerprint{}, errors.Wrap(err, "failed to get the build graph hash")
+	}
+	runtimeCode, err := NewReloadableCode(*g.GetRuntimeGraph())
+	if err != nil {
+		return GraphFingerprint{}, err
+	}
+	return GraphFingerprint{
+		BuildHash:   buildHash,
+		RuntimeCode: runtimeCode,
+	}, nil
+}
+
+// Diff returns the kind of changes from f to the other fingerprint.
+func (f GraphFingerprint) Diff(other GraphFingerprint) GraphChange {
+	if f.BuildHash != other.BuildHash {
+		return GraphChangeBuild
+	}
+	if f.RuntimeCode != other.RuntimeCode {
+		return GraphChangeRuntime
+	}
+	return GraphChangeNone
+}
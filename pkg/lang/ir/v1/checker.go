@@ -15,6 +15,7 @@
 package v1
 
 import (
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strings"
@@ -24,6 +25,26 @@ func (g generalGraph) GetDepsFiles(deps []string) []string {
 	tHandle := reflect.TypeOf(g)
 	vHandle := reflect.ValueOf(g)
 	deps = searchFileInGraph(tHandle, vHandle, deps)
+	for _, project := range g.PythonProjects {
+		deps = append(deps,
+			filepath.Join(project.Path, pythonProjectManifest),
+			filepath.Join(project.Path, project.Lock))
+	}
+	if g.NodeProject != nil {
+		deps = append(deps,
+			filepath.Join(g.NodeProject.Dir, nodeManifest),
+			filepath.Join(g.NodeProject.Dir, g.NodeProject.Lock))
+	}
+	// The *string fields are skipped by searchFileInGraph.
+	for _, file := range []*string{g.RLockFile, g.JuliaProjectFile, g.JuliaManifestFile} {
+		if file != nil {
+			deps = append(deps, *file)
+		}
+	}
+	if g.PixiConfig != nil && g.PixiConfig.Manifest != "" {
+		deps = append(deps, g.PixiConfig.Manifest,
+			filepath.Join(filepath.Dir(g.PixiConfig.Manifest), pixiLockFile))
+	}
 	return deps
 }
 
@@ -0,0 +1,35 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetDepsFilesLockFiles(t *testing.T) {
+	rLock, juliaProject, juliaManifest := "renv.lock", "Project.toml", "Manifest.toml"
+	g := generalGraph{
+		RLockFile:         &rLock,
+		JuliaProjectFile:  &juliaProject,
+		JuliaManifestFile: &juliaManifest,
+	}
+	deps := g.GetDepsFiles(nil)
+	for _, expected := range []string{rLock, juliaProject, juliaManifest} {
+		if !slices.Contains(deps, expected) {
+			t.Errorf("expected %s in the dependency files %v", expected, deps)
+		}
+	}
+}
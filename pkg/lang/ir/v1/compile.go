@@ -110,6 +110,10 @@ func (g generalGraph) GetRStudioServerConfig() *ir.RStudioServerConfig {
 	return g.RStudioServerConfig
 }
 
+func (g generalGraph) GetEditors() []ir.EditorConfig {
+	return g.Editors
+}
+
 func (g generalGraph) GetExposedPorts() []ir.ExposeItem {
 	return g.RuntimeExpose
 }
@@ -136,6 +140,12 @@ func (g *generalGraph) Compile(ctx context.Context, envPath string, pub string,
 		return nil, errors.Wrap(err, "failed to get the current envd context")
 	}
 
+	// The compilation modifies the graph, thus the hash is recorded in the
+	// labels to tell if the image can be reused by the later manifest.
+	g.buildHash, err = g.GetBuildGraphHash()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the build graph hash")
+	}
 	g.Writer = w
 	g.EnvironmentPath = envPath
 	g.EnvironmentName = filepath.Base(envPath)
@@ -194,11 +204,64 @@ func (g generalGraph) Labels() (map[string]string, error) {
 		return nil, err
 	}
 	labels[types.ImageLabelPyPI] = string(str)
-	str, err = json.Marshal(g.RPackages)
+	rPackages := g.RPackages
+	if g.RLockFile != nil {
+		locked, err := readRLockfile(filepath.Join(g.EnvironmentPath, *g.RLockFile))
+		if err != nil {
+			return nil, err
+		}
+		rPackages = append(rPackages, locked)
+	}
+	str, err = json.Marshal(rPackages)
 	if err != nil {
 		return nil, err
 	}
 	labels[types.ImageLabelR] = string(str)
+	juliaPackages := g.JuliaPackages
+	if g.JuliaManifestFile != nil {
+		locked, err := readJuliaManifest(filepath.Join(g.EnvironmentPath, *g.JuliaManifestFile))
+		if err != nil {
+			return nil, err
+		}
+		juliaPackages = append(juliaPackages, locked)
+	}
+	if len(juliaPackages) > 0 {
+		str, err = json.Marshal(juliaPackages)
+		if err != nil {
+			return nil, err
+		}
+		labels[types.ImageLabelJulia] = string(str)
+	}
+	nodePackages := [][]string{}
+	for _, packages := range g.NodePackages {
+		nodePackages = append(nodePackages, packages.Name)
+	}
+	if g.NodeProject != nil {
+		locked, err := readNodeProjectPackages(
+			filepath.Join(g.EnvironmentPath, g.NodeProject.Dir), g.NodeProject.Lock)
+		if err != nil {
+			return nil, err
+		}
+		nodePackages = append(nodePackages, locked)
+	}
+	if len(nodePackages) > 0 {
+		str, err = json.Marshal(nodePackages)
+		if err != nil {
+			return nil, err
+		}
+		labels[types.ImageLabelNode] = string(str)
+	}
+	if g.JupyterConfig != nil {
+		kernels, err := g.jupyterKernels()
+		if err != nil {
+			return nil, err
+		}
+		str, err = json.Marshal(kernels)
+		if err != nil {
+			return nil, err
+		}
+		labels[types.ImageLabelJupyterKernel] = string(str)
+	}
 	if g.GPUEnabled() {
 		labels[types.ImageLabelGPU] = "true"
 		labels[types.ImageLabelCUDA] = *g.CUDA
@@ -216,6 +279,9 @@ func (g generalGraph) Labels() (map[string]string, error) {
 		return labels, err
 	}
 	labels[types.GeneralGraphCode] = code
+	if g.buildHash != "" {
+		labels[types.ImageLabelBuildGraphHash] = g.buildHash
+	}
 
 	ports := []servertypes.EnvironmentPort{}
 	ports = append(ports, servertypes.EnvironmentPort{
@@ -344,7 +410,12 @@ func (g *generalGraph) CompileLLB(uid, gid int) (llb.State, error) {
 		dev := g.compileDevPackages(aptMirror)
 		sshd := g.compileSSHD(dev)
 		horust := g.installHorust(sshd)
-		starship := g.compileStarship(horust)
+		vscodeServer := g.installVSCodeServer(horust)
+		editors, err := g.installEditors(vscodeServer)
+		if err != nil {
+			return llb.State{}, errors.Wrap(err, "failed to install editors")
+		}
+		starship := g.compileStarship(editors)
 		userGroup := g.compileUserGroup(starship)
 		aptMirror = userGroup
 	}
@@ -367,12 +438,21 @@ func (g *generalGraph) CompileLLB(uid, gid int) (llb.State, error) {
 			llb.Diff(base, lang, llb.WithCustomName("[internal] prepare language")),
 		}, llb.WithCustomName("[internal] language environment and system packages"))
 	}
+	if err := g.compileJupyter(); err != nil {
+		return llb.State{}, errors.Wrap(err, "failed to compile jupyter")
+	}
 	packages := g.compileLanguagePackages(language)
+	projects, err := g.compilePythonProjects(packages)
+	if err != nil {
+		return llb.State{}, errors.Wrap(err, "failed to compile python projects")
+	}
+
+	pixi, err := g.compilePixiProject(projects)
 	if err != nil {
-		return llb.State{}, errors.Wrap(err, "failed to compile language")
+		return llb.State{}, errors.Wrap(err, "failed to compile pixi project")
 	}
 
-	source := g.compileExtraSource(packages)
+	source := g.compileExtraSource(pixi)
 	copy := g.compileCopy(source)
 	agent := g.compileAgent(copy)
 
@@ -380,7 +460,11 @@ func (g *generalGraph) CompileLLB(uid, gid int) (llb.State, error) {
 	if g.Dev {
 		git := g.compileGit(agent)
 		user := g.compileUserOwn(git)
-		key, err := g.copySSHKey(user)
+		editors, err := g.setupEditors(user)
+		if err != nil {
+			return llb.State{}, errors.Wrap(err, "failed to setup editors")
+		}
+		key, err := g.copySSHKey(editors)
 		if err != nil {
 			return llb.State{}, errors.Wrap(err, "failed to copy ssh key")
 		}
@@ -393,7 +477,8 @@ func (g *generalGraph) CompileLLB(uid, gid int) (llb.State, error) {
 			// install uv Python for dev user
 			prompt = g.compileUVPython(prompt)
 		}
-		entrypoint, err := g.compileEntrypoint(prompt)
+		dotfiles := g.compileDotfiles(prompt)
+		entrypoint, err := g.compileEntrypoint(dotfiles)
 		if err != nil {
 			return llb.State{}, errors.Wrap(err, "failed to compile entrypoint")
 		}
@@ -404,8 +489,13 @@ func (g *generalGraph) CompileLLB(uid, gid int) (llb.State, error) {
 		agent = vscode
 	}
 
+	// the projects are installed at last since the whole build context is mounted
+	projectSources, err := g.compilePythonProjectSources(agent)
+	if err != nil {
+		return llb.State{}, errors.Wrap(err, "failed to compile python project sources")
+	}
 	// it's necessary to exec `run` with the desired user
-	run := g.compileRun(agent)
+	run := g.compileRun(projectSources)
 	mount := g.compileMountDir(run)
 
 	g.Writer.Finish()
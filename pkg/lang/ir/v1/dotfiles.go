@@ -0,0 +1,124 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/moby/buildkit/client/llb"
+
+	"github.com/tensorchord/envd/pkg/util/fileutil"
+)
+
+const (
+	dotfilesDir       = ".dotfiles"
+	dotfilesScriptDir = "/tmp/envd/dotfiles"
+	dotfilesService   = "dotfiles"
+	// dotfilesInstallTemplate runs the install script in the repository. The common
+	// installers are detected like GitHub Codespaces if the script is not specified,
+	// and the dotfiles are linked to the home directory if there is no installer.
+	dotfilesInstallTemplate = `cd "$HOME/%[1]s"
+installer="%[2]s"
+if [ -z "$installer" ]; then
+  for f in install.sh install bootstrap.sh bootstrap script/bootstrap setup.sh setup script/setup; do
+    if [ -f "$f" ]; then installer="$f"; break; fi
+  done
+fi
+if [ -n "$installer" ]; then
+  chmod +x "$installer" && "./$installer"
+else
+  for f in .[!.]*; do
+    if [ -e "$f" ] && [ "$f" != .git ]; then ln -sf "$PWD/$f" "$HOME/$f"; fi
+  done
+fi
+`
+	// dotfilesStartTemplate clones the repository at the first start, and the
+	// installer is rerun only if the revision of the repository changes.
+	dotfilesStartTemplate = `dir="$HOME/%[1]s"
+if [ ! -d "$dir" ]; then
+  git clone "%[2]s" "$dir"
+  if [ -n "%[3]s" ]; then git -C "$dir" checkout "%[3]s"; fi
+fi
+rev=$(git -C "$dir" rev-parse HEAD)
+if [ "$(cat "$dir/.git/envd-installed" 2>/dev/null || true)" != "$rev" ]; then
+  (
+%[4]s
+  )
+  echo "$rev" > "$dir/.git/envd-installed"
+fi`
+)
+
+// scpLikeURL matches the scp-like syntax of the ssh remote, e.g. `git@github.com:user/repo`.
+var scpLikeURL = regexp.MustCompile(`^[A-Za-z0-9_.-]+@[^/:]+:`)
+
+// isSSHURL returns true if the repository is cloned with ssh.
+func isSSHURL(repo string) bool {
+	return strings.HasPrefix(repo, "ssh://") || scpLikeURL.MatchString(repo)
+}
+
+// SSHAgentRequired returns true if the host ssh agent is needed to fetch the
+// dotfiles repository at build time.
+func (g generalGraph) SSHAgentRequired() bool {
+	return g.Dotfiles != nil && !g.Dotfiles.OnStart && isSSHURL(g.Dotfiles.Repo)
+}
+
+func (g generalGraph) homeDir() string {
+	if g.uid == 0 {
+		return "/root"
+	}
+	return fileutil.EnvdHomeDir()
+}
+
+func (g generalGraph) dotfilesInstallScript() string {
+	return fmt.Sprintf(dotfilesInstallTemplate, dotfilesDir, g.Dotfiles.InstallScript)
+}
+
+// compileDotfiles installs the dotfiles as the user at build time. The repository
+// is resolved to the revision by buildkit, thus the installer is cached until the
+// revision changes.
+func (g generalGraph) compileDotfiles(root llb.State) llb.State {
+	if g.Dotfiles == nil || g.Dotfiles.OnStart {
+		return root
+	}
+
+	home := g.homeDir()
+	repo := llb.Git(g.Dotfiles.Repo, g.Dotfiles.Ref, llb.KeepGitDir(),
+		llb.WithCustomNamef("[internal] fetch dotfiles %s", g.Dotfiles.Repo))
+	script := llb.Scratch().File(
+		llb.Mkfile("dotfiles.sh", 0755, []byte(g.dotfilesInstallScript())),
+		llb.WithCustomName("[internal] generate dotfiles install script"))
+	root = root.File(
+		llb.Copy(repo, "/", filepath.Join(home, dotfilesDir),
+			&llb.CopyInfo{CreateDestPath: true}, llb.WithUIDGID(g.uid, g.gid)),
+		llb.WithCustomNamef("[internal] copy dotfiles %s", g.Dotfiles.Repo))
+	run := root.Run(llb.Shlexf("bash %s/dotfiles.sh", dotfilesScriptDir),
+		llb.AddEnv("HOME", home),
+		llb.WithCustomNamef("[internal] install dotfiles %s", g.Dotfiles.Repo))
+	run.AddMount(dotfilesScriptDir, script, llb.Readonly)
+	return run.Root()
+}
+
+// dotfilesStartScript returns the script that installs the dotfiles at start,
+// it's empty if the dotfiles are installed at build time.
+func (g generalGraph) dotfilesStartScript() string {
+	if g.Dotfiles == nil || !g.Dotfiles.OnStart {
+		return ""
+	}
+	return fmt.Sprintf(dotfilesStartTemplate, dotfilesDir, g.Dotfiles.Repo, g.Dotfiles.Ref,
+		g.dotfilesInstallScript())
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tensorchord/envd/pkg/lang/ir"
+)
+
+func TestDotfilesInstallScript(t *testing.T) {
+	g := generalGraph{Dotfiles: &ir.DotfilesConfig{Repo: "https://github.com/user/dotfiles"}}
+	script := g.dotfilesInstallScript()
+	if !strings.Contains(script, `installer=""`) || !strings.Contains(script, "bootstrap.sh") {
+		t.Errorf("expected the installer to be detected in %s", script)
+	}
+
+	g.Dotfiles.InstallScript = "scripts/install.sh"
+	if script := g.dotfilesInstallScript(); !strings.Contains(script, `installer="scripts/install.sh"`) {
+		t.Errorf("expected the install script in %s", script)
+	}
+	if script := g.dotfilesStartScript(); script != "" {
+		t.Errorf("expected no start script for the build time dotfiles, got %s", script)
+	}
+}
+
+func TestDotfilesStartService(t *testing.T) {
+	g := generalGraph{Dotfiles: &ir.DotfilesConfig{
+		Repo:    "https://github.com/user/dotfiles",
+		Ref:     "main",
+		OnStart: true,
+	}}
+	services := g.GetRuntimeServices()
+	if len(services) != 1 || services[0].Name != dotfilesService {
+		t.Fatalf("expected the dotfiles service, got %v", services)
+	}
+	for _, expected := range []string{
+		`git clone "https://github.com/user/dotfiles" "$dir"`,
+		`git -C "$dir" checkout "main"`,
+		`echo "$rev" > "$dir/.git/envd-installed"`,
+	} {
+		if !strings.Contains(services[0].Script, expected) {
+			t.Errorf("expected %s in %s", expected, services[0].Script)
+		}
+	}
+	if strings.Contains(g.dotfilesStartScript(), "'") {
+		t.Errorf("the start script is quoted by single quotes")
+	}
+}
+
+func TestDotfilesSSHAgentRequired(t *testing.T) {
+	tcs := []struct {
+		repo     string
+		onStart  bool
+		expected bool
+	}{
+		{repo: "https://github.com/user/dotfiles", expected: false},
+		{repo: "git@github.com:user/dotfiles.git", expected: true},
+		{repo: "ssh://git@github.com/user/dotfiles.git", expected: true},
+		{repo: "git@github.com:user/dotfiles.git", onStart: true, expected: false},
+	}
+	for _, tc := range tcs {
+		g := generalGraph{Dotfiles: &ir.DotfilesConfig{Repo: tc.repo, OnStart: tc.onStart}}
+		if got := g.SSHAgentRequired(); got != tc.expected {
+			t.Errorf("%s (on start: %t): expected %t, got %t", tc.repo, tc.onStart, tc.expected, got)
+		}
+	}
+	if (generalGraph{}).SSHAgentRequired() {
+		t.Errorf("expected no ssh agent without the dotfiles")
+	}
+}
+
+func TestDotfilesValidation(t *testing.T) {
+	DefaultGraph = NewGraph()
+	if err := Dotfiles("", "", "", false); err == nil {
+		t.Errorf("expected an error without the repo")
+	}
+	if err := Dotfiles("https://github.com/user/dotfiles", "", "../install.sh", false); err == nil {
+		t.Errorf("expected an error for the install script outside the repo")
+	}
+	if err := Dotfiles("git@github.com:user/dotfiles.git", "", "", true); err == nil {
+		t.Errorf("expected an error for the ssh repo cloned on start")
+	}
+	if err := Dotfiles("https://github.com/user/dotfiles", "", "install.sh", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Dotfiles("git@github.com:user/dotfiles.git", "", "", false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := DefaultGraph.(*generalGraph)
+	if g.Dotfiles.Repo != "git@github.com:user/dotfiles.git" || g.Dotfiles.OnStart {
+		t.Errorf("expected the later call to override the dotfiles, got %+v", g.Dotfiles)
+	}
+}
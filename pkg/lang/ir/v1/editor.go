@@ -16,12 +16,14 @@ package v1
 
 import (
 	"fmt"
+	"path/filepath"
 	"strconv"
 
 	"github.com/cockroachdb/errors"
 	"github.com/moby/buildkit/client/llb"
 
 	"github.com/tensorchord/envd/pkg/config"
+	"github.com/tensorchord/envd/pkg/editor"
 	"github.com/tensorchord/envd/pkg/editor/vscode"
 	"github.com/tensorchord/envd/pkg/flag"
 	"github.com/tensorchord/envd/pkg/progress/compileui"
@@ -57,14 +59,74 @@ func (g generalGraph) compileVSCode(root llb.State) (llb.State, error) {
 	return root, nil
 }
 
-// nolint:unused
-func (g *generalGraph) compileJupyter() {
+// installEditors downloads the editors of `install.editor` into the image.
+func (g *generalGraph) installEditors(root llb.State) (llb.State, error) {
+	for _, cfg := range g.Editors {
+		e, err := editor.New(cfg.Name, cfg.Version, cfg.Options)
+		if err != nil {
+			return llb.State{}, err
+		}
+		tmpDir := filepath.Join("/tmp", "editor", e.Name())
+		builder := llb.Image(curlImage).Run(
+			llb.Shlexf(`sh -c "%s || exit 1"`, e.DownloadScript(tmpDir)),
+			llb.WithCustomNamef("[internal] download %s %s", e.Name(), e.Version()),
+		).Root()
+		root = root.File(
+			llb.Copy(builder, tmpDir, e.InstallDir(), &llb.CopyInfo{CreateDestPath: true}),
+			llb.WithCustomNamef("[internal] install %s %s", e.Name(), e.Version()))
+		if bin := e.BinDir(); bin != "" {
+			root = g.updateEnvPath(root, bin)
+		}
+	}
+	return root, nil
+}
+
+// setupEditors configures the editors as the user, e.g. syncing the plugins.
+func (g generalGraph) setupEditors(root llb.State) (llb.State, error) {
+	for _, cfg := range g.Editors {
+		e, err := editor.New(cfg.Name, cfg.Version, cfg.Options)
+		if err != nil {
+			return llb.State{}, err
+		}
+		script := e.SetupScript()
+		if script == "" {
+			continue
+		}
+		root = root.Run(llb.Shlexf(`bash -c "%s"`, script),
+			llb.AddEnv("HOME", g.homeDir()),
+			llb.WithCustomNamef("[internal] setup %s", e.Name())).Root()
+	}
+	return root, nil
+}
+
+func (g *generalGraph) compileJupyter() error {
 	if g.JupyterConfig == nil {
-		return
+		return nil
+	}
+	kernels, err := g.jupyterKernels()
+	if err != nil {
+		return err
 	}
 
 	// no need to check if `python` is installed since v1 should support user costumed image
 	g.PyPIPackages = append(g.PyPIPackages, []string{"jupyter"})
+	if len(g.JupyterConfig.Extensions) > 0 {
+		g.PyPIPackages = append(g.PyPIPackages, g.JupyterConfig.Extensions)
+	}
+	for _, kernel := range kernels {
+		switch kernel {
+		case "r":
+			g.RPackages = append(g.RPackages, []string{"IRkernel"})
+		case "julia":
+			g.JuliaPackages = append(g.JuliaPackages, []string{"IJulia"})
+		case "rust":
+			g.CargoPackages = append(g.CargoPackages, []string{"evcxr_jupyter"})
+		case "go":
+			g.GoTools = append(g.GoTools,
+				[]string{"github.com/gopherdata/gophernotes@" + gophernotesVersion})
+		}
+	}
+	return nil
 }
 
 func (g generalGraph) generateJupyterCommand(workingDir string) []string {
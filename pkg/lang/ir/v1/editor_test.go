@@ -95,3 +95,20 @@ func equal(a, b []string) bool {
 	}
 	return true
 }
+
+func TestGenerateVSCodeServerCommand(t *testing.T) {
+	if cmd := (generalGraph{}).generateVSCodeServerCommand(); cmd != "" {
+		t.Errorf("expected no command without the vscode server, got %s", cmd)
+	}
+
+	cmd := generalGraph{VSCodeServer: true}.generateVSCodeServerCommand()
+	expected := `/bin/bash -c '(umask 077 && mkdir -p /home/envd/.vscode-server && ` +
+		`printf "%s" "${ENVD_VSCODE_SERVER_TOKEN:?}" > /home/envd/.vscode-server/connection-token) && ` +
+		`unset ENVD_VSCODE_SERVER_TOKEN && ` +
+		`exec /opt/openvscode-server/bin/openvscode-server --host 0.0.0.0 --port 2224 ` +
+		`--connection-token-file /home/envd/.vscode-server/connection-token --extensions-dir /home/envd/.vscode-server/extensions ` +
+		`--default-folder "${ENVD_WORKDIR}" --telemetry-level off'`
+	if cmd != expected {
+		t.Errorf("failed to generate the command: expected %s, got %s", expected, cmd)
+	}
+}
@@ -15,6 +15,8 @@
 package v1
 
 import (
+	"strings"
+
 	"github.com/moby/buildkit/client/llb"
 )
 
@@ -22,6 +24,10 @@ const (
 	golangDefaultVersion = "1.25.3"
 	golangFilePath       = "/tmp/golang.linux.tar.gz"
 	golangHomeBin        = "/usr/local/go/bin"
+	// golangToolsBin holds the binaries installed by `install.go_tools`.
+	golangToolsBin = "/opt/go/bin"
+	golangModCache = "/root/go/pkg/mod"
+	golangBuildDir = "/root/.cache/go-build"
 )
 
 func (g *generalGraph) installGolang(root llb.State, version *string) llb.State {
@@ -46,3 +52,40 @@ func (g *generalGraph) installGolang(root llb.State, version *string) llb.State
 	g.RuntimeEnvPaths = append(g.RuntimeEnvPaths, golangHomeBin)
 	return root
 }
+
+// installGoTools installs the tools with `go install`. The module cache and the
+// build cache are kept in the cache mounts.
+func (g *generalGraph) installGoTools(root llb.State) llb.State {
+	if len(g.GoTools) == 0 {
+		return root
+	}
+
+	g.RuntimeEnvPaths = append(g.RuntimeEnvPaths, golangToolsBin)
+	for _, tools := range g.GoTools {
+		specs := make([]string, 0, len(tools))
+		for _, tool := range tools {
+			specs = append(specs, goToolSpec(tool))
+		}
+		run := root.Run(
+			llb.Shlexf("%s/go install %s", golangHomeBin, strings.Join(specs, " ")),
+			llb.AddEnv("GOBIN", golangToolsBin),
+			llb.AddEnv("GOMODCACHE", golangModCache),
+			llb.AddEnv("GOCACHE", golangBuildDir),
+			llb.AddEnv("PATH", strings.Join(g.RuntimeEnvPaths, ":")),
+			llb.WithCustomNamef("[internal] installing go tools: %s", strings.Join(tools, " ")))
+		for _, cacheDir := range []string{golangModCache, golangBuildDir} {
+			run.AddMount(cacheDir, llb.Scratch(),
+				llb.AsPersistentCacheDir(g.CacheID(cacheDir), llb.CacheMountShared))
+		}
+		root = run.Root()
+	}
+	return root
+}
+
+// goToolSpec adds `@latest` since `go install` requires the version outside a module.
+func goToolSpec(tool string) string {
+	if strings.Contains(tool, "@") {
+		return tool
+	}
+	return tool + "@latest"
+}
@@ -15,12 +15,15 @@
 package v1
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/opencontainers/go-digest"
 	"github.com/sirupsen/logrus"
 
+	"github.com/tensorchord/envd/pkg/config"
+	"github.com/tensorchord/envd/pkg/editor"
 	"github.com/tensorchord/envd/pkg/editor/vscode"
 	"github.com/tensorchord/envd/pkg/lang/ir"
 	"github.com/tensorchord/envd/pkg/types"
@@ -58,7 +61,14 @@ func Conda(mamba bool) {
 	}
 }
 
-func Pixi(usePixiMirror bool, pypiIndex string) {
+func Pixi(usePixiMirror bool, pypiIndex, manifest, environment string) error {
+	if manifest != "" && !filepath.IsLocal(manifest) {
+		return errors.Newf("pixi manifest %s must be relative to the build context", manifest)
+	}
+	if manifest != "" && environment == "" {
+		return errors.New("pixi environment is required")
+	}
+
 	g := DefaultGraph.(*generalGraph)
 
 	g.PixiConfig = &ir.PixiConfig{
@@ -68,6 +78,11 @@ func Pixi(usePixiMirror bool, pypiIndex string) {
 	if len(pypiIndex) != 0 {
 		g.PixiConfig.PyPIIndex = &pypiIndex
 	}
+	if manifest != "" {
+		g.PixiConfig.Manifest = filepath.Clean(manifest)
+		g.PixiConfig.Environment = environment
+	}
+	return nil
 }
 
 func UV(pythonVersion string) {
@@ -94,7 +109,7 @@ func Julia() {
 	})
 }
 
-func Rust(version string) {
+func Rust(version string, components, targets []string) {
 	g := DefaultGraph.(*generalGraph)
 
 	rust := ir.Language{Name: "rust"}
@@ -102,6 +117,8 @@ func Rust(version string) {
 		rust.Version = &version
 	}
 	g.Languages = append(g.Languages, rust)
+	g.RustComponents = append(g.RustComponents, components...)
+	g.RustTargets = append(g.RustTargets, targets...)
 }
 
 func Golang(version string) {
@@ -114,6 +131,39 @@ func Golang(version string) {
 	g.Languages = append(g.Languages, golang)
 }
 
+func Java(version, distribution string, buildTools []string, kernel string, cacheDependencies bool) error {
+	if _, ok := javaDistributions[distribution]; !ok {
+		return errors.Newf("Java distribution %s is not supported, expect temurin, corretto or microsoft", distribution)
+	}
+	for _, tool := range buildTools {
+		if _, ok := javaBuildTools[tool]; !ok {
+			return errors.Newf("Java build tool %s is not supported, expect maven, gradle or sbt", tool)
+		}
+	}
+	if kernel != "" && kernel != javaKernelScala && kernel != javaKernelKotlin {
+		return errors.Newf("Jupyter kernel %s is not supported, expect scala or kotlin", kernel)
+	}
+
+	g := DefaultGraph.(*generalGraph)
+
+	java := ir.Language{Name: "java"}
+	if len(version) > 0 {
+		java.Version = &version
+	}
+	g.Languages = append(g.Languages, java)
+	g.JavaConfig = &ir.JavaConfig{
+		Distribution: distribution,
+		BuildTools:   buildTools,
+		Kernel:       kernel,
+
+		CacheDependencies: cacheDependencies,
+	}
+	if kernel == javaKernelKotlin {
+		g.PyPIPackages = append(g.PyPIPackages, []string{"kotlin-jupyter-kernel"})
+	}
+	return nil
+}
+
 func NodeJS(version string) {
 	g := DefaultGraph.(*generalGraph)
 
@@ -139,32 +189,147 @@ func PyPIPackage(deps []string, requirementsFile string, wheels []string) error
 	return nil
 }
 
-func RPackage(deps []string) error {
+func PythonProject(path, lock string, groups []string, editable bool) error {
+	if path == "" {
+		path = "."
+	}
+	if !filepath.IsLocal(path) {
+		return errors.Newf("project path %s must be relative to the build context", path)
+	}
+	if !filepath.IsLocal(filepath.Join(path, lock)) {
+		return errors.Newf("lock file %s must be inside the project %s", lock, path)
+	}
+	if _, err := pythonProjectTool(lock); err != nil {
+		return err
+	}
+
+	g := DefaultGraph.(*generalGraph)
+
+	g.PythonProjects = append(g.PythonProjects, ir.PythonProjectConfig{
+		Path:     filepath.Clean(path),
+		Lock:     filepath.Clean(lock),
+		Groups:   groups,
+		Editable: editable,
+	})
+	return nil
+}
+
+func RPackage(deps []string, lockfile string) error {
 
-	if len(deps) == 0 {
+	if len(deps) == 0 && lockfile == "" {
 		return errors.New("Can not install empty R package")
 	}
+	if lockfile != "" && !filepath.IsLocal(lockfile) {
+		return errors.Newf("renv lockfile %s must be relative to the build context", lockfile)
+	}
 
 	g := DefaultGraph.(*generalGraph)
 
-	g.RPackages = append(g.RPackages, deps)
+	if len(deps) > 0 {
+		g.RPackages = append(g.RPackages, deps)
+	}
+	if lockfile != "" {
+		lockfile = filepath.Clean(lockfile)
+		g.RLockFile = &lockfile
+	}
 
 	return nil
 }
 
-func JuliaPackage(deps []string) error {
+func JuliaPackage(deps []string, project, manifest string) error {
 
-	if len(deps) == 0 {
+	if len(deps) == 0 && project == "" {
 		return errors.New("Can not install empty Julia package")
 	}
+	if project == "" && manifest != "" {
+		return errors.New("Julia manifest requires the project file")
+	}
 
 	g := DefaultGraph.(*generalGraph)
 
-	g.JuliaPackages = append(g.JuliaPackages, deps)
+	if len(deps) > 0 {
+		g.JuliaPackages = append(g.JuliaPackages, deps)
+	}
+	if project != "" {
+		if manifest == "" {
+			manifest = filepath.Join(filepath.Dir(project), juliaManifestName)
+		}
+		for _, f := range []string{project, manifest} {
+			if !filepath.IsLocal(f) {
+				return errors.Newf("Julia project file %s must be relative to the build context", f)
+			}
+		}
+		project = filepath.Clean(project)
+		manifest = filepath.Clean(manifest)
+		g.JuliaProjectFile = &project
+		g.JuliaManifestFile = &manifest
+	}
 
 	return nil
 }
 
+func NodePackage(deps []string, global bool) error {
+	if len(deps) == 0 {
+		return errors.New("Can not install empty Node.js package")
+	}
+
+	g := DefaultGraph.(*generalGraph)
+
+	g.NodePackages = append(g.NodePackages, ir.NodePackageConfig{
+		Name:   deps,
+		Global: global,
+	})
+	return nil
+}
+
+func NodeProject(path, lock string) error {
+	if path == "" {
+		path = "."
+	}
+	if !filepath.IsLocal(path) {
+		return errors.Newf("project path %s must be relative to the build context", path)
+	}
+	if !filepath.IsLocal(filepath.Join(path, lock)) {
+		return errors.Newf("lock file %s must be inside the project %s", lock, path)
+	}
+	if _, err := nodeProjectTool(lock); err != nil {
+		return err
+	}
+
+	g := DefaultGraph.(*generalGraph)
+
+	if g.NodeProject != nil {
+		return errors.New("only one Node.js project is supported")
+	}
+	g.NodeProject = &ir.NodeProjectConfig{
+		Dir:  filepath.Clean(path),
+		Lock: filepath.Clean(lock),
+	}
+	return nil
+}
+
+func CargoPackage(deps []string) error {
+	if len(deps) == 0 {
+		return errors.New("Can not install empty cargo package")
+	}
+
+	g := DefaultGraph.(*generalGraph)
+
+	g.CargoPackages = append(g.CargoPackages, deps)
+	return nil
+}
+
+func GoTools(deps []string) error {
+	if len(deps) == 0 {
+		return errors.New("Can not install empty Go tool")
+	}
+
+	g := DefaultGraph.(*generalGraph)
+
+	g.GoTools = append(g.GoTools, deps)
+	return nil
+}
+
 func SystemPackage(deps []string) {
 	g := DefaultGraph.(*generalGraph)
 
@@ -192,6 +357,26 @@ func CUDA(version, cudnn string) {
 	}
 }
 
+func Editor(name, version string, options map[string]string) error {
+	if _, err := editor.New(name, version, options); err != nil {
+		return err
+	}
+
+	g := DefaultGraph.(*generalGraph)
+
+	for _, e := range g.Editors {
+		if e.Name == name {
+			return errors.Newf("the editor %s is already installed", name)
+		}
+	}
+	g.Editors = append(g.Editors, ir.EditorConfig{
+		Name:    name,
+		Version: version,
+		Options: options,
+	})
+	return nil
+}
+
 func VSCodePlugins(plugins []string) error {
 	g := DefaultGraph.(*generalGraph)
 
@@ -251,12 +436,22 @@ func Shell(shell string) error {
 	return nil
 }
 
-func Jupyter(pwd string, port int64) error {
+func Jupyter(pwd string, port int64, kernels, extensions []string, settings string) error {
+	for _, kernel := range kernels {
+		if _, ok := jupyterKernelLanguages[kernel]; !ok {
+			return errors.Newf("Jupyter kernel %s is not supported, expect one of %s",
+				kernel, strings.Join(supportedJupyterKernels(), ", "))
+		}
+	}
+
 	g := DefaultGraph.(*generalGraph)
 
 	g.JupyterConfig = &ir.JupyterConfig{
-		Token: pwd,
-		Port:  port,
+		Token:      pwd,
+		Port:       port,
+		Kernels:    kernels,
+		Extensions: extensions,
+		Settings:   settings,
 	}
 	return nil
 }
@@ -268,6 +463,86 @@ func RStudioServer() error {
 	return nil
 }
 
+func SSH(sshConfig ir.SSHConfig) error {
+	g := DefaultGraph.(*generalGraph)
+
+	for _, ports := range [][]string{sshConfig.LocalForwardPorts, sshConfig.RemoteForwardPorts} {
+		for _, p := range ports {
+			if _, err := types.ParsePortRange(p); err != nil {
+				return err
+			}
+		}
+	}
+	for _, hosts := range [][]string{sshConfig.LocalForwardHosts, sshConfig.RemoteForwardHosts} {
+		for _, h := range hosts {
+			if h == "" || strings.ContainsAny(h, " \t\n'\",") {
+				return errors.Newf("invalid host %q", h)
+			}
+		}
+	}
+	if sshConfig.MaxSessions < 0 {
+		return errors.New("max_sessions must not be negative")
+	}
+	if sshConfig.IdleTimeout < 0 {
+		return errors.New("idle_timeout must not be negative")
+	}
+	g.SSHConfig = &sshConfig
+	return nil
+}
+
+// WebTerminal exposes the web terminal served by envd-sshd.
+func WebTerminal(hostPort int, listeningAddr string) error {
+	g := DefaultGraph.(*generalGraph)
+
+	if g.WebTerminal {
+		return errors.New("the web terminal is already enabled")
+	}
+	g.WebTerminal = true
+	return RuntimeExpose(config.WebTerminalPortInContainer, hostPort,
+		config.WebTerminalService, listeningAddr)
+}
+
+// VSCodeServer exposes the OpenVSCode Server with the extensions.
+func VSCodeServer(hostPort int, listeningAddr string, extensions []string) error {
+	g := DefaultGraph.(*generalGraph)
+
+	if g.VSCodeServer {
+		return errors.New("the vscode server is already enabled")
+	}
+	if err := VSCodePlugins(extensions); err != nil {
+		return err
+	}
+	g.VSCodeServer = true
+	return RuntimeExpose(config.VSCodeServerPortInContainer, hostPort,
+		config.VSCodeServerService, listeningAddr)
+}
+
+// Dotfiles installs the dotfiles repository for the user. It's usually called in
+// the user's `config.envd`, and the later call overrides the former one.
+func Dotfiles(repo, ref, installScript string, onStart bool) error {
+	if repo == "" {
+		return errors.New("the dotfiles repo is required")
+	}
+	if installScript != "" && !filepath.IsLocal(installScript) {
+		return errors.Newf("the install script %s must be a relative path in the repo", installScript)
+	}
+	// The ssh agent is only forwarded at build time, there are no credentials
+	// to clone the repository in the container.
+	if onStart && isSSHURL(repo) {
+		return errors.Newf("the ssh repo %s cannot be cloned on start, use the https URL instead", repo)
+	}
+
+	g := DefaultGraph.(*generalGraph)
+
+	g.Dotfiles = &ir.DotfilesConfig{
+		Repo:          repo,
+		Ref:           ref,
+		InstallScript: installScript,
+		OnStart:       onStart,
+	}
+	return nil
+}
+
 func Run(commands []string, mount bool) error {
 	g := DefaultGraph.(*generalGraph)
 
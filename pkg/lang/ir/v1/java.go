@@ -0,0 +1,185 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/moby/buildkit/client/llb"
+)
+
+const (
+	javaDefaultVersion = "17"
+	javaTempDir        = "/tmp/java"
+	javaHomeDir        = "/opt/java"
+	javaHomeBin        = "/opt/java/bin"
+	// javaDownloadCacheDir keeps the archives of the pinned build tools.
+	javaDownloadCacheDir = "/var/cache/envd/java"
+
+	javaKernelScala  = "scala"
+	javaKernelKotlin = "kotlin"
+
+	// https://github.com/coursier/coursier
+	coursierVersion  = "2.1.24"
+	coursierCacheDir = "/root/.cache/coursier"
+	// https://github.com/almond-sh/almond
+	almondVersion      = "0.14.1"
+	almondScalaVersion = "2.13.16"
+	almondDir          = "/opt/almond"
+)
+
+// javaDistributions maps the distribution to the URL of the latest JDK archive,
+// `%[1]s` is the major version and `%[2]s` is the architecture (x64 or aarch64).
+var javaDistributions = map[string]string{
+	"temurin":   "https://api.adoptium.net/v3/binary/latest/%[1]s/ga/linux/%[2]s/jdk/hotspot/normal/eclipse",
+	"corretto":  "https://corretto.aws/downloads/latest/amazon-corretto-%[1]s-%[2]s-linux-jdk.tar.gz",
+	"microsoft": "https://aka.ms/download-jdk/microsoft-jdk-%[1]s-linux-%[2]s.tar.gz",
+}
+
+type javaBuildTool struct {
+	version string
+	// url of the release archive, `%[1]s` is the version.
+	url string
+	// cacheDirs keep the downloaded dependencies, relative to the home directory.
+	cacheDirs []string
+}
+
+var javaBuildTools = map[string]javaBuildTool{
+	"maven": {
+		version:   "3.9.11",
+		url:       "https://archive.apache.org/dist/maven/maven-3/%[1]s/binaries/apache-maven-%[1]s-bin.tar.gz",
+		cacheDirs: []string{".m2"},
+	},
+	"gradle": {
+		version:   "8.14.3",
+		url:       "https://services.gradle.org/distributions/gradle-%[1]s-bin.zip",
+		cacheDirs: []string{".gradle"},
+	},
+	"sbt": {
+		version:   "1.11.7",
+		url:       "https://github.com/sbt/sbt/releases/download/v%[1]s/sbt-%[1]s.tgz",
+		cacheDirs: []string{".ivy2", ".cache/coursier"},
+	},
+}
+
+// javaBuildToolScript downloads the archive into the cache directory if it is
+// not there yet, then extracts it into `/tmp/<name>`.
+func javaBuildToolScript(name string, tool javaBuildTool) string {
+	url := fmt.Sprintf(tool.url, tool.version)
+	archive := filepath.Join(javaDownloadCacheDir, path.Base(url))
+	target := filepath.Join("/tmp", name)
+
+	extract := fmt.Sprintf("tar -xzf %s --strip-components=1 -C %s", archive, target)
+	if strings.HasSuffix(archive, ".zip") {
+		extract = fmt.Sprintf("unzip -q %[1]s -d /tmp/unzip && mv /tmp/unzip/*/* %[2]s", archive, target)
+	}
+	return fmt.Sprintf("mkdir -p %[1]s && ([ -f %[2]s ] || (wget -qO %[2]s.part %[3]s && mv %[2]s.part %[2]s)) && %[4]s",
+		target, archive, url, extract)
+}
+
+func (g *generalGraph) installJava(root llb.State, version *string) llb.State {
+	javaVersion := javaDefaultVersion
+	if version != nil {
+		javaVersion = *version
+	}
+	distribution := g.JavaConfig.Distribution
+	url := fmt.Sprintf(javaDistributions[distribution], javaVersion, "$(uname -m | sed -e 's/x86_64/x64/')")
+
+	base := llb.Image(curlImage)
+	builder := base.Run(
+		llb.Shlexf(`sh -c "mkdir %[1]s && wget -qO- %[2]s | tar -xz --strip-components=1 -C %[1]s || exit 1"`, javaTempDir, url),
+		llb.WithCustomNamef("[internal] download %s jdk %s", distribution, javaVersion),
+	).Root()
+
+	root = root.File(
+		llb.Copy(builder, javaTempDir, javaHomeDir),
+		llb.WithCustomNamef("[internal] prepare %s jdk %s", distribution, javaVersion),
+	).AddEnv("JAVA_HOME", javaHomeDir)
+	g.RuntimeEnviron["JAVA_HOME"] = javaHomeDir
+	return g.updateEnvPath(root, javaHomeBin)
+}
+
+// installJavaPackages installs the build tools and the Jupyter kernel for the JVM languages.
+func (g *generalGraph) installJavaPackages(root llb.State) llb.State {
+	if g.JavaConfig == nil {
+		return root
+	}
+
+	for _, name := range g.JavaConfig.BuildTools {
+		tool := javaBuildTools[name]
+		download := llb.Image(curlImage).Run(
+			llb.Shlexf(`sh -c "%s || exit 1"`, javaBuildToolScript(name, tool)),
+			llb.WithCustomNamef("[internal] download %s %s", name, tool.version),
+		)
+		download.AddMount(javaDownloadCacheDir, llb.Scratch(),
+			llb.AsPersistentCacheDir(g.CacheID(javaDownloadCacheDir), llb.CacheMountShared))
+		builder := download.Root()
+		dir := filepath.Join("/opt", name)
+		root = root.File(
+			llb.Copy(builder, filepath.Join("/tmp", name), dir),
+			llb.WithCustomNamef("[internal] install %s %s", name, tool.version),
+		)
+		root = g.updateEnvPath(root, filepath.Join(dir, "bin"))
+	}
+
+	if g.JavaConfig.Kernel == javaKernelScala {
+		root = g.installAlmond(root)
+	}
+	return root
+}
+
+// addJavaCacheMounts keeps the dependencies downloaded by the build tools in the
+// cache mounts, which are owned by the user running the commands. It's opt-in
+// since the files written to the cache directories are not kept in the image.
+func (g generalGraph) addJavaCacheMounts(run llb.ExecState) {
+	if g.JavaConfig == nil || !g.JavaConfig.CacheDependencies {
+		return
+	}
+	// Refer to https://github.com/moby/buildkit/blob/31054718bf775bf32d1376fe1f3611985f837584/frontend/dockerfile/dockerfile2llb/convert_runmount.go#L46
+	cacheMount := llb.Scratch().File(llb.Mkdir("/cache-java", 0755, llb.WithUIDGID(g.uid, g.gid)),
+		llb.WithCustomName("[internal] setting java cache mount permissions"))
+	for _, name := range g.JavaConfig.BuildTools {
+		for _, dir := range javaBuildTools[name].cacheDirs {
+			cacheDir := filepath.Join(g.homeDir(), dir)
+			run.AddMount(cacheDir, cacheMount,
+				llb.AsPersistentCacheDir(g.CacheID(cacheDir), llb.CacheMountShared), llb.SourcePath("/cache-java"))
+		}
+	}
+}
+
+// installAlmond installs the almond Scala kernel as a standalone launcher, thus the
+// dependencies don't need to be fetched at runtime.
+func (g generalGraph) installAlmond(root llb.State) llb.State {
+	builder := llb.Image(curlImage).Run(
+		llb.Shlexf(`sh -c "wget -qO- https://github.com/coursier/coursier/releases/download/v%s/cs-$(uname -m)-pc-linux.gz | gzip -d > /tmp/cs && chmod +x /tmp/cs || exit 1"`, coursierVersion),
+		llb.WithCustomNamef("[internal] download coursier %s", coursierVersion),
+	).Root()
+
+	launcher := filepath.Join(almondDir, "almond")
+	run := root.Run(
+		llb.Shlexf(`sh -c "mkdir -p %[1]s && /tmp/envd/cs/cs bootstrap --standalone almond:%[2]s --scala %[3]s -o %[4]s && %[4]s --install --global --force"`,
+			almondDir, almondVersion, almondScalaVersion, launcher),
+		llb.AddEnv("COURSIER_CACHE", coursierCacheDir),
+		llb.AddEnv("PATH", strings.Join(g.RuntimeEnvPaths, ":")),
+		llb.WithCustomNamef("[internal] install almond %s scala kernel", almondVersion),
+	)
+	run.AddMount("/tmp/envd/cs", builder, llb.SourcePath("/tmp"), llb.Readonly)
+	run.AddMount(coursierCacheDir, llb.Scratch(),
+		llb.AsPersistentCacheDir(g.CacheID(coursierCacheDir), llb.CacheMountShared))
+	return run.Root()
+}
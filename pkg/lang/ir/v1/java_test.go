@@ -0,0 +1,95 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/moby/buildkit/client/llb"
+
+	"github.com/tensorchord/envd/pkg/lang/ir"
+)
+
+func TestJavaBuildToolScript(t *testing.T) {
+	tcs := []struct {
+		name     string
+		archive  string
+		contains string
+	}{
+		{name: "maven", archive: "apache-maven-3.9.11-bin.tar.gz", contains: "tar -xzf /var/cache/envd/java/apache-maven-3.9.11-bin.tar.gz --strip-components=1 -C /tmp/maven"},
+		{name: "gradle", archive: "gradle-8.14.3-bin.zip", contains: "unzip -q /var/cache/envd/java/gradle-8.14.3-bin.zip"},
+		{name: "sbt", archive: "sbt-1.11.7.tgz", contains: "-C /tmp/sbt"},
+	}
+
+	for _, tc := range tcs {
+		script := javaBuildToolScript(tc.name, javaBuildTools[tc.name])
+		if !strings.Contains(script, "[ -f /var/cache/envd/java/"+tc.archive+" ]") {
+			t.Errorf("%s: archive %s is not cached: %s", tc.name, tc.archive, script)
+		}
+		if !strings.Contains(script, tc.contains) {
+			t.Errorf("%s: expected %q in %s", tc.name, tc.contains, script)
+		}
+	}
+}
+
+func TestJavaValidation(t *testing.T) {
+	DefaultGraph = NewGraph()
+	if err := Java("17", "oracle", nil, "", false); err == nil {
+		t.Errorf("expected an error for an unsupported distribution")
+	}
+	if err := Java("17", "temurin", []string{"ant"}, "", false); err == nil {
+		t.Errorf("expected an error for an unsupported build tool")
+	}
+	if err := Java("17", "temurin", nil, "clojure", false); err == nil {
+		t.Errorf("expected an error for an unsupported kernel")
+	}
+	if err := Java("21", "corretto", []string{"maven"}, javaKernelKotlin, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := DefaultGraph.(*generalGraph)
+	if g.JavaConfig == nil || g.JavaConfig.Distribution != "corretto" {
+		t.Errorf("unexpected java config: %+v", g.JavaConfig)
+	}
+	if len(g.PyPIPackages) != 1 || g.PyPIPackages[0][0] != "kotlin-jupyter-kernel" {
+		t.Errorf("expected the kotlin kernel in the PyPI packages, got %v", g.PyPIPackages)
+	}
+}
+
+func TestJavaCacheMounts(t *testing.T) {
+	for _, cacheDependencies := range []bool{false, true} {
+		g := generalGraph{JavaConfig: &ir.JavaConfig{
+			BuildTools:        []string{"maven"},
+			CacheDependencies: cacheDependencies,
+		}}
+		run := llb.Image("ubuntu:22.04").Run(llb.Shlex("mvn dependency:go-offline"))
+		g.addJavaCacheMounts(run)
+		def, err := run.Root().Marshal(context.TODO())
+		if err != nil {
+			t.Fatal(err)
+		}
+		mounted := false
+		for _, dt := range def.Def {
+			if bytes.Contains(dt, []byte("/root/.m2")) {
+				mounted = true
+			}
+		}
+		if mounted != cacheDependencies {
+			t.Errorf("expected the maven cache mount to be %t, got %t", cacheDependencies, mounted)
+		}
+	}
+}
@@ -18,9 +18,14 @@ import (
 	_ "embed"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/BurntSushi/toml"
+	"github.com/cockroachdb/errors"
 	"github.com/moby/buildkit/client/llb"
+
+	"github.com/tensorchord/envd/pkg/flag"
 )
 
 const (
@@ -28,6 +33,12 @@ const (
 	juliaBinDir  = "/opt/julia/bin"           // Location of Julia executable binary file
 	juliaPkgDir  = "/opt/julia/user_packages" // Location of additional packages installed via Julia
 	juliaBinName = "julia.tar.gz"             // Julia archive name
+
+	juliaProjectName  = "Project.toml"
+	juliaManifestName = "Manifest.toml"
+	juliaProjectDir   = "/opt/julia/project" // Holds the project and manifest activated in the environment
+	// The registries are kept in a cache mount since they are only needed to resolve packages
+	juliaRegistriesDir = "/opt/julia/user_packages/registries"
 )
 
 //go:embed julia.sh
@@ -64,7 +75,7 @@ func (g *generalGraph) installJulia(root llb.State) llb.State {
 // installJuliaPackages returns the llb.State only after installing required Julia packages
 // A successful run of installJuliaPackages should install Julia packages under "/opt/julia/user_packages" and export the path
 func (g *generalGraph) installJuliaPackages(root llb.State) llb.State {
-	if len(g.JuliaPackages) == 0 {
+	if len(g.JuliaPackages) == 0 && g.JuliaProjectFile == nil {
 		return root
 	}
 
@@ -85,5 +96,68 @@ func (g *generalGraph) installJuliaPackages(root llb.State) llb.State {
 			Run(llb.Shlex(command), llb.WithCustomNamef("[internal] installing Julia packages: %s", strings.Join(packages, " ")))
 		root = run.Root()
 	}
-	return root
+	return g.instantiateJuliaProject(root)
+}
+
+// instantiateJuliaProject installs the exact versions in the Julia manifest into the depot.
+// The project is copied into the image and activated by `JULIA_PROJECT`, thus it's used
+// in the environment even if the build context is not mounted.
+func (g *generalGraph) instantiateJuliaProject(root llb.State) llb.State {
+	if g.JuliaProjectFile == nil {
+		return root
+	}
+
+	project, manifest := *g.JuliaProjectFile, *g.JuliaManifestFile
+	src := llb.Local(flag.FlagBuildContext, llb.IncludePatterns([]string{project, manifest}),
+		llb.SharedKeyHint("julia-project"))
+	root = root.
+		File(llb.Copy(src, project, filepath.Join(juliaProjectDir, juliaProjectName),
+			&llb.CopyInfo{CreateDestPath: true}, llb.WithUIDGID(g.uid, g.gid)),
+			llb.WithCustomNamef("[internal] copy %s", project)).
+		File(llb.Copy(src, manifest, filepath.Join(juliaProjectDir, juliaManifestName),
+			&llb.CopyInfo{CreateDestPath: true}, llb.WithUIDGID(g.uid, g.gid)),
+			llb.WithCustomNamef("[internal] copy %s", manifest))
+	g.RuntimeEnviron["JULIA_PROJECT"] = juliaProjectDir
+	// The packages can be added to the project by the users
+	g.UserDirectories = append(g.UserDirectories, juliaProjectDir)
+
+	run := root.Run(
+		llb.Shlexf(`julia --project=%s -e 'using Pkg; Pkg.instantiate()'`, juliaProjectDir),
+		llb.WithCustomNamef("[internal] instantiating Julia project from %s", manifest))
+	run.AddMount(juliaRegistriesDir, llb.Scratch(),
+		llb.AsPersistentCacheDir(g.CacheID(juliaRegistriesDir), llb.CacheMountShared))
+	return run.Root()
+}
+
+// readJuliaManifest returns the sorted `name==version` of the packages in the Julia manifest.
+// Both the v1 (packages at the top level) and v2 (packages in `deps`) formats are supported.
+// The standard libraries without versions are skipped.
+func readJuliaManifest(path string) ([]string, error) {
+	type entry struct {
+		Version string `toml:"version"`
+	}
+	var manifest struct {
+		ManifestFormat string             `toml:"manifest_format"`
+		Deps           map[string][]entry `toml:"deps"`
+	}
+	if _, err := toml.DecodeFile(path, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the Julia manifest %s", path)
+	}
+	deps := manifest.Deps
+	if manifest.ManifestFormat == "" {
+		if _, err := toml.DecodeFile(path, &deps); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the Julia manifest %s", path)
+		}
+	}
+
+	var packages []string
+	for name, entries := range deps {
+		for _, e := range entries {
+			if e.Version != "" {
+				packages = append(packages, fmt.Sprintf("%s==%s", name, e.Version))
+			}
+		}
+	}
+	sort.Strings(packages)
+	return packages, nil
 }
@@ -0,0 +1,171 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/moby/buildkit/client/llb"
+)
+
+const (
+	jupyterDataDir     = "/usr/local/share/jupyter"
+	jupyterSettingsDir = "/tmp/envd/jupyter"
+	// https://github.com/gopherdata/gophernotes
+	gophernotesVersion = "v0.7.5"
+	gophernotesKernel  = `{
+  "argv": ["%s", "{connection_file}"],
+  "display_name": "Go",
+  "language": "go",
+  "name": "go"
+}
+`
+)
+
+// jupyterKernelLanguages maps the Jupyter kernel to the language that provides it.
+var jupyterKernelLanguages = map[string]string{
+	"python":         "python",
+	"r":              "r",
+	"julia":          "julia",
+	"rust":           "rust",
+	"go":             "go",
+	javaKernelScala:  "java",
+	javaKernelKotlin: "java",
+}
+
+func supportedJupyterKernels() []string {
+	kernels := make([]string, 0, len(jupyterKernelLanguages))
+	for kernel := range jupyterKernelLanguages {
+		kernels = append(kernels, kernel)
+	}
+	slices.Sort(kernels)
+	return kernels
+}
+
+// jupyterKernels returns the kernels registered in the image. The Python kernel
+// comes with Jupyter and the JVM kernel is configured by `install.java`, thus
+// they are always registered. The other kernels are registered for all the
+// installed languages unless they are restricted by `config.jupyter(kernels=...)`.
+func (g generalGraph) jupyterKernels() ([]string, error) {
+	if g.JupyterConfig == nil {
+		return nil, nil
+	}
+
+	kernels := []string{"python"}
+	installed := map[string]bool{}
+	for _, language := range g.Languages {
+		switch language.Name {
+		case "r", "julia", "rust", "go":
+			installed[language.Name] = true
+		}
+	}
+	javaKernel := ""
+	if g.JavaConfig != nil {
+		javaKernel = g.JavaConfig.Kernel
+	}
+
+	for _, kernel := range g.JupyterConfig.Kernels {
+		switch jupyterKernelLanguages[kernel] {
+		case "python":
+		case "java":
+			if kernel != javaKernel {
+				return nil, errors.Newf("Jupyter kernel %s requires install.java(kernel=\"%s\")", kernel, kernel)
+			}
+		default:
+			if !installed[kernel] {
+				return nil, errors.Newf("Jupyter kernel %s requires the %s language to be installed", kernel, kernel)
+			}
+		}
+	}
+
+	for _, language := range g.Languages {
+		name := language.Name
+		if !installed[name] || slices.Contains(kernels, name) {
+			continue
+		}
+		if len(g.JupyterConfig.Kernels) > 0 && !slices.Contains(g.JupyterConfig.Kernels, name) {
+			continue
+		}
+		kernels = append(kernels, name)
+	}
+	if javaKernel != "" {
+		kernels = append(kernels, javaKernel)
+	}
+	return kernels, nil
+}
+
+// compileJupyterKernels registers the kernels of the installed languages to the
+// system Jupyter data directory, the packages are installed by `compileJupyter`.
+func (g generalGraph) compileJupyterKernels(root llb.State) llb.State {
+	// the kernels have been validated in `compileJupyter`
+	kernels, _ := g.jupyterKernels()
+	path := strings.Join(g.RuntimeEnvPaths, ":")
+
+	for _, kernel := range kernels {
+		switch kernel {
+		case "r":
+			root = root.Run(
+				llb.Shlex(`R -e 'IRkernel::installspec(user = FALSE, prefix = "/usr/local")'`),
+				llb.AddEnv("PATH", path),
+				llb.WithCustomName("[internal] register IRkernel"),
+			).Root()
+		case "julia":
+			root = root.Run(
+				llb.Shlex(`julia -e 'using IJulia; IJulia.installkernel("Julia")'`),
+				llb.AddEnv("JUPYTER_DATA_DIR", jupyterDataDir),
+				llb.AddEnv("PATH", path),
+				llb.WithCustomName("[internal] register IJulia kernel"),
+			).Root()
+		case "rust":
+			// evcxr installs the kernel into `$XDG_DATA_HOME/jupyter`
+			root = root.Run(
+				llb.Shlexf("%s/evcxr_jupyter --install", cargoHomeBin),
+				llb.AddEnv("XDG_DATA_HOME", filepath.Dir(jupyterDataDir)),
+				llb.AddEnv("PATH", path),
+				llb.WithCustomName("[internal] register evcxr kernel"),
+			).Root()
+		case "go":
+			dir := filepath.Join(jupyterDataDir, "kernels", "gophernotes")
+			spec := fmt.Sprintf(gophernotesKernel, filepath.Join(golangToolsBin, "gophernotes"))
+			root = root.File(llb.Mkdir(dir, 0755, llb.WithParents(true)),
+				llb.WithCustomName("[internal] create gophernotes kernel dir")).
+				File(llb.Mkfile(filepath.Join(dir, "kernel.json"), 0644, []byte(spec)),
+					llb.WithCustomName("[internal] register gophernotes kernel"))
+		}
+	}
+	return g.compileJupyterSettings(root)
+}
+
+// compileJupyterSettings writes the settings to the `overrides.json` of JupyterLab,
+// which is located in the prefix of the Python environment that runs Jupyter.
+func (g generalGraph) compileJupyterSettings(root llb.State) llb.State {
+	if len(g.JupyterConfig.Settings) == 0 {
+		return root
+	}
+
+	settings := llb.Scratch().File(
+		llb.Mkfile("overrides.json", 0644, []byte(g.JupyterConfig.Settings)),
+		llb.WithCustomName("[internal] generate jupyterlab overrides.json"))
+	run := root.Run(
+		llb.Shlexf(`sh -c "dir=$(python3 -c 'import sys; print(sys.prefix)')/share/jupyter/lab/settings && mkdir -p $dir && cp %s/overrides.json $dir/"`, jupyterSettingsDir),
+		llb.AddEnv("PATH", strings.Join(g.RuntimeEnvPaths, ":")),
+		llb.WithCustomName("[internal] configure jupyterlab settings"))
+	run.AddMount(jupyterSettingsDir, settings, llb.Readonly)
+	return run.Root()
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/tensorchord/envd/pkg/lang/ir"
+)
+
+func TestJupyterKernels(t *testing.T) {
+	rust := "1.72.0"
+	languages := []ir.Language{{Name: "python"}, {Name: "r"}, {Name: "rust", Version: &rust}, {Name: "java"}}
+	testcases := []struct {
+		kernels  []string
+		java     string
+		expected []string
+		err      bool
+	}{
+		{kernels: nil, expected: []string{"python", "r", "rust"}},
+		{kernels: nil, java: "scala", expected: []string{"python", "r", "rust", "scala"}},
+		{kernels: []string{"rust"}, expected: []string{"python", "rust"}},
+		{kernels: []string{"python", "kotlin"}, java: "kotlin", expected: []string{"python", "kotlin"}},
+		{kernels: []string{"julia"}, err: true},
+		{kernels: []string{"scala"}, java: "kotlin", err: true},
+	}
+	for _, tc := range testcases {
+		g := generalGraph{
+			Languages:     languages,
+			JupyterConfig: &ir.JupyterConfig{Kernels: tc.kernels},
+			JavaConfig:    &ir.JavaConfig{Kernel: tc.java},
+		}
+		kernels, err := g.jupyterKernels()
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error for kernels %v", tc.kernels)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !equal(kernels, tc.expected) {
+			t.Errorf("kernels %v: expected %v, got %v", tc.kernels, tc.expected, kernels)
+		}
+	}
+}
+
+func TestJupyterUnsupportedKernel(t *testing.T) {
+	DefaultGraph = NewGraph()
+	if err := Jupyter("", 8888, []string{"haskell"}, nil, ""); err == nil {
+		t.Errorf("expected an error for an unsupported kernel")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/moby/buildkit/client/llb"
+
+	"github.com/tensorchord/envd/pkg/lang/ir"
+)
+
+func TestReadRLockfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "renv.lock")
+	if err := os.WriteFile(path, []byte(`{
+  "R": {"Version": "4.4.1"},
+  "Packages": {
+    "rlang": {"Package": "rlang", "Version": "1.1.4", "Source": "Repository"},
+    "cli": {"Package": "cli", "Version": "3.6.3", "Source": "Repository"}
+  }
+}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	packages, err := readRLockfile(path)
+	if err != nil {
+		t.Fatalf("readRLockfile returned error: %v", err)
+	}
+	expected := []string{"cli==3.6.3", "rlang==1.1.4"}
+	if !reflect.DeepEqual(packages, expected) {
+		t.Errorf("readRLockfile = %v, expected %v", packages, expected)
+	}
+}
+
+func TestReadJuliaManifest(t *testing.T) {
+	tcs := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "v2",
+			content: `julia_version = "1.10.4"
+manifest_format = "2.0"
+
+[[deps.CSV]]
+deps = ["Dates"]
+uuid = "336ed68f-0bac-5ca0-87d4-7b16caf5d00b"
+version = "0.10.14"
+
+[[deps.Dates]]
+uuid = "ade2ca70-3891-5945-98fb-dc099432e06a"
+`,
+			expected: []string{"CSV==0.10.14"},
+		},
+		{
+			name: "v1",
+			content: `[[JSON]]
+uuid = "682c06a0-de6a-54ab-a142-c8b1cf79cde6"
+version = "0.21.4"
+`,
+			expected: []string{"JSON==0.21.4"},
+		},
+	}
+
+	for _, tc := range tcs {
+		path := filepath.Join(t.TempDir(), "Manifest.toml")
+		if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		packages, err := readJuliaManifest(path)
+		if err != nil {
+			t.Fatalf("readJuliaManifest(%s) returned error: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(packages, tc.expected) {
+			t.Errorf("readJuliaManifest(%s) = %v, expected %v", tc.name, packages, tc.expected)
+		}
+	}
+}
+
+func TestInstantiateJuliaProject(t *testing.T) {
+	project, manifest := "julia/Project.toml", "julia/Manifest.toml"
+	// The build context is not mounted in the non-dev image.
+	g := generalGraph{
+		Dev:               false,
+		RuntimeGraph:      ir.RuntimeGraph{RuntimeEnviron: map[string]string{}},
+		JuliaProjectFile:  &project,
+		JuliaManifestFile: &manifest,
+	}
+	def, err := g.instantiateJuliaProject(llb.Image("ubuntu:22.04")).Marshal(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to marshal the julia project: %v", err)
+	}
+	if g.RuntimeEnviron["JULIA_PROJECT"] != juliaProjectDir {
+		t.Errorf("expected JULIA_PROJECT to be %s, got %s", juliaProjectDir, g.RuntimeEnviron["JULIA_PROJECT"])
+	}
+	for _, expected := range []string{
+		"/opt/julia/project/Project.toml",
+		"/opt/julia/project/Manifest.toml",
+		"--project=/opt/julia/project",
+	} {
+		found := false
+		for _, dt := range def.Def {
+			if bytes.Contains(dt, []byte(expected)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected %s in the definition", expected)
+		}
+	}
+}
@@ -15,7 +15,21 @@
 package v1
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cockroachdb/errors"
 	"github.com/moby/buildkit/client/llb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tensorchord/envd/pkg/flag"
+	"github.com/tensorchord/envd/pkg/util/fileutil"
 )
 
 // from https://nodejs.org/download/release/
@@ -24,8 +38,27 @@ const (
 	nodejsTempDir        = "/tmp/nodejs"
 	nodejsHomeDir        = "/opt/nodejs"
 	nodejsHomeBin        = "/opt/nodejs/bin"
+
+	nodeToolNPM  = "npm"
+	nodeToolPNPM = "pnpm"
+	nodeToolYarn = "yarn"
+
+	nodeManifest = "package.json"
+	// nodeLocalPrefix holds the packages installed with `global=False`.
+	nodeLocalPrefix = "/opt/envd/node"
+	nodeNPMCacheDir = "/root/.npm"
+	// The project directory is mounted from the host in the dev environment, thus
+	// node_modules is installed in its parent directory, which is also searched by
+	// the Node.js module resolution.
+	nodeProjectPrefix = "/home/envd"
 )
 
+var nodeCacheDirs = map[string]string{
+	nodeToolNPM:  nodeNPMCacheDir,
+	nodeToolPNPM: "/root/.cache/pnpm",
+	nodeToolYarn: "/root/.cache/yarn",
+}
+
 func (g *generalGraph) installNodeJS(root llb.State, version *string) llb.State {
 	nodejsVersion := nodejsDefaultVersion
 	if version != nil {
@@ -45,3 +78,240 @@ func (g *generalGraph) installNodeJS(root llb.State, version *string) llb.State
 	g.RuntimeEnvPaths = append(g.RuntimeEnvPaths, nodejsHomeBin)
 	return root
 }
+
+// nodeProjectTool returns the package manager that understands the lock file.
+func nodeProjectTool(lock string) (string, error) {
+	switch filepath.Base(lock) {
+	case "package-lock.json":
+		return nodeToolNPM, nil
+	case "pnpm-lock.yaml":
+		return nodeToolPNPM, nil
+	case "yarn.lock":
+		return nodeToolYarn, nil
+	}
+	return "", errors.Newf("unsupported lock file %s, expect package-lock.json, pnpm-lock.yaml or yarn.lock", lock)
+}
+
+func nodeProjectCommand(tool string) string {
+	switch tool {
+	case nodeToolPNPM:
+		return fmt.Sprintf("npx --yes pnpm install --frozen-lockfile --store-dir %s", nodeCacheDirs[nodeToolPNPM])
+	case nodeToolYarn:
+		// yarn berry writes `__metadata` to the lock file
+		return "if grep -q __metadata yarn.lock; then npx --yes @yarnpkg/cli-dist install --immutable; " +
+			"else npx --yes yarn@1 install --frozen-lockfile; fi"
+	}
+	return "npm ci"
+}
+
+func (g generalGraph) addNodeCacheMount(run llb.ExecState, cacheDir string) {
+	run.AddMount(cacheDir, llb.Scratch(),
+		llb.AsPersistentCacheDir(g.CacheID(cacheDir), llb.CacheMountShared))
+}
+
+// installNodePackages installs the Node.js packages and the locked Node.js project.
+func (g *generalGraph) installNodePackages(root llb.State) llb.State {
+	if len(g.NodePackages) == 0 && g.NodeProject == nil {
+		return root
+	}
+
+	// Allow root to utilize the installed Node.js
+	root = root.AddEnv("PATH", strings.Join(g.RuntimeEnvPaths, ":"))
+	for _, packages := range g.NodePackages {
+		command := fmt.Sprintf("npm install -g %s", strings.Join(packages.Name, " "))
+		if !packages.Global {
+			command = fmt.Sprintf("npm install --no-save --prefix %s %s", nodeLocalPrefix, strings.Join(packages.Name, " "))
+		}
+		run := root.Run(llb.Shlex(command),
+			llb.AddEnv("npm_config_cache", nodeNPMCacheDir),
+			llb.WithCustomNamef("[internal] installing Node.js packages: %s", strings.Join(packages.Name, " ")))
+		g.addNodeCacheMount(run, nodeNPMCacheDir)
+		root = run.Root()
+		if !packages.Global && g.RuntimeEnviron["NODE_PATH"] == "" {
+			g.RuntimeEnviron["NODE_PATH"] = filepath.Join(nodeLocalPrefix, "node_modules")
+			g.RuntimeEnvPaths = append(g.RuntimeEnvPaths, filepath.Join(nodeLocalPrefix, "node_modules", ".bin"))
+			g.UserDirectories = append(g.UserDirectories, nodeLocalPrefix)
+		}
+	}
+	return g.installNodeProject(root)
+}
+
+// installNodeProject installs node_modules from the lock file. Only the manifest and
+// the lock file are mounted so the layer stays cached until one of them changes.
+func (g *generalGraph) installNodeProject(root llb.State) llb.State {
+	if g.NodeProject == nil {
+		return root
+	}
+
+	// validated in the frontend
+	tool, _ := nodeProjectTool(g.NodeProject.Lock)
+	manifest := filepath.Join(g.NodeProject.Dir, nodeManifest)
+	lockFile := filepath.Join(g.NodeProject.Dir, g.NodeProject.Lock)
+	lock := filepath.Base(lockFile)
+	src := llb.Local(flag.FlagBuildContext,
+		llb.IncludePatterns([]string{manifest, lockFile}),
+		llb.SharedKeyHint("node-project"),
+		llb.WithCustomNamef("[internal] load %s and %s", manifest, lockFile))
+
+	run := root.Run(
+		llb.Dir(nodeProjectPrefix),
+		llb.Shlexf(`sh -c "%s"`, nodeProjectCommand(tool)),
+		llb.AddEnv("npm_config_cache", nodeNPMCacheDir),
+		llb.AddEnv("YARN_CACHE_FOLDER", nodeCacheDirs[nodeToolYarn]),
+		llb.AddEnv("YARN_NODE_LINKER", "node-modules"),
+		llb.WithCustomNamef("[internal] installing Node.js project %s from %s", g.NodeProject.Dir, lock))
+	run.AddMount(filepath.Join(nodeProjectPrefix, nodeManifest), src,
+		llb.SourcePath(manifest), llb.Readonly)
+	run.AddMount(filepath.Join(nodeProjectPrefix, lock), src,
+		llb.SourcePath(lockFile), llb.Readonly)
+	g.addNodeCacheMount(run, nodeNPMCacheDir)
+	if tool != nodeToolNPM {
+		g.addNodeCacheMount(run, nodeCacheDirs[tool])
+	}
+
+	g.RuntimeEnvPaths = append(g.RuntimeEnvPaths, fileutil.EnvdHomeDir("node_modules", ".bin"))
+	g.UserDirectories = append(g.UserDirectories, fileutil.EnvdHomeDir("node_modules"))
+	return run.Root()
+}
+
+// readNodeProjectPackages returns the sorted `name@version` of the direct dependencies
+// in package.json, resolved by the lock file.
+func readNodeProjectPackages(dir, lock string) ([]string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, nodeManifest))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", nodeManifest)
+	}
+	var manifest struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", nodeManifest)
+	}
+	deps := make(map[string]string)
+	for _, m := range []map[string]string{manifest.Dependencies, manifest.DevDependencies} {
+		for name, spec := range m {
+			deps[name] = spec
+		}
+	}
+
+	content, err = os.ReadFile(filepath.Join(dir, lock))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the lock file %s", lock)
+	}
+	var versions map[string]string
+	switch filepath.Base(lock) {
+	case "package-lock.json":
+		versions, err = parseNPMLock(content)
+	case "pnpm-lock.yaml":
+		versions, err = parsePNPMLock(content)
+	case "yarn.lock":
+		versions = parseYarnLock(content, deps)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the lock file %s", lock)
+	}
+
+	packages := make([]string, 0, len(deps))
+	for name, spec := range deps {
+		version, ok := versions[name]
+		if !ok {
+			version = spec
+		}
+		packages = append(packages, fmt.Sprintf("%s@%s", name, version))
+	}
+	sort.Strings(packages)
+	return packages, nil
+}
+
+func parseNPMLock(content []byte) (map[string]string, error) {
+	type entry struct {
+		Version string `json:"version"`
+	}
+	var lock struct {
+		Packages     map[string]entry `json:"packages"`
+		Dependencies map[string]entry `json:"dependencies"`
+	}
+	if err := json.Unmarshal(content, &lock); err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	// lockfileVersion 1
+	for name, e := range lock.Dependencies {
+		versions[name] = e.Version
+	}
+	for key, e := range lock.Packages {
+		name, ok := strings.CutPrefix(key, "node_modules/")
+		if ok && !strings.Contains(name, "/node_modules/") {
+			versions[name] = e.Version
+		}
+	}
+	return versions, nil
+}
+
+func parsePNPMLock(content []byte) (map[string]string, error) {
+	type deps map[string]struct {
+		Version string `yaml:"version"`
+	}
+	type importer struct {
+		Dependencies    deps `yaml:"dependencies"`
+		DevDependencies deps `yaml:"devDependencies"`
+	}
+	var lock struct {
+		importer  `yaml:",inline"`
+		Importers map[string]importer `yaml:"importers"`
+	}
+	if err := yaml.Unmarshal(content, &lock); err != nil {
+		return nil, err
+	}
+	root := lock.importer
+	if i, ok := lock.Importers["."]; ok {
+		root = i
+	}
+	versions := make(map[string]string)
+	for _, d := range []deps{root.Dependencies, root.DevDependencies} {
+		for name, e := range d {
+			// remove the peer dependencies suffix, e.g. `1.0.0(react@18.2.0)`
+			version, _, _ := strings.Cut(e.Version, "(")
+			versions[name] = version
+		}
+	}
+	return versions, nil
+}
+
+// parseYarnLock supports both the yarn v1 and the yarn berry lock files. The entry
+// headers are the comma separated descriptors like `name@spec` or `name@npm:spec`.
+func parseYarnLock(content []byte, deps map[string]string) map[string]string {
+	resolved := make(map[string]string)
+	var descriptors []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, " ") {
+			descriptors = strings.Split(strings.TrimSuffix(line, ":"), ",")
+			continue
+		}
+		field := strings.TrimSpace(line)
+		if version, ok := strings.CutPrefix(field, "version"); ok && len(descriptors) > 0 {
+			version = strings.Trim(strings.TrimSpace(strings.TrimPrefix(version, ":")), `"`)
+			for _, d := range descriptors {
+				resolved[strings.Trim(strings.TrimSpace(d), `"`)] = version
+			}
+			descriptors = nil
+		}
+	}
+
+	versions := make(map[string]string)
+	for name, spec := range deps {
+		for _, d := range []string{name + "@" + spec, name + "@npm:" + spec} {
+			if version, ok := resolved[d]; ok {
+				versions[name] = version
+				break
+			}
+		}
+	}
+	return versions
+}
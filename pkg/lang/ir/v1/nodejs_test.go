@@ -0,0 +1,125 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadNodeProjectPackages(t *testing.T) {
+	manifest := `{
+  "name": "demo",
+  "dependencies": {"react": "^18.2.0"},
+  "devDependencies": {"typescript": "~5.4.0"}
+}`
+	tcs := []struct {
+		lock    string
+		content string
+	}{
+		{
+			lock: "package-lock.json",
+			content: `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"dependencies": {"react": "^18.2.0"}},
+    "node_modules/react": {"version": "18.3.1"},
+    "node_modules/typescript": {"version": "5.4.5"},
+    "node_modules/react/node_modules/loose-envify": {"version": "1.4.0"}
+  }
+}`,
+		},
+		{
+			lock: "pnpm-lock.yaml",
+			content: `lockfileVersion: '9.0'
+
+importers:
+
+  .:
+    dependencies:
+      react:
+        specifier: ^18.2.0
+        version: 18.3.1
+    devDependencies:
+      typescript:
+        specifier: ~5.4.0
+        version: 5.4.5(@types/node@20.0.0)
+`,
+		},
+		{
+			lock: "yarn.lock",
+			content: `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+react@^18.0.0, react@^18.2.0:
+  version "18.3.1"
+  resolved "https://registry.yarnpkg.com/react/-/react-18.3.1.tgz"
+
+typescript@~5.4.0:
+  version "5.4.5"
+`,
+		},
+		{
+			lock: "yarn.lock",
+			content: `__metadata:
+  version: 8
+
+"react@npm:^18.2.0":
+  version: 18.3.1
+  resolution: "react@npm:18.3.1"
+
+"typescript@npm:~5.4.0":
+  version: 5.4.5
+`,
+		},
+	}
+
+	expected := []string{"react@18.3.1", "typescript@5.4.5"}
+	for _, tc := range tcs {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, nodeManifest), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, tc.lock), []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		packages, err := readNodeProjectPackages(dir, tc.lock)
+		if err != nil {
+			t.Fatalf("readNodeProjectPackages(%s) returned error: %v", tc.lock, err)
+		}
+		if !reflect.DeepEqual(packages, expected) {
+			t.Errorf("readNodeProjectPackages(%s) = %v, expected %v", tc.lock, packages, expected)
+		}
+	}
+}
+
+func TestNodeProjectTool(t *testing.T) {
+	for lock, expected := range map[string]string{
+		"package-lock.json":  nodeToolNPM,
+		"web/pnpm-lock.yaml": nodeToolPNPM,
+		"yarn.lock":          nodeToolYarn,
+	} {
+		tool, err := nodeProjectTool(lock)
+		if err != nil || tool != expected {
+			t.Errorf("nodeProjectTool(%s) = %s, %v, expected %s", lock, tool, err, expected)
+		}
+	}
+	if _, err := nodeProjectTool("bun.lockb"); err == nil {
+		t.Errorf("nodeProjectTool(bun.lockb) should return an error")
+	}
+}
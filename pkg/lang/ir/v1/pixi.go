@@ -16,17 +16,33 @@ package v1
 
 import (
 	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
 	"text/template"
 
+	"github.com/BurntSushi/toml"
+	"github.com/cockroachdb/errors"
 	"github.com/moby/buildkit/client/llb"
 	"github.com/sirupsen/logrus"
+
+	"github.com/tensorchord/envd/pkg/flag"
+	"github.com/tensorchord/envd/pkg/lang/ir"
 )
 
 // https://github.com/prefix-dev/pixi
 const (
-	pixiVersion        = "0.62.2"
+	pixiVersion = "0.62.2"
+	// pixiEnvsDir keeps the environments out of the project directory since it's
+	// mounted from the host in the dev environment.
+	pixiEnvsDir        = "/opt/pixi/envs"
+	pixiCacheDir       = "/root/.cache/rattler"
+	pixiLockFile       = "pixi.lock"
 	pixiConfigTemplate = `
-{{- if .UsePixiMirror -}}
+{{- if .Manifest }}
+detached-environments = "{{ .EnvsDir }}"
+{{- end }}
+{{- if .UsePixiMirror }}
 [mirrors]
 "https://conda.anaconda.org" = ["https://prefix.dev/"]
 # prefix.dev doesn't mirror conda-forge's label channels
@@ -34,11 +50,11 @@ const (
 "https://conda.anaconda.org/conda-forge/label" = [
   "https://conda.anaconda.org/conda-forge/label",
 ]
-{{- end -}}
-{{- if .PyPIIndex -}}
+{{- end }}
+{{- if .PyPIIndex }}
 [pypi-config]
 index-url = "{{ .PyPIIndex }}"
-{{- end -}}
+{{- end }}
 `
 )
 
@@ -61,7 +77,7 @@ func (g generalGraph) compilePixi(root llb.State) llb.State {
 }
 
 func (g generalGraph) compilePixiConfig(root llb.State) llb.State {
-	if !g.PixiConfig.UsePixiMirror && g.PixiConfig.PyPIIndex == nil {
+	if !g.PixiConfig.UsePixiMirror && g.PixiConfig.PyPIIndex == nil && g.PixiConfig.Manifest == "" {
 		return root
 	}
 
@@ -70,20 +86,139 @@ func (g generalGraph) compilePixiConfig(root llb.State) llb.State {
 		llb.WithCustomName("[internal] create pixi config directory"),
 	)
 
-	tmpl, err := template.New("pixi-config").Parse(pixiConfigTemplate)
+	config, err := renderPixiConfig(g.PixiConfig)
 	if err != nil {
-		logrus.Errorf("failed to parse pixi config template: %v", err)
-		return root
-	}
-	var buf bytes.Buffer
-	err = tmpl.Execute(&buf, g.PixiConfig)
-	if err != nil {
-		logrus.Errorf("failed to execute pixi config template: %v", err)
+		logrus.Errorf("failed to render pixi config: %v", err)
 		return root
 	}
 	root = root.File(
-		llb.Mkfile("/etc/pixi/config.toml", 0755, buf.Bytes(), llb.WithUIDGID(g.uid, g.gid)),
+		llb.Mkfile("/etc/pixi/config.toml", 0755, config, llb.WithUIDGID(g.uid, g.gid)),
 		llb.WithCustomName("[internal] create pixi config file"),
 	)
 	return root
 }
+
+func renderPixiConfig(config *ir.PixiConfig) ([]byte, error) {
+	tmpl, err := template.New("pixi-config").Parse(pixiConfigTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse pixi config template")
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		*ir.PixiConfig
+		EnvsDir string
+	}{config, pixiEnvsDir})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute pixi config template")
+	}
+	return append(bytes.TrimSpace(buf.Bytes()), '\n'), nil
+}
+
+// pixiManifestPath returns the path of the pixi manifest in the environment.
+func (g generalGraph) pixiManifestPath() string {
+	return filepath.Join(g.getWorkingDir(), g.PixiConfig.Manifest)
+}
+
+// compilePixiProject installs the locked pixi environment and exposes the pixi
+// tasks as runtime commands. Only the manifest and the lock file are copied, so
+// the layer stays cached until one of them changes.
+func (g *generalGraph) compilePixiProject(root llb.State) (llb.State, error) {
+	if g.PixiConfig == nil || g.PixiConfig.Manifest == "" {
+		return root, nil
+	}
+
+	manifest := g.PixiConfig.Manifest
+	lock := filepath.Join(filepath.Dir(manifest), pixiLockFile)
+	tasks, err := readPixiTasks(filepath.Join(g.EnvironmentPath, manifest), g.PixiConfig.Environment)
+	if err != nil {
+		return llb.State{}, err
+	}
+	for _, task := range tasks {
+		if _, ok := g.RuntimeCommands[task]; ok {
+			logrus.WithField("task", task).Debug("skip the pixi task since the command already exists")
+			continue
+		}
+		g.RuntimeCommands[task] = fmt.Sprintf("pixi run --manifest-path %s -e %s %s",
+			g.pixiManifestPath(), g.PixiConfig.Environment, task)
+	}
+
+	src := llb.Local(flag.FlagBuildContext,
+		llb.IncludePatterns([]string{manifest, lock}),
+		llb.SharedKeyHint("pixi-project"),
+		llb.WithCustomNamef("[internal] load %s and %s", manifest, lock))
+	root = root.
+		File(llb.Copy(src, manifest, g.pixiManifestPath(), &llb.CopyInfo{CreateDestPath: true}),
+			llb.WithCustomNamef("[internal] copy %s", manifest)).
+		File(llb.Copy(src, lock, filepath.Join(g.getWorkingDir(), lock), &llb.CopyInfo{CreateDestPath: true}),
+			llb.WithCustomNamef("[internal] copy %s", lock))
+
+	run := root.Run(
+		llb.Shlexf("pixi install --frozen --manifest-path %s -e %s", g.pixiManifestPath(), g.PixiConfig.Environment),
+		llb.AddEnv("RATTLER_CACHE_DIR", pixiCacheDir),
+		llb.WithCustomNamef("[internal] pixi install environment %s", g.PixiConfig.Environment))
+	run.AddMount(pixiCacheDir, llb.Scratch(),
+		llb.AsPersistentCacheDir(g.CacheID(pixiCacheDir), llb.CacheMountShared))
+	g.UserDirectories = append(g.UserDirectories, pixiEnvsDir)
+	return run.Root(), nil
+}
+
+type pixiTasks struct {
+	Tasks        map[string]any `toml:"tasks"`
+	Environments map[string]any `toml:"environments"`
+	Feature      map[string]struct {
+		Tasks map[string]any `toml:"tasks"`
+	} `toml:"feature"`
+}
+
+// readPixiTasks returns the sorted names of the tasks in the pixi environment,
+// including the tasks of the features that the environment is composed of.
+func readPixiTasks(path, environment string) ([]string, error) {
+	var manifest pixiTasks
+	if filepath.Base(path) == "pyproject.toml" {
+		var pyproject struct {
+			Tool struct {
+				Pixi pixiTasks `toml:"pixi"`
+			} `toml:"tool"`
+		}
+		if _, err := toml.DecodeFile(path, &pyproject); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the pixi manifest %s", path)
+		}
+		manifest = pyproject.Tool.Pixi
+	} else if _, err := toml.DecodeFile(path, &manifest); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the pixi manifest %s", path)
+	}
+
+	var features []string
+	switch env := manifest.Environments[environment].(type) {
+	case []any:
+		for _, f := range env {
+			if name, ok := f.(string); ok {
+				features = append(features, name)
+			}
+		}
+	case map[string]any:
+		if list, ok := env["features"].([]any); ok {
+			for _, f := range list {
+				if name, ok := f.(string); ok {
+					features = append(features, name)
+				}
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	for name := range manifest.Tasks {
+		seen[name] = true
+	}
+	for _, feature := range features {
+		for name := range manifest.Feature[feature].Tasks {
+			seen[name] = true
+		}
+	}
+	tasks := make([]string, 0, len(seen))
+	for name := range seen {
+		tasks = append(tasks, name)
+	}
+	sort.Strings(tasks)
+	return tasks, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tensorchord/envd/pkg/lang/ir"
+)
+
+func TestRenderPixiConfig(t *testing.T) {
+	index := "https://pypi.example.com/simple"
+	tcs := []struct {
+		config   ir.PixiConfig
+		expected string
+	}{
+		{
+			config:   ir.PixiConfig{Manifest: "pixi.toml"},
+			expected: "detached-environments = \"/opt/pixi/envs\"\n",
+		},
+		{
+			config: ir.PixiConfig{Manifest: "pixi.toml", PyPIIndex: &index},
+			expected: "detached-environments = \"/opt/pixi/envs\"\n" +
+				"[pypi-config]\nindex-url = \"https://pypi.example.com/simple\"\n",
+		},
+	}
+
+	for _, tc := range tcs {
+		config, err := renderPixiConfig(&tc.config)
+		if err != nil {
+			t.Fatalf("renderPixiConfig(%+v) returned error: %v", tc.config, err)
+		}
+		if string(config) != tc.expected {
+			t.Errorf("renderPixiConfig(%+v) = %q, expected %q", tc.config, config, tc.expected)
+		}
+	}
+}
+
+func TestReadPixiTasks(t *testing.T) {
+	dir := t.TempDir()
+	pixi := filepath.Join(dir, "pixi.toml")
+	if err := os.WriteFile(pixi, []byte(`
+[tasks]
+train = "python train.py"
+lint = { cmd = "ruff check ." }
+
+[feature.test.tasks]
+test = "pytest"
+
+[feature.docs.tasks]
+docs = "mkdocs serve"
+
+[environments]
+test = ["test"]
+docs = { features = ["docs"], solve-group = "default" }
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pyproject := filepath.Join(dir, "pyproject.toml")
+	if err := os.WriteFile(pyproject, []byte(`
+[project]
+name = "demo"
+
+[tool.pixi.tasks]
+serve = "python -m demo"
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		path        string
+		environment string
+		expected    []string
+	}{
+		{path: pixi, environment: "default", expected: []string{"lint", "train"}},
+		{path: pixi, environment: "test", expected: []string{"lint", "test", "train"}},
+		{path: pixi, environment: "docs", expected: []string{"docs", "lint", "train"}},
+		{path: pyproject, environment: "default", expected: []string{"serve"}},
+	}
+
+	for _, tc := range tcs {
+		tasks, err := readPixiTasks(tc.path, tc.environment)
+		if err != nil {
+			t.Fatalf("readPixiTasks(%s, %s) returned error: %v", tc.path, tc.environment, err)
+		}
+		if !reflect.DeepEqual(tasks, tc.expected) {
+			t.Errorf("readPixiTasks(%s, %s) = %v, expected %v", tc.path, tc.environment, tasks, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/moby/buildkit/client/llb"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tensorchord/envd/pkg/flag"
+	"github.com/tensorchord/envd/pkg/lang/ir"
+)
+
+const (
+	pythonProjectToolUV     = "uv"
+	pythonProjectToolPoetry = "poetry"
+	pythonProjectToolPDM    = "pdm"
+
+	pythonProjectManifest = "pyproject.toml"
+	// pythonProjectDir only holds the manifest and the lock file during the build.
+	pythonProjectDir = "/tmp/envd/python-project"
+	// pythonProjectToolDir is a throwaway venv for poetry and pdm.
+	pythonProjectToolDir = "/tmp/envd/tool"
+	pythonProjectUVDir   = "/tmp/envd/uv"
+	// pythonProjectStamp finds the files installed by the project install.
+	pythonProjectStamp = "/tmp/envd/stamp/python-project"
+	// pythonProjectVenv is used when the environment doesn't have `install.python()`.
+	pythonProjectVenv      = "/opt/envd/venv"
+	pythonProjectPythonDir = "/opt/envd/python"
+	envdPythonPrefix       = "/opt/conda/envs/envd"
+)
+
+// pythonProjectTool returns the tool that understands the lock file.
+func pythonProjectTool(lock string) (string, error) {
+	switch filepath.Base(lock) {
+	case "uv.lock":
+		return pythonProjectToolUV, nil
+	case "poetry.lock":
+		return pythonProjectToolPoetry, nil
+	case "pdm.lock":
+		return pythonProjectToolPDM, nil
+	}
+	return "", errors.Newf("unsupported lock file %s, expect uv.lock, poetry.lock or pdm.lock", lock)
+}
+
+// pythonProjectEnvironment returns the prefix that the projects are installed into.
+func (g generalGraph) pythonProjectEnvironment() string {
+	for _, language := range g.Languages {
+		if language.Name == "python" {
+			return envdPythonPrefix
+		}
+	}
+	return pythonProjectVenv
+}
+
+// pythonProjectCommand returns the shell command that installs the dependencies
+// of the project, or the project itself if `self` is true.
+func pythonProjectCommand(project ir.PythonProjectConfig, tool, target string, self bool) string {
+	var sb strings.Builder
+	switch tool {
+	case pythonProjectToolUV:
+		sb.WriteString(filepath.Join(pythonProjectUVDir, "uv"))
+		sb.WriteString(" sync --frozen --inexact")
+		if !self {
+			sb.WriteString(" --no-install-workspace")
+		} else if !project.Editable {
+			sb.WriteString(" --no-editable")
+		}
+		for _, group := range project.Groups {
+			sb.WriteString(" --group " + group)
+		}
+	case pythonProjectToolPoetry:
+		if self && !project.Editable {
+			// poetry always installs the root project in editable mode
+			return fmt.Sprintf("%s/bin/python -m pip install --no-deps .", target)
+		}
+		fmt.Fprintf(&sb, "%s/bin/python -m venv %s && ", envdPythonPrefix, pythonProjectToolDir)
+		fmt.Fprintf(&sb, "%s/bin/python -m pip install poetry && ", pythonProjectToolDir)
+		fmt.Fprintf(&sb, "%s/bin/poetry install --no-interaction", pythonProjectToolDir)
+		if self {
+			sb.WriteString(" --only-root")
+		} else {
+			sb.WriteString(" --no-root")
+			if len(project.Groups) > 0 {
+				sb.WriteString(" --with " + strings.Join(project.Groups, ","))
+			}
+		}
+	case pythonProjectToolPDM:
+		fmt.Fprintf(&sb, "%s/bin/python -m venv %s && ", envdPythonPrefix, pythonProjectToolDir)
+		fmt.Fprintf(&sb, "%s/bin/python -m pip install pdm && ", pythonProjectToolDir)
+		fmt.Fprintf(&sb, "%s/bin/pdm sync", pythonProjectToolDir)
+		if !self {
+			sb.WriteString(" --no-self")
+		} else if !project.Editable {
+			sb.WriteString(" --no-editable")
+		}
+		for _, group := range project.Groups {
+			sb.WriteString(" -G " + group)
+		}
+	}
+	return sb.String()
+}
+
+// pythonProjectEnv returns the environment variables that point the tool to
+// the target prefix and to the persistent cache directories.
+func (g generalGraph) pythonProjectEnv(tool, target string) []llb.RunOption {
+	var envs []llb.RunOption
+	switch tool {
+	case pythonProjectToolUV:
+		envs = append(envs,
+			llb.AddEnv("UV_PROJECT_ENVIRONMENT", target),
+			llb.AddEnv("UV_CACHE_DIR", "/root/.cache/uv"),
+			llb.AddEnv("UV_LINK_MODE", "copy"),
+		)
+		if target == pythonProjectVenv {
+			envs = append(envs,
+				llb.AddEnv("UV_PYTHON_INSTALL_DIR", pythonProjectPythonDir),
+				llb.AddEnv("UV_PYTHON_PREFERENCE", "only-managed"),
+			)
+			if g.UVConfig != nil {
+				envs = append(envs, llb.AddEnv("UV_PYTHON", g.UVConfig.PythonVersion))
+			}
+		}
+	case pythonProjectToolPoetry:
+		envs = append(envs,
+			llb.AddEnv("POETRY_VIRTUALENVS_CREATE", "false"),
+			llb.AddEnv("POETRY_CACHE_DIR", "/root/.cache/pypoetry"),
+			llb.AddEnv("VIRTUAL_ENV", target),
+		)
+	case pythonProjectToolPDM:
+		envs = append(envs,
+			llb.AddEnv("PDM_CACHE_DIR", "/root/.cache/pdm"),
+			llb.AddEnv("PDM_CHECK_UPDATE", "false"),
+			llb.AddEnv("VIRTUAL_ENV", target),
+		)
+	}
+	return envs
+}
+
+// pythonProjectCacheDirs returns the cache directories used by the tool.
+func pythonProjectCacheDirs(tool string) []string {
+	switch tool {
+	case pythonProjectToolUV:
+		return []string{"/root/.cache/uv"}
+	case pythonProjectToolPoetry:
+		return []string{"/root/.cache/pypoetry", "/root/.cache/pip"}
+	case pythonProjectToolPDM:
+		return []string{"/root/.cache/pdm", "/root/.cache/pip"}
+	}
+	return nil
+}
+
+func (g generalGraph) addPythonProjectMounts(run llb.ExecState, tool string) {
+	for _, cacheDir := range pythonProjectCacheDirs(tool) {
+		run.AddMount(cacheDir, llb.Scratch(),
+			llb.AsPersistentCacheDir(g.CacheID(cacheDir), llb.CacheMountShared))
+	}
+	if tool == pythonProjectToolUV {
+		run.AddMount(pythonProjectUVDir, g.downloadUV(), llb.SourcePath("/tmp"), llb.Readonly)
+	} else {
+		run.AddMount(pythonProjectToolDir, llb.Scratch())
+	}
+}
+
+// compilePythonProjects installs the dependencies of the `pyproject.toml` projects
+// with their lock files. The dependencies are installed from a layer that only
+// contains the manifest and the lock file, so it stays cached until one of them
+// changes. The projects themselves are installed by compilePythonProjectSources.
+func (g *generalGraph) compilePythonProjects(root llb.State) (llb.State, error) {
+	if len(g.PythonProjects) == 0 {
+		return root, nil
+	}
+
+	target := g.pythonProjectEnvironment()
+	for _, project := range g.PythonProjects {
+		tool, err := pythonProjectTool(project.Lock)
+		if err != nil {
+			return llb.State{}, err
+		}
+		if tool != pythonProjectToolUV && target != envdPythonPrefix {
+			return llb.State{}, errors.Newf("%s projects require `install.python()`", tool)
+		}
+
+		lock := filepath.Base(project.Lock)
+		manifest := filepath.Join(project.Path, pythonProjectManifest)
+		lockFile := filepath.Join(project.Path, project.Lock)
+		src := llb.Local(flag.FlagBuildContext,
+			llb.IncludePatterns([]string{manifest, lockFile}),
+			llb.SharedKeyHint(fmt.Sprintf("python-project-%s", project.Path)),
+			llb.WithCustomNamef("[internal] load %s and %s", manifest, lockFile))
+		files := llb.Scratch().
+			File(llb.Copy(src, manifest, pythonProjectManifest),
+				llb.WithCustomNamef("[internal] copy %s", manifest)).
+			File(llb.Copy(src, lockFile, lock),
+				llb.WithCustomNamef("[internal] copy %s", lockFile))
+
+		command := pythonProjectCommand(project, tool, target, false)
+		logrus.WithField("command", command).Debug("Configure python project dependencies")
+		deps := root.Run(append(g.pythonProjectEnv(tool, target),
+			llb.Dir(pythonProjectDir),
+			llb.Shlexf(`sh -c "%s"`, command),
+			llb.WithCustomNamef("[internal] install dependencies of %s from %s", project.Path, lock))...)
+		deps.AddMount(pythonProjectDir, files)
+		g.addPythonProjectMounts(deps, tool)
+		root = deps.Root()
+	}
+
+	if target == pythonProjectVenv {
+		g.RuntimeEnviron["VIRTUAL_ENV"] = pythonProjectVenv
+		g.RuntimeEnviron["UV_PROJECT_ENVIRONMENT"] = pythonProjectVenv
+		g.RuntimeEnvPaths = append(g.RuntimeEnvPaths, filepath.Join(pythonProjectVenv, "bin"))
+		g.UserDirectories = append(g.UserDirectories, pythonProjectVenv, pythonProjectPythonDir)
+	}
+	return root, nil
+}
+
+// compilePythonProjectSources installs the `pyproject.toml` projects themselves
+// with the whole build context mounted. It is the last step before `run`, thus
+// the changes of the source code do not invalidate the other layers.
+func (g *generalGraph) compilePythonProjectSources(root llb.State) (llb.State, error) {
+	if len(g.PythonProjects) == 0 {
+		return root, nil
+	}
+
+	target := g.pythonProjectEnvironment()
+	for _, project := range g.PythonProjects {
+		tool, err := pythonProjectTool(project.Lock)
+		if err != nil {
+			return llb.State{}, err
+		}
+		if project.Editable && !g.Dev {
+			logrus.WithField("project", project.Path).
+				Debug("install the python project in non-editable mode since the source is not mounted")
+			project.Editable = false
+		}
+
+		command := pythonProjectCommand(project, tool, target, true)
+		if g.Dev && g.uid != 0 {
+			// The environment is already owned by the user, give the
+			// installed files back to the user after installing as root.
+			command = fmt.Sprintf("touch %[1]s && %[2]s && find %[3]s -newer %[1]s -exec chown -h %[4]d:%[5]d {} +",
+				pythonProjectStamp, command, target, g.uid, g.gid)
+		}
+		logrus.WithField("command", command).Debug("Configure python project")
+		self := root.Run(append(g.pythonProjectEnv(tool, target),
+			llb.User("root"),
+			llb.AddEnv("HOME", "/root"),
+			llb.Dir(filepath.Join(g.getWorkingDir(), project.Path)),
+			llb.Shlexf(`sh -c "%s"`, command),
+			llb.WithCustomNamef("[internal] install python project %s", project.Path))...)
+		self.AddMount(g.getWorkingDir(), llb.Local(flag.FlagBuildContext))
+		self.AddMount(filepath.Dir(pythonProjectStamp), llb.Scratch())
+		g.addPythonProjectMounts(self, tool)
+		root = self.Root()
+	}
+	return root, nil
+}
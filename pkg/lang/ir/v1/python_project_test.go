@@ -0,0 +1,99 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/tensorchord/envd/pkg/lang/ir"
+)
+
+func TestPythonProjectTool(t *testing.T) {
+	tcs := []struct {
+		lock          string
+		expectedTool  string
+		expectedError bool
+	}{
+		{lock: "uv.lock", expectedTool: pythonProjectToolUV},
+		{lock: "locks/poetry.lock", expectedTool: pythonProjectToolPoetry},
+		{lock: "pdm.lock", expectedTool: pythonProjectToolPDM},
+		{lock: "requirements.txt", expectedError: true},
+	}
+
+	for _, tc := range tcs {
+		tool, err := pythonProjectTool(tc.lock)
+		if tc.expectedError {
+			if err == nil {
+				t.Errorf("pythonProjectTool(%s) should return an error", tc.lock)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("pythonProjectTool(%s) returned error: %v", tc.lock, err)
+		}
+		if tool != tc.expectedTool {
+			t.Errorf("pythonProjectTool(%s) = %s, expected %s", tc.lock, tool, tc.expectedTool)
+		}
+	}
+}
+
+func TestPythonProjectCommand(t *testing.T) {
+	tcs := []struct {
+		project  ir.PythonProjectConfig
+		tool     string
+		self     bool
+		expected string
+	}{
+		{
+			project:  ir.PythonProjectConfig{Lock: "uv.lock", Groups: []string{"dev"}},
+			tool:     pythonProjectToolUV,
+			expected: "/tmp/envd/uv/uv sync --frozen --inexact --no-install-workspace --group dev",
+		},
+		{
+			project:  ir.PythonProjectConfig{Lock: "uv.lock"},
+			tool:     pythonProjectToolUV,
+			self:     true,
+			expected: "/tmp/envd/uv/uv sync --frozen --inexact --no-editable",
+		},
+		{
+			project: ir.PythonProjectConfig{Lock: "poetry.lock", Groups: []string{"dev", "docs"}},
+			tool:    pythonProjectToolPoetry,
+			expected: "/opt/conda/envs/envd/bin/python -m venv /tmp/envd/tool && " +
+				"/tmp/envd/tool/bin/python -m pip install poetry && " +
+				"/tmp/envd/tool/bin/poetry install --no-interaction --no-root --with dev,docs",
+		},
+		{
+			project:  ir.PythonProjectConfig{Lock: "poetry.lock"},
+			tool:     pythonProjectToolPoetry,
+			self:     true,
+			expected: "/opt/conda/envs/envd/bin/python -m pip install --no-deps .",
+		},
+		{
+			project: ir.PythonProjectConfig{Lock: "pdm.lock", Editable: true},
+			tool:    pythonProjectToolPDM,
+			self:    true,
+			expected: "/opt/conda/envs/envd/bin/python -m venv /tmp/envd/tool && " +
+				"/tmp/envd/tool/bin/python -m pip install pdm && " +
+				"/tmp/envd/tool/bin/pdm sync",
+		},
+	}
+
+	for _, tc := range tcs {
+		command := pythonProjectCommand(tc.project, tc.tool, envdPythonPrefix, tc.self)
+		if command != tc.expected {
+			t.Errorf("pythonProjectCommand(%+v, %t) = %s, expected %s", tc.project, tc.self, command, tc.expected)
+		}
+	}
+}
@@ -15,13 +15,24 @@
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 
+	"github.com/cockroachdb/errors"
 	"github.com/moby/buildkit/client/llb"
+
+	"github.com/tensorchord/envd/pkg/flag"
 )
 
-const rPath = "/usr/local/lib/R/site-library"
+const (
+	rPath = "/usr/local/lib/R/site-library"
+	// renvDir only holds the lockfile during the build.
+	renvDir      = "/tmp/envd/renv"
+	renvCacheDir = "/root/.cache/R/renv"
+)
 
 func (g *generalGraph) installRLang(root llb.State) llb.State {
 	g.UserDirectories = append(g.UserDirectories, rPath)
@@ -36,7 +47,7 @@ echo "deb [signed-by=/usr/share/keyrings/r-project.gpg] https://cloud.r-project.
 }
 
 func (g generalGraph) installRPackages(root llb.State) llb.State {
-	if len(g.RPackages) == 0 {
+	if len(g.RPackages) == 0 && g.RLockFile == nil {
 		return root
 	}
 
@@ -51,5 +62,56 @@ func (g generalGraph) installRPackages(root llb.State) llb.State {
 			Run(llb.Shlex(command), llb.WithCustomNamef("[internal] installing R packages: %s", strings.Join(packages, " ")))
 		root = run.Root()
 	}
-	return root
+	return g.restoreRLockfile(root, mirrorURL)
+}
+
+// restoreRLockfile installs the exact versions in the renv lockfile into the site library.
+// The packages are copied from the renv cache instead of being linked since the cache
+// is a mount that is not part of the image.
+func (g generalGraph) restoreRLockfile(root llb.State, mirrorURL string) llb.State {
+	if g.RLockFile == nil {
+		return root
+	}
+
+	lockfile := *g.RLockFile
+	files := llb.Scratch().File(
+		llb.Copy(llb.Local(flag.FlagBuildContext, llb.IncludePatterns([]string{lockfile}),
+			llb.SharedKeyHint("renv-lockfile")), lockfile, "renv.lock"),
+		llb.WithCustomNamef("[internal] copy %s", lockfile))
+	command := fmt.Sprintf(`R -e 'options(repos = "%[1]s"); if (!requireNamespace("renv", quietly = TRUE)) install.packages("renv", lib = "%[2]s"); renv::restore(lockfile = "%[3]s/renv.lock", library = "%[2]s", prompt = FALSE)'`,
+		mirrorURL, rPath, renvDir)
+	run := root.Run(llb.Shlex(command),
+		llb.AddEnv("RENV_PATHS_CACHE", renvCacheDir),
+		llb.AddEnv("RENV_CONFIG_CACHE_SYMLINKS", "FALSE"),
+		llb.WithCustomNamef("[internal] restoring R packages from %s", lockfile))
+	run.AddMount(renvDir, files, llb.Readonly)
+	run.AddMount(renvCacheDir, llb.Scratch(),
+		llb.AsPersistentCacheDir(g.CacheID(renvCacheDir), llb.CacheMountShared))
+	return run.Root()
+}
+
+// readRLockfile returns the sorted `name==version` of the packages in the renv lockfile.
+func readRLockfile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the renv lockfile %s", path)
+	}
+	var lockfile struct {
+		Packages map[string]struct {
+			Package string `json:"Package"`
+			Version string `json:"Version"`
+		} `json:"Packages"`
+	}
+	if err := json.Unmarshal(content, &lockfile); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the renv lockfile %s", path)
+	}
+	packages := make([]string, 0, len(lockfile.Packages))
+	for name, pkg := range lockfile.Packages {
+		if pkg.Package != "" {
+			name = pkg.Package
+		}
+		packages = append(packages, fmt.Sprintf("%s==%s", name, pkg.Version))
+	}
+	sort.Strings(packages)
+	return packages, nil
 }
@@ -15,6 +15,10 @@
 package v1
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/moby/buildkit/client/llb"
 )
 
@@ -22,6 +26,8 @@ const (
 	rustUpInitFilePath = "/tmp/rustup-init.sh"
 	cargoHomeDir       = "/opt/rust"
 	cargoHomeBin       = "/opt/rust/bin"
+	// cargoTargetDir keeps the build artifacts of `cargo install` across builds.
+	cargoTargetDir = "/root/.cache/cargo-target"
 )
 
 func (g *generalGraph) installRust(root llb.State, version *string) llb.State {
@@ -44,6 +50,63 @@ func (g *generalGraph) installRust(root llb.State, version *string) llb.State {
 		llb.Shlexf(`sh -c "sh %[1]s -y -q --no-modify-path && rm %[1]s"`, rustUpInitFilePath),
 		llb.WithCustomName("[internal] install rust"),
 	).Root()
+	if len(g.RustComponents) > 0 {
+		root = root.Run(
+			llb.Shlexf("%s/rustup component add %s", cargoHomeBin, strings.Join(g.RustComponents, " ")),
+			llb.WithCustomNamef("[internal] add rust components: %s", strings.Join(g.RustComponents, " ")),
+		).Root()
+	}
+	if len(g.RustTargets) > 0 {
+		root = root.Run(
+			llb.Shlexf("%s/rustup target add %s", cargoHomeBin, strings.Join(g.RustTargets, " ")),
+			llb.WithCustomNamef("[internal] add rust targets: %s", strings.Join(g.RustTargets, " ")),
+		).Root()
+	}
 	g.RuntimeEnvPaths = append(g.RuntimeEnvPaths, cargoHomeBin)
 	return root
 }
+
+// installCargoPackages installs the crates with `cargo install --locked`. The crate
+// registry and the build artifacts are kept in the cache mounts.
+func (g generalGraph) installCargoPackages(root llb.State) llb.State {
+	if len(g.CargoPackages) == 0 {
+		return root
+	}
+
+	for _, packages := range g.CargoPackages {
+		specs := make([]string, 0, len(packages))
+		for _, pkg := range packages {
+			specs = append(specs, cargoPackageSpec(pkg))
+		}
+		run := root.Run(
+			llb.Shlexf("%s/cargo install --locked %s", cargoHomeBin, strings.Join(specs, " ")),
+			llb.AddEnv("CARGO_HOME", cargoHomeDir),
+			llb.AddEnv("CARGO_TARGET_DIR", cargoTargetDir),
+			llb.AddEnv("PATH", strings.Join(g.RuntimeEnvPaths, ":")),
+			llb.WithCustomNamef("[internal] installing cargo packages: %s", strings.Join(packages, " ")))
+		for _, cacheDir := range []string{
+			filepath.Join(cargoHomeDir, "registry"),
+			filepath.Join(cargoHomeDir, "git"),
+			cargoTargetDir,
+		} {
+			run.AddMount(cacheDir, llb.Scratch(),
+				llb.AsPersistentCacheDir(g.CacheID(cacheDir), llb.CacheMountShared))
+		}
+		root = run.Root()
+	}
+	return root
+}
+
+// cargoPackageSpec converts the partial version like `cargo-nextest@0.9` to a
+// requirement since `cargo install` only accepts the exact `MAJOR.MINOR.PATCH`
+// version without an operator.
+func cargoPackageSpec(pkg string) string {
+	name, version, ok := strings.Cut(pkg, "@")
+	if !ok || version == "" {
+		return pkg
+	}
+	if strings.ContainsAny(version[:1], "^~=<>*") || strings.Count(version, ".") >= 2 {
+		return pkg
+	}
+	return fmt.Sprintf("%s@^%s", name, version)
+}
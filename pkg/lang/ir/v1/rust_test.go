@@ -0,0 +1,51 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import "testing"
+
+func TestCargoPackageSpec(t *testing.T) {
+	tcs := []struct {
+		pkg      string
+		expected string
+	}{
+		{pkg: "ripgrep", expected: "ripgrep"},
+		{pkg: "ripgrep@14.1.0", expected: "ripgrep@14.1.0"},
+		{pkg: "cargo-nextest@0.9", expected: "cargo-nextest@^0.9"},
+		{pkg: "cargo-nextest@~0.9", expected: "cargo-nextest@~0.9"},
+	}
+
+	for _, tc := range tcs {
+		if spec := cargoPackageSpec(tc.pkg); spec != tc.expected {
+			t.Errorf("cargoPackageSpec(%s) = %s, expected %s", tc.pkg, spec, tc.expected)
+		}
+	}
+}
+
+func TestGoToolSpec(t *testing.T) {
+	tcs := []struct {
+		tool     string
+		expected string
+	}{
+		{tool: "golang.org/x/tools/gopls@v0.15.3", expected: "golang.org/x/tools/gopls@v0.15.3"},
+		{tool: "github.com/go-delve/delve/cmd/dlv", expected: "github.com/go-delve/delve/cmd/dlv@latest"},
+	}
+
+	for _, tc := range tcs {
+		if spec := goToolSpec(tc.tool); spec != tc.expected {
+			t.Errorf("goToolSpec(%s) = %s, expected %s", tc.tool, spec, tc.expected)
+		}
+	}
+}
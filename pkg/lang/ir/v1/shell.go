@@ -120,6 +120,34 @@ func (g generalGraph) compilePixiShell(root llb.State) llb.State {
 			llb.WithCustomName("[internal] setting pixi bash config"),
 		).Root()
 	}
+	return g.compilePixiActivation(root)
+}
+
+// compilePixiActivation activates the pixi environment in the shell.
+func (g generalGraph) compilePixiActivation(root llb.State) llb.State {
+	if g.PixiConfig.Manifest == "" {
+		return root
+	}
+
+	hook := fmt.Sprintf("pixi shell-hook --shell %%s --manifest-path %s -e %s",
+		g.pixiManifestPath(), g.PixiConfig.Environment)
+	switch g.Shell {
+	case shellZSH:
+		root = root.Run(
+			llb.Shlexf(`sh -c 'echo "eval \"\$(%s)\"" >> ~/.zshrc'`, fmt.Sprintf(hook, "zsh")),
+			llb.WithCustomNamef("[internal] activate pixi environment %s in zsh", g.PixiConfig.Environment),
+		).Root()
+	case shellFish:
+		root = root.Run(
+			llb.Shlexf(`sh -c 'echo "%s | source" >> ~/.config/fish/config.fish'`, fmt.Sprintf(hook, "fish")),
+			llb.WithCustomNamef("[internal] activate pixi environment %s in fish", g.PixiConfig.Environment),
+		).Root()
+	case shellBASH:
+		root = root.Run(
+			llb.Shlexf(`sh -c 'echo "eval \"\$(%s)\"" >> ~/.bashrc'`, fmt.Sprintf(hook, "bash")),
+			llb.WithCustomNamef("[internal] activate pixi environment %s in bash", g.PixiConfig.Environment),
+		).Root()
+	}
 	return root
 }
 
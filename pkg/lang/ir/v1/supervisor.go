@@ -17,12 +17,15 @@ package v1
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/cockroachdb/errors"
+	"github.com/google/shlex"
 	"github.com/moby/buildkit/client/llb"
 
 	"github.com/tensorchord/envd/pkg/config"
+	"github.com/tensorchord/envd/pkg/lang/ir"
 	"github.com/tensorchord/envd/pkg/types"
 )
 
@@ -49,6 +52,23 @@ attempts = 2
 [termination]
 wait = "5s"
 `
+	// daemonLauncherTemplate runs the daemon script as a child, which is restarted
+	// with the new script on SIGHUP. The exit status of the daemon is kept, thus
+	// horust only restarts the failed daemon.
+	daemonLauncherTemplate = `echo $$ > %[1]s
+trap "rm -f %[1]s" EXIT
+on_hup() { reload=1; kill -TERM "$child" 2>/dev/null; }
+on_term() { kill -TERM "$child" 2>/dev/null; wait "$child"; exit 143; }
+trap on_hup HUP
+trap on_term TERM
+while true; do
+  reload=
+  status=0
+  /bin/bash %[2]s &
+  child=$!
+  while kill -0 "$child" 2>/dev/null; do wait "$child"; status=$?; done
+  [ -n "$reload" ] || exit "$status"
+done`
 )
 
 func (g generalGraph) installHorust(root llb.State) llb.State {
@@ -57,6 +77,8 @@ func (g generalGraph) installHorust(root llb.State) llb.State {
 			llb.WithCustomName("[internal] install horust")).
 		File(llb.Mkdir(types.HorustServiceDir, 0755, llb.WithParents(true)),
 			llb.WithCustomNamef("[internal] mkdir for horust service: %s", types.HorustServiceDir)).
+		File(llb.Mkdir(types.HorustScriptDir, 0755, llb.WithParents(true)),
+			llb.WithCustomNamef("[internal] mkdir for horust script: %s", types.HorustScriptDir)).
 		File(llb.Mkdir(types.HorustSocketDir, 0777, llb.WithParents(true)),
 			llb.WithCustomNamef("[internal] mkdir for horust socket: %s", types.HorustSocketDir)).
 		File(llb.Mkdir(types.HorustLogDir, 0777, llb.WithParents(true)),
@@ -67,7 +89,7 @@ func (g generalGraph) installHorust(root llb.State) llb.State {
 	return horust.Root()
 }
 
-func (g generalGraph) addNewProcess(root llb.State, name, command string, depends []string) llb.State {
+func horustService(name, command string, depends []string) ir.ServiceFile {
 	var sb strings.Builder
 	if len(depends) != 0 {
 		sb.WriteString("start-after = [")
@@ -78,41 +100,156 @@ func (g generalGraph) addNewProcess(root llb.State, name, command string, depend
 		}
 		sb.WriteString("]\n")
 	}
-	template := fmt.Sprintf(horustTemplate, name, command, types.EnvdWorkDir, sb.String())
+	return ir.ServiceFile{
+		Name:    name,
+		Content: fmt.Sprintf(horustTemplate, name, command, types.EnvdWorkDir, sb.String()),
+	}
+}
+
+// runtimeService returns the service which runs the script, thus the script
+// can be replaced when the runtime graph is applied.
+func runtimeService(name, script string, depends []string) ir.ServiceFile {
+	command := fmt.Sprintf("/bin/bash %s", ir.ServiceScriptPath(name))
+	service := horustService(name, command, depends)
+	service.Script = script
+	return service
+}
+
+// daemonService returns the service which records its pid and runs the script,
+// the daemon is restarted with the new script by SIGHUP.
+func daemonService(name string, command []string, depends []string) ir.ServiceFile {
+	launcher := fmt.Sprintf(daemonLauncherTemplate, ir.ServicePIDPath(name), ir.ServiceScriptPath(name))
+	service := horustService(name, fmt.Sprintf("/bin/bash -c '%s'", launcher), depends)
+	service.Script = daemonScript(command)
+	return service
+}
+
+// daemonScript execs the daemon with the same arguments as horust, which splits
+// the joined command like a shell without the expansion and the redirection.
+func daemonScript(command []string) string {
+	joined := strings.Join(command, " ")
+	args, err := shlex.Split(joined)
+	if err != nil {
+		return "exec " + joined
+	}
+	for i, arg := range args {
+		args[i] = "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+	}
+	return "exec " + strings.Join(args, " ")
+}
 
-	filename := filepath.Join(types.HorustServiceDir, fmt.Sprintf("%s.toml", name))
-	supervisor := root.File(llb.Mkfile(filename, 0644, []byte(template), llb.WithUIDGID(g.uid, g.gid)), llb.WithCustomNamef("[internal] create file %s", filename))
+func (g generalGraph) addNewProcess(root llb.State, service ir.ServiceFile) llb.State {
+	filename := filepath.Join(types.HorustServiceDir, fmt.Sprintf("%s.toml", service.Name))
+	supervisor := root.File(llb.Mkfile(filename, 0644, []byte(service.Content), llb.WithUIDGID(g.uid, g.gid)), llb.WithCustomNamef("[internal] create file %s", filename))
+	if service.Script != "" {
+		script := ir.ServiceScriptPath(service.Name)
+		supervisor = supervisor.File(llb.Mkfile(script, 0644, []byte(service.Script), llb.WithUIDGID(g.uid, g.gid)),
+			llb.WithCustomNamef("[internal] create file %s", script))
+	}
 	return supervisor
 }
 
+// runtimeServiceDeps returns the names of the init services, which the other
+// services start after.
+func (g generalGraph) runtimeServiceDeps() []string {
+	var deps []string
+	for i := range g.RuntimeInitScript {
+		deps = append(deps, ir.InitServiceName(i))
+	}
+	return deps
+}
+
+// GetRuntimeServices returns the services of the init scripts and daemons,
+// which can be regenerated without rebuilding the image.
+func (g generalGraph) GetRuntimeServices() []ir.ServiceFile {
+	var services []ir.ServiceFile
+	for i, command := range g.RuntimeInitScript {
+		services = append(services, runtimeService(ir.InitServiceName(i),
+			"set -euo pipefail\n"+strings.Join(command, "\n"), nil))
+	}
+	deps := g.runtimeServiceDeps()
+	for i, command := range g.RuntimeDaemon {
+		services = append(services, daemonService(ir.DaemonServiceName(i), command, deps))
+	}
+	if script := g.dotfilesStartScript(); script != "" {
+		services = append(services, runtimeService(dotfilesService,
+			"set -euo pipefail\n"+script, nil))
+	}
+	return services
+}
+
+// sshdPolicyArgs returns the envd-sshd flags of `config.ssh`.
+func (g generalGraph) sshdPolicyArgs() []string {
+	if g.SSHConfig == nil {
+		return nil
+	}
+	var args []string
+	if g.SSHConfig.DisablePortForward {
+		args = append(args, "--no-port-forward")
+	}
+	// The empty list of the ports denies the forwarding, while nil allows all.
+	if ports := g.SSHConfig.LocalForwardPorts; ports != nil && len(ports) == 0 {
+		args = append(args, "--no-local-forward")
+	}
+	if ports := g.SSHConfig.RemoteForwardPorts; ports != nil && len(ports) == 0 {
+		args = append(args, "--no-remote-forward")
+	}
+	for _, f := range []struct {
+		flag   string
+		values []string
+	}{
+		{"--allow-local-forward-ports", g.SSHConfig.LocalForwardPorts},
+		{"--allow-local-forward-hosts", g.SSHConfig.LocalForwardHosts},
+		{"--allow-remote-forward-ports", g.SSHConfig.RemoteForwardPorts},
+		{"--allow-remote-forward-hosts", g.SSHConfig.RemoteForwardHosts},
+	} {
+		if len(f.values) > 0 {
+			args = append(args, f.flag, strings.Join(f.values, ","))
+		}
+	}
+	if g.SSHConfig.DisableSFTP {
+		args = append(args, "--no-sftp")
+	}
+	if g.SSHConfig.MaxSessions > 0 {
+		args = append(args, "--max-sessions", strconv.Itoa(g.SSHConfig.MaxSessions))
+	}
+	if g.SSHConfig.IdleTimeout > 0 {
+		args = append(args, "--idle-timeout", g.SSHConfig.IdleTimeout.String())
+	}
+	return args
+}
+
 func (g generalGraph) compileEntrypoint(root llb.State) (llb.State, error) {
 	if len(g.Entrypoint) > 0 {
 		return root, errors.New("`config.entrypoint` is only for custom image, maybe you need `runtime.init`")
 	}
-	cmd := fmt.Sprintf("/var/envd/bin/envd-sshd --port %d --shell %s", config.SSHPortInContainer, g.Shell)
-	entrypoint := g.addNewProcess(root, "sshd", cmd, nil)
-	var deps []string
-	if g.RuntimeInitScript != nil {
-		for i, command := range g.RuntimeInitScript {
-			entrypoint = g.addNewProcess(entrypoint, fmt.Sprintf("init_%d", i), fmt.Sprintf("/bin/bash -c 'set -euo pipefail\n%s'", strings.Join(command, "\n")), nil)
-			deps = append(deps, fmt.Sprintf("init_%d", i))
-		}
+	cmd := fmt.Sprintf("/var/envd/bin/envd-sshd --port %d --shell %s --hostkey %s",
+		config.SSHPortInContainer, g.Shell, config.ContainerHostKeyPath)
+	if args := g.sshdPolicyArgs(); len(args) > 0 {
+		cmd += " " + strings.Join(args, " ")
 	}
-
-	if g.RuntimeDaemon != nil {
-		for i, command := range g.RuntimeDaemon {
-			entrypoint = g.addNewProcess(entrypoint, fmt.Sprintf("daemon_%d", i), strings.Join(command, " "), deps)
-		}
+	if g.WebTerminal {
+		cmd += fmt.Sprintf(" --web-terminal-port %d", config.WebTerminalPortInContainer)
+	}
+	entrypoint := g.addNewProcess(root, horustService("sshd", cmd, nil))
+	for _, service := range g.GetRuntimeServices() {
+		entrypoint = g.addNewProcess(entrypoint, service)
 	}
+	deps := g.runtimeServiceDeps()
 
 	if g.JupyterConfig != nil {
 		jupyterCmd := g.generateJupyterCommand("")
-		entrypoint = g.addNewProcess(entrypoint, "jupyter", strings.Join(jupyterCmd, " "), deps)
+		entrypoint = g.addNewProcess(entrypoint, horustService("jupyter", strings.Join(jupyterCmd, " "), deps))
 	}
 
 	if g.RStudioServerConfig != nil {
 		rstudioCmd := g.generateRStudioCommand("")
-		entrypoint = g.addNewProcess(entrypoint, "rstudio", strings.Join(rstudioCmd, " "), deps)
+		entrypoint = g.addNewProcess(entrypoint, horustService("rstudio", strings.Join(rstudioCmd, " "), deps))
+	}
+
+	if g.VSCodeServer {
+		entrypoint = g.addNewProcess(entrypoint,
+			horustService(config.VSCodeServerService, g.generateVSCodeServerCommand(), deps))
 	}
 
 	return entrypoint, nil
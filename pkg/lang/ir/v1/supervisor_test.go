@@ -0,0 +1,168 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tensorchord/envd/pkg/lang/ir"
+)
+
+func TestGetRuntimeServices(t *testing.T) {
+	g := generalGraph{
+		RuntimeGraph: ir.RuntimeGraph{
+			RuntimeInitScript: [][]string{{"echo init"}},
+			RuntimeDaemon:     [][]string{{"python3", "-m", "http.server"}},
+		},
+	}
+	services := g.GetRuntimeServices()
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+	if services[0].Name != "init_0" || services[1].Name != "daemon_0" {
+		t.Errorf("unexpected services: %s, %s", services[0].Name, services[1].Name)
+	}
+	if services[1].Script != "exec 'python3' '-m' 'http.server'" {
+		t.Errorf("unexpected daemon script: %s", services[1].Script)
+	}
+	for _, expected := range []string{
+		"echo $$ > /var/run/horust/daemon_0.pid",
+		"/bin/bash /etc/horust/scripts/daemon_0.sh &",
+		"trap on_hup HUP",
+		`strategy = "on-failure"`,
+	} {
+		if !strings.Contains(services[1].Content, expected) {
+			t.Errorf("expected %s in %s", expected, services[1].Content)
+		}
+	}
+	if !strings.Contains(services[1].Content, `start-after = ["init_0",]`) {
+		t.Errorf("expected the daemon to start after the init script in %s", services[1].Content)
+	}
+}
+
+func TestDaemonScript(t *testing.T) {
+	tcs := []struct {
+		command  []string
+		expected string
+	}{
+		{
+			command:  []string{"python3", "-c", "'print(1 + 2)'"},
+			expected: `exec 'python3' '-c' 'print(1 + 2)'`,
+		},
+		{
+			// horust splits the joined command, thus the spaces separate the arguments.
+			command:  []string{"streamlit", "run", "my app.py"},
+			expected: `exec 'streamlit' 'run' 'my' 'app.py'`,
+		},
+		{
+			command:  []string{"streamlit", "run", `"my app.py"`},
+			expected: `exec 'streamlit' 'run' 'my app.py'`,
+		},
+		{
+			// There is no expansion or redirection like horust.
+			command:  []string{"echo", "$HOME", ">>echo.log"},
+			expected: `exec 'echo' '$HOME' '>>echo.log'`,
+		},
+		{
+			command:  []string{"echo", `"it's"`},
+			expected: `exec 'echo' 'it'"'"'s'`,
+		},
+	}
+	for _, tc := range tcs {
+		if script := daemonScript(tc.command); script != tc.expected {
+			t.Errorf("daemonScript(%q) = %s, expected %s", tc.command, script, tc.expected)
+		}
+	}
+}
+
+func TestGetBuildGraphHash(t *testing.T) {
+	newGraph := func(init [][]string, daemon [][]string, environ map[string]string) generalGraph {
+		return generalGraph{
+			Image: defaultImage,
+			RuntimeGraph: ir.RuntimeGraph{
+				RuntimeCommands:   map[string]string{"test": "echo test"},
+				RuntimeInitScript: init,
+				RuntimeDaemon:     daemon,
+				RuntimeEnviron:    environ,
+			},
+		}
+	}
+	hash := func(g generalGraph) string {
+		h, err := g.GetBuildGraphHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	base := hash(newGraph([][]string{{"echo a"}}, [][]string{{"sleep", "1"}}, nil))
+	if h := hash(newGraph([][]string{{"echo b"}}, [][]string{{"sleep", "2"}}, nil)); h != base {
+		t.Errorf("expected the same hash when the daemons and init scripts are changed")
+	}
+	if h := hash(newGraph([][]string{{"echo a"}, {"echo b"}}, nil, nil)); h == base {
+		t.Errorf("expected a different hash when the number of init scripts is changed")
+	}
+	if h := hash(newGraph([][]string{{"echo a"}}, nil, map[string]string{"A": "B"})); h == base {
+		t.Errorf("expected a different hash when the environ is changed")
+	}
+}
+
+func TestSSHDPolicyArgs(t *testing.T) {
+	g := generalGraph{}
+	if args := g.sshdPolicyArgs(); len(args) != 0 {
+		t.Errorf("expected no args without config.ssh, got %v", args)
+	}
+
+	g.SSHConfig = &ir.SSHConfig{
+		LocalForwardPorts:  []string{"8888", "6006-6010"},
+		RemoteForwardHosts: []string{"127.0.0.1"},
+		DisableSFTP:        true,
+		MaxSessions:        4,
+		IdleTimeout:        30 * time.Minute,
+	}
+	expected := "--allow-local-forward-ports 8888,6006-6010 --allow-remote-forward-hosts 127.0.0.1 " +
+		"--no-sftp --max-sessions 4 --idle-timeout 30m0s"
+	if args := strings.Join(g.sshdPolicyArgs(), " "); args != expected {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+
+	// The empty list denies all the ports, while nil allows all of them.
+	g.SSHConfig = &ir.SSHConfig{RemoteForwardPorts: []string{}}
+	if args := strings.Join(g.sshdPolicyArgs(), " "); args != "--no-remote-forward" {
+		t.Errorf("expected the remote forwarding to be disabled, got %q", args)
+	}
+}
+
+func TestChangedServices(t *testing.T) {
+	prev := ir.RuntimeGraph{
+		RuntimeInitScript: [][]string{{"echo init"}},
+		RuntimeDaemon:     [][]string{{"sleep", "1"}, {"sleep", "2"}},
+	}
+	next := prev
+	next.RuntimeDaemon = [][]string{{"sleep", "1"}, {"sleep", "3"}}
+	inits, daemons := prev.ChangedServices(next)
+	if len(inits) != 0 || !slices.Equal(daemons, []string{"daemon_1"}) {
+		t.Errorf("expected only daemon_1 to be changed, got %v %v", inits, daemons)
+	}
+
+	next.RuntimeInitScript = [][]string{{"echo changed"}}
+	inits, daemons = prev.ChangedServices(next)
+	if !slices.Equal(inits, []string{"init_0"}) || !slices.Equal(daemons, []string{"daemon_0", "daemon_1"}) {
+		t.Errorf("expected all the daemons to be changed with the init script, got %v %v", inits, daemons)
+	}
+}
@@ -66,6 +66,7 @@ func (g generalGraph) compileRun(root llb.State) llb.State {
 		if execGroup.MountHost {
 			run.AddMount(workingDir, llb.Local(flag.FlagBuildContext))
 		}
+		g.addJavaCacheMounts(run)
 		root = run.Root()
 	}
 	return root
@@ -174,6 +175,8 @@ func (g *generalGraph) compileLanguage(root llb.State) (llb.State, error) {
 				root = g.installGolang(root, language.Version)
 			case "nodejs":
 				root = g.installNodeJS(root, language.Version)
+			case "java":
+				root = g.installJava(root, language.Version)
 			}
 		}
 		return root, err
@@ -193,6 +196,8 @@ func (g *generalGraph) compileLanguage(root llb.State) (llb.State, error) {
 			lang = g.installGolang(root, language.Version)
 		case "nodejs":
 			lang = g.installNodeJS(root, language.Version)
+		case "java":
+			lang = g.installJava(root, language.Version)
 		}
 		langs = append(langs, lang)
 	}
@@ -208,7 +213,6 @@ func (g *generalGraph) compileLanguage(root llb.State) (llb.State, error) {
 
 func (g *generalGraph) compileLanguagePackages(root llb.State) llb.State {
 	// Use default python in the base image if install.python() is not specified.
-	g.compileJupyter()
 	index := g.compilePyPIIndex(root)
 	pack := g.compilePyPIPackages(index)
 	if g.CondaConfig != nil {
@@ -228,8 +232,19 @@ func (g *generalGraph) compileLanguagePackages(root llb.State) llb.State {
 			pack = g.installRPackages(pack)
 		case "julia":
 			pack = g.installJuliaPackages(pack)
+		case "nodejs":
+			pack = g.installNodePackages(pack)
+		case "rust":
+			pack = g.installCargoPackages(pack)
+		case "go":
+			pack = g.installGoTools(pack)
+		case "java":
+			pack = g.installJavaPackages(pack)
 		}
 	}
+	if g.JupyterConfig != nil {
+		pack = g.compileJupyterKernels(pack)
+	}
 	return pack
 }
 
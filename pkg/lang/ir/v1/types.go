@@ -29,6 +29,8 @@ import (
 type generalGraph struct {
 	uid int `default:"-1"`
 	gid int `default:"-1"`
+	// buildHash is the build graph hash before the compilation.
+	buildHash string
 
 	Languages         []ir.Language
 	CodeAgents        []ir.CodeAgent
@@ -55,11 +57,23 @@ type generalGraph struct {
 	PyPIPackages     [][]string
 	RequirementsFile *string
 	PythonWheels     []string
+	PythonProjects   []ir.PythonProjectConfig
 	RPackages        [][]string
+	RLockFile        *string
 	JuliaPackages    [][]string
-	SystemPackages   []string
+	// JuliaProjectFile and JuliaManifestFile are instantiated with `Pkg.instantiate`.
+	JuliaProjectFile  *string
+	JuliaManifestFile *string
+	NodePackages      []ir.NodePackageConfig
+	NodeProject       *ir.NodeProjectConfig
+	CargoPackages     [][]string
+	GoTools           [][]string
+	RustComponents    []string
+	RustTargets       []string
+	SystemPackages    []string
 
 	VSCodePlugins   []vscode.Plugin
+	Editors         []ir.EditorConfig
 	UserDirectories []string
 
 	Exec       []ir.RunBuildCommand
@@ -75,7 +89,14 @@ type generalGraph struct {
 	*ir.CondaConfig
 	*ir.UVConfig
 	*ir.PixiConfig
+	*ir.JavaConfig
 	*ir.RStudioServerConfig
+	*ir.SSHConfig
+	// WebTerminal serves the web terminal by envd-sshd.
+	WebTerminal bool
+	// VSCodeServer serves the OpenVSCode Server in the browser.
+	VSCodeServer bool
+	Dotfiles     *ir.DotfilesConfig
 
 	Writer compileui.Writer `json:"-"`
 	// EnvironmentName is the base name of the environment.
@@ -161,6 +161,34 @@ func (g *generalGraph) Dump() (string, error) {
 	return runtimeGraphCode, nil
 }
 
+// GetBuildGraphHash returns the hash of the graph excluding the reloadable
+// part of the runtime graph. It is used to tell if the changes of the graph
+// need to rebuild the image.
+func (g generalGraph) GetBuildGraphHash() (string, error) {
+	// Keep the number of the init scripts since the jupyter and rstudio
+	// services start after them.
+	g.RuntimeGraph = g.RuntimeGraph.WithReloadable(ir.RuntimeGraph{
+		RuntimeInitScript: make([][]string, len(g.RuntimeInitScript)),
+	})
+	b, err := json.Marshal(g)
+	if err != nil {
+		return "", err
+	}
+	hashD := md5.Sum(b)
+	return hex.EncodeToString(hashD[:]), nil
+}
+
+func (g generalGraph) GetRuntimeGraph() *ir.RuntimeGraph {
+	rg := g.RuntimeGraph
+	return &rg
+}
+
+// ResetDefaultGraph replaces the default graph with an empty one, so that the
+// manifest can be interpreted again in the same process.
+func ResetDefaultGraph() {
+	DefaultGraph = NewGraph()
+}
+
 func (g *generalGraph) Load(code []byte) error {
 	err := json.Unmarshal(code, g)
 	if err != nil {
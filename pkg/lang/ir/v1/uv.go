@@ -29,12 +29,7 @@ func (g generalGraph) compileUV(root llb.State) llb.State {
 	g.RuntimeEnviron["UV_LINK_MODE"] = "copy"
 	g.RuntimeEnviron["UV_PYTHON_PREFERENCE"] = "only-managed"
 
-	base := llb.Image(curlImage)
-	builder := base.Run(
-		llb.Shlexf(`sh -c "wget -qO- https://github.com/astral-sh/uv/releases/download/%s/uv-$(uname -m)-unknown-linux-gnu.tar.gz | tar -xz --strip-components=1 -C /tmp || exit 1"`, uvVersion),
-		llb.WithCustomNamef("[internal] download uv %s", uvVersion),
-	).Root()
-
+	builder := g.downloadUV()
 	root = root.File(
 		llb.Copy(builder, "/tmp/uv", "/usr/bin/uv"), llb.WithCustomName("[internal] install uv")).
 		File(llb.Copy(builder, "/tmp/uvx", "/usr/bin/uvx"), llb.WithCustomName("[internal] install uvx"))
@@ -46,6 +41,15 @@ func (g generalGraph) compileUV(root llb.State) llb.State {
 	return g.compileUVPython(root)
 }
 
+// downloadUV returns a state with the uv and uvx binaries in /tmp.
+func (g generalGraph) downloadUV() llb.State {
+	base := llb.Image(curlImage)
+	return base.Run(
+		llb.Shlexf(`sh -c "wget -qO- https://github.com/astral-sh/uv/releases/download/%s/uv-$(uname -m)-unknown-linux-gnu.tar.gz | tar -xz --strip-components=1 -C /tmp || exit 1"`, uvVersion),
+		llb.WithCustomNamef("[internal] download uv %s", uvVersion),
+	).Root()
+}
+
 func (g generalGraph) compileUVPython(root llb.State) llb.State {
 	if g.UVConfig == nil {
 		return root
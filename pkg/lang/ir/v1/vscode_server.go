@@ -0,0 +1,77 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/moby/buildkit/client/llb"
+
+	"github.com/tensorchord/envd/pkg/config"
+	"github.com/tensorchord/envd/pkg/types"
+	"github.com/tensorchord/envd/pkg/util/fileutil"
+)
+
+const (
+	// https://github.com/gitpod-io/openvscode-server
+	openVSCodeServerVersion = "1.105.1"
+	openVSCodeServerTempDir = "/tmp/openvscode-server"
+	openVSCodeServerDir     = "/opt/openvscode-server"
+)
+
+// openVSCodeServerTokenFile keeps the connection token out of the command line.
+var openVSCodeServerTokenFile = fileutil.EnvdHomeDir(".vscode-server", "connection-token")
+
+func (g generalGraph) installVSCodeServer(root llb.State) llb.State {
+	if !g.VSCodeServer {
+		return root
+	}
+
+	base := llb.Image(curlImage)
+	builder := base.Run(
+		llb.Shlexf(`sh -c "mkdir %[1]s && wget -qO- https://github.com/gitpod-io/openvscode-server/releases/download/openvscode-server-v%[2]s/openvscode-server-v%[2]s-linux-$(uname -m | sed -e 's/x86_64/x64/' -e 's/aarch64/arm64/').tar.gz | tar -xz --strip-components=1 -C %[1]s || exit 1"`,
+			openVSCodeServerTempDir, openVSCodeServerVersion),
+		llb.WithCustomNamef("[internal] download openvscode-server %s", openVSCodeServerVersion),
+	).Root()
+	return root.File(
+		llb.Copy(builder, openVSCodeServerTempDir, openVSCodeServerDir),
+		llb.WithCustomNamef("[internal] install openvscode-server %s", openVSCodeServerVersion))
+}
+
+// generateVSCodeServerCommand shares the extensions directory with VS Code Remote-SSH,
+// thus the extensions in `install.vscode_extensions` are available in both of them.
+// The token is written to a file only readable by the user at start, and removed
+// from the environment of the server.
+func (g generalGraph) generateVSCodeServerCommand() string {
+	if !g.VSCodeServer {
+		return ""
+	}
+
+	args := []string{
+		fmt.Sprintf("%s/bin/openvscode-server", openVSCodeServerDir),
+		"--host", "0.0.0.0",
+		"--port", strconv.Itoa(config.VSCodeServerPortInContainer),
+		"--connection-token-file", openVSCodeServerTokenFile,
+		"--extensions-dir", fileutil.EnvdHomeDir(".vscode-server", "extensions"),
+		"--default-folder", fmt.Sprintf(`"${%s}"`, types.EnvdWorkDir),
+		"--telemetry-level", "off",
+	}
+	token := fmt.Sprintf(`(umask 077 && mkdir -p %[1]s && printf "%%s" "${%[2]s:?}" > %[3]s) && unset %[2]s`,
+		filepath.Dir(openVSCodeServerTokenFile), types.EnvdVSCodeServerToken, openVSCodeServerTokenFile)
+	return fmt.Sprintf("/bin/bash -c '%s && exec %s'", token, strings.Join(args, " "))
+}
@@ -34,6 +34,8 @@ type Getter interface {
 	GetVersion() Version
 	GetDefaultGraph() ir.Graph
 	GetDefaultGraphHash() string
+	// ResetDefaultGraph resets the default graph before interpreting the manifest again.
+	ResetDefaultGraph()
 	GetStarlarkInterpreter(buildContextDir string) starlark.Interpreter
 }
 
@@ -119,6 +121,15 @@ func (g generalGetter) GetDefaultGraphHash() string {
 	}
 }
 
+func (g generalGetter) ResetDefaultGraph() {
+	switch g.v {
+	case V1:
+		v1.ResetDefaultGraph()
+	case V0:
+		logrus.Fatal("v0 is no longer supported in envd v1, try to use v1")
+	}
+}
+
 func (g generalGetter) GetStarlarkInterpreter(buildContextDir string) starlark.Interpreter {
 	switch g.v {
 	case V1:
@@ -57,7 +57,7 @@ func New(ctx context.Context, out console.File, mode string) (Writer, error) {
 				return nil, errors.Wrap(err, "failed to get console")
 			}
 		}
-	case progressmode.PLAIN:
+	case progressmode.PLAIN, progressmode.JSON:
 	default:
 		return nil, errors.Errorf("invalid progress mode %s", mode)
 	}
@@ -18,5 +18,6 @@ const (
 	AUTO  = "auto"
 	TTY   = "tty"
 	PLAIN = "plain"
+	JSON  = "json"
 	NONE  = ""
 )
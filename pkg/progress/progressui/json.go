@@ -0,0 +1,132 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progressui
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+)
+
+type EventType string
+
+const (
+	EventTypeVertex  EventType = "vertex"
+	EventTypeStatus  EventType = "status"
+	EventTypeLog     EventType = "log"
+	EventTypeWarning EventType = "warning"
+)
+
+// Event is the machine-readable progress event, one per line in the json mode.
+type Event struct {
+	Type      EventType     `json:"type"`
+	Timestamp time.Time     `json:"timestamp"`
+	Vertex    digest.Digest `json:"vertex,omitempty"`
+	Name      string        `json:"name,omitempty"`
+	Started   *time.Time    `json:"started,omitempty"`
+	Completed *time.Time    `json:"completed,omitempty"`
+	Cached    bool          `json:"cached,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	// ID, Current and Total are set for the status events.
+	ID      string `json:"id,omitempty"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+	// Stream and Data are set for the log events.
+	Stream int    `json:"stream,omitempty"`
+	Data   string `json:"data,omitempty"`
+}
+
+// DisplaySolveStatusJSON writes one JSON event per vertex, status, log line and warning.
+func DisplaySolveStatusJSON(ctx context.Context, w io.Writer, ch chan *client.SolveStatus) error {
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case <-ctx.Done():
+			drainSolveStatus(ch)
+			return ctx.Err()
+		case ss, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			for _, e := range solveStatusToEvents(ss, time.Now()) {
+				if err := enc.Encode(e); err != nil {
+					drainSolveStatus(ch)
+					return err
+				}
+			}
+		}
+	}
+}
+
+// drainSolveStatus consumes the channel until it is closed, so that the
+// senders are not blocked after the display returns.
+func drainSolveStatus(ch chan *client.SolveStatus) {
+	for range ch {
+	}
+}
+
+func solveStatusToEvents(ss *client.SolveStatus, now time.Time) []Event {
+	events := make([]Event, 0, len(ss.Vertexes)+len(ss.Statuses)+len(ss.Logs)+len(ss.Warnings))
+	for _, v := range ss.Vertexes {
+		events = append(events, Event{
+			Type:      EventTypeVertex,
+			Timestamp: now,
+			Vertex:    v.Digest,
+			Name:      v.Name,
+			Started:   v.Started,
+			Completed: v.Completed,
+			Cached:    v.Cached,
+			Error:     v.Error,
+		})
+	}
+	for _, s := range ss.Statuses {
+		events = append(events, Event{
+			Type:      EventTypeStatus,
+			Timestamp: s.Timestamp,
+			Vertex:    s.Vertex,
+			Name:      s.Name,
+			ID:        s.ID,
+			Current:   s.Current,
+			Total:     s.Total,
+			Started:   s.Started,
+			Completed: s.Completed,
+		})
+	}
+	for _, l := range ss.Logs {
+		for _, line := range strings.Split(strings.TrimSuffix(string(l.Data), "\n"), "\n") {
+			events = append(events, Event{
+				Type:      EventTypeLog,
+				Timestamp: l.Timestamp,
+				Vertex:    l.Vertex,
+				Stream:    l.Stream,
+				Data:      line,
+			})
+		}
+	}
+	for _, warn := range ss.Warnings {
+		events = append(events, Event{
+			Type:      EventTypeWarning,
+			Timestamp: now,
+			Vertex:    warn.Vertex,
+			Data:      string(warn.Short),
+		})
+	}
+	return events
+}
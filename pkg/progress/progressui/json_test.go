@@ -0,0 +1,83 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progressui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisplaySolveStatusJSON(t *testing.T) {
+	dgst := digest.FromString("vertex")
+	started := time.Unix(1, 0)
+	ch := make(chan *client.SolveStatus, 2)
+	ch <- &client.SolveStatus{
+		Vertexes: []*client.Vertex{{Digest: dgst, Name: "pip install", Started: &started}},
+		Statuses: []*client.VertexStatus{{ID: "layer", Vertex: dgst, Current: 10, Total: 20}},
+	}
+	ch <- &client.SolveStatus{
+		Logs: []*client.VertexLog{{Vertex: dgst, Stream: 1, Data: []byte("line 1\nline 2\n")}},
+	}
+	close(ch)
+
+	var buf bytes.Buffer
+	require.NoError(t, DisplaySolveStatusJSON(context.Background(), &buf, ch))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	events := make([]Event, 0, len(lines))
+	for _, line := range lines {
+		var e Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		events = append(events, e)
+	}
+	require.Equal(t, EventTypeVertex, events[0].Type)
+	require.Equal(t, "pip install", events[0].Name)
+	require.Equal(t, EventTypeStatus, events[1].Type)
+	require.Equal(t, int64(10), events[1].Current)
+	require.Equal(t, EventTypeLog, events[2].Type)
+	require.Equal(t, "line 1", events[2].Data)
+	require.Equal(t, "line 2", events[3].Data)
+}
+
+func TestDisplaySolveStatusJSONDrainsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ch := make(chan *client.SolveStatus)
+	errCh := make(chan error, 1)
+	go func() {
+		var buf bytes.Buffer
+		errCh <- DisplaySolveStatusJSON(ctx, &buf, ch)
+	}()
+
+	// The sends block forever if the channel is not drained after cancelling.
+	for i := 0; i < 3; i++ {
+		select {
+		case ch <- &client.SolveStatus{}:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the status channel is not drained after cancelling")
+		}
+	}
+	close(ch)
+	require.ErrorIs(t, <-errCh, context.Canceled)
+}
@@ -0,0 +1,180 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progressui
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+)
+
+// Profiler records the duration, cache hit and transferred bytes of every
+// vertex in the build.
+type Profiler struct {
+	mu       sync.Mutex
+	vertexes map[digest.Digest]*profileVertex
+	order    []digest.Digest
+}
+
+type profileVertex struct {
+	name      string
+	started   *time.Time
+	completed *time.Time
+	cached    bool
+	err       string
+	// bytes is the latest progress of every status, keyed by status ID.
+	bytes map[string]int64
+}
+
+func NewProfiler() *Profiler {
+	return &Profiler{
+		vertexes: make(map[digest.Digest]*profileVertex),
+	}
+}
+
+// Consume records the solve status until the channel is closed.
+func (p *Profiler) Consume(ch chan *client.SolveStatus) {
+	for ss := range ch {
+		p.Update(ss)
+	}
+}
+
+func (p *Profiler) Update(ss *client.SolveStatus) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, v := range ss.Vertexes {
+		pv := p.vertex(v.Digest)
+		pv.name = v.Name
+		if v.Started != nil && (pv.started == nil || v.Started.Before(*pv.started)) {
+			pv.started = v.Started
+		}
+		if v.Completed != nil {
+			pv.completed = v.Completed
+		}
+		pv.cached = v.Cached
+		pv.err = v.Error
+	}
+	for _, s := range ss.Statuses {
+		pv := p.vertex(s.Vertex)
+		if s.Current > pv.bytes[s.ID] {
+			pv.bytes[s.ID] = s.Current
+		}
+	}
+}
+
+func (p *Profiler) vertex(dgst digest.Digest) *profileVertex {
+	pv, ok := p.vertexes[dgst]
+	if !ok {
+		pv = &profileVertex{bytes: make(map[string]int64)}
+		p.vertexes[dgst] = pv
+		p.order = append(p.order, dgst)
+	}
+	return pv
+}
+
+// traceEvent is the complete event in the Chrome trace event format.
+// Refer to https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU
+type traceEvent struct {
+	Name      string                 `json:"name"`
+	Category  string                 `json:"cat"`
+	Phase     string                 `json:"ph"`
+	Timestamp int64                  `json:"ts"`
+	Duration  int64                  `json:"dur"`
+	PID       int                    `json:"pid"`
+	TID       int                    `json:"tid"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// WriteChromeTrace writes the profile in the Chrome trace event format, which
+// can be loaded in chrome://tracing or https://ui.perfetto.dev.
+func (p *Profiler) WriteChromeTrace(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := make([]traceEvent, 0, len(p.order))
+	// lanes records the end time of the last vertex in every thread, so that
+	// parallel vertexes are displayed in different rows.
+	lanes := []time.Time{}
+	for _, dgst := range p.sortedVertexes() {
+		pv := p.vertexes[dgst]
+		end := *pv.started
+		if pv.completed != nil {
+			end = *pv.completed
+		}
+		tid := -1
+		for i, last := range lanes {
+			if !pv.started.Before(last) {
+				tid = i
+				break
+			}
+		}
+		if tid == -1 {
+			tid = len(lanes)
+			lanes = append(lanes, end)
+		} else {
+			lanes[tid] = end
+		}
+
+		var bytes int64
+		for _, b := range pv.bytes {
+			bytes += b
+		}
+		args := map[string]interface{}{
+			"digest": dgst.String(),
+			"cached": pv.cached,
+			"bytes":  bytes,
+		}
+		if pv.err != "" {
+			args["error"] = pv.err
+		}
+		events = append(events, traceEvent{
+			Name:      pv.name,
+			Category:  "vertex",
+			Phase:     "X",
+			Timestamp: pv.started.UnixMicro(),
+			Duration:  end.Sub(*pv.started).Microseconds(),
+			PID:       1,
+			TID:       tid,
+			Args:      args,
+		})
+	}
+	return json.NewEncoder(w).Encode(traceFile{
+		TraceEvents:     events,
+		DisplayTimeUnit: "ms",
+	})
+}
+
+// sortedVertexes returns the started vertexes ordered by the start time.
+func (p *Profiler) sortedVertexes() []digest.Digest {
+	res := make([]digest.Digest, 0, len(p.order))
+	for _, dgst := range p.order {
+		if p.vertexes[dgst].started != nil {
+			res = append(res, dgst)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return p.vertexes[res[i]].started.Before(*p.vertexes[res[j]].started)
+	})
+	return res
+}
@@ -0,0 +1,76 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progressui
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfilerChromeTrace(t *testing.T) {
+	a, b, c := digest.FromString("a"), digest.FromString("b"), digest.FromString("c")
+	t0 := time.Unix(100, 0)
+	t1, t2, t3 := t0.Add(time.Second), t0.Add(2*time.Second), t0.Add(3*time.Second)
+
+	p := NewProfiler()
+	p.Update(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: a, Name: "pull image", Started: &t0},
+			{Digest: b, Name: "pip install", Started: &t0},
+		},
+		Statuses: []*client.VertexStatus{
+			{ID: "layer-1", Vertex: a, Current: 50},
+			{ID: "layer-2", Vertex: a, Current: 100},
+		},
+	})
+	p.Update(&client.SolveStatus{
+		Vertexes: []*client.Vertex{
+			{Digest: a, Name: "pull image", Started: &t0, Completed: &t1},
+			{Digest: b, Name: "pip install", Started: &t0, Completed: &t3},
+			{Digest: c, Name: "apt install", Started: &t2, Completed: &t2, Cached: true},
+		},
+		Statuses: []*client.VertexStatus{
+			{ID: "layer-1", Vertex: a, Current: 80},
+		},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, p.WriteChromeTrace(&buf))
+	var trace traceFile
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+	require.Len(t, trace.TraceEvents, 3)
+
+	pull := trace.TraceEvents[0]
+	require.Equal(t, "pull image", pull.Name)
+	require.Equal(t, "X", pull.Phase)
+	require.Equal(t, t0.UnixMicro(), pull.Timestamp)
+	require.Equal(t, time.Second.Microseconds(), pull.Duration)
+	require.Equal(t, float64(180), pull.Args["bytes"])
+
+	pip := trace.TraceEvents[1]
+	require.Equal(t, "pip install", pip.Name)
+	require.NotEqual(t, pull.TID, pip.TID)
+
+	apt := trace.TraceEvents[2]
+	require.Equal(t, true, apt.Args["cached"])
+	// apt starts after the image pulling is finished, thus reuses the row.
+	require.Equal(t, pull.TID, apt.TID)
+}
@@ -17,6 +17,7 @@ package progresswriter
 
 import (
 	"context"
+	"io"
 	"os"
 
 	"github.com/cockroachdb/errors"
@@ -74,7 +75,9 @@ func Tee(w Writer, ch chan *client.SolveStatus) Writer {
 	return t
 }
 
-func NewPrinter(ctx context.Context, out console.File, mode string) (Writer, error) {
+// NewPrinter creates the progress writer. The console is only used when the
+// output is a console file.
+func NewPrinter(ctx context.Context, out io.Writer, mode string) (Writer, error) {
 	statusCh := make(chan *client.SolveStatus)
 	doneCh := make(chan struct{})
 
@@ -90,7 +93,14 @@ func NewPrinter(ctx context.Context, out console.File, mode string) (Writer, err
 	var c console.Console
 	switch mode {
 	case progressmode.AUTO, progressmode.TTY, progressmode.NONE:
-		if cons, err := console.ConsoleFromFile(out); err == nil {
+		f, ok := out.(console.File)
+		if !ok {
+			if mode == progressmode.TTY {
+				return nil, errors.New("failed to get console: the output is not a file")
+			}
+			break
+		}
+		if cons, err := console.ConsoleFromFile(f); err == nil {
 			c = cons
 		} else {
 			if mode == progressmode.TTY {
@@ -98,6 +108,12 @@ func NewPrinter(ctx context.Context, out console.File, mode string) (Writer, err
 			}
 		}
 	case progressmode.PLAIN:
+	case progressmode.JSON:
+		go func() {
+			pw.err = progressui.DisplaySolveStatusJSON(ctx, out, statusCh)
+			close(doneCh)
+		}()
+		return pw, nil
 	default:
 		return nil, errors.Errorf("invalid progress mode %s", mode)
 	}
@@ -0,0 +1,134 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/gliderlabs/ssh"
+	"github.com/sirupsen/logrus"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/tensorchord/envd/pkg/types"
+	"github.com/tensorchord/envd/pkg/util/asciicast"
+)
+
+// AuditLogger writes the audit events as JSON lines.
+type AuditLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewAuditLogger(w io.Writer) *AuditLogger {
+	return &AuditLogger{w: w}
+}
+
+// OpenAuditLog opens the audit log file in the append mode.
+func OpenAuditLog(path string) (*AuditLogger, io.Closer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to create the directory of %s", path)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to open the audit log %s", path)
+	}
+	return NewAuditLogger(f), f, nil
+}
+
+// Log writes the event, it is a no-op if the logger is nil.
+func (a *AuditLogger) Log(e types.AuditEvent) {
+	if a == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to marshal the audit event")
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Write(append(b, '\n')); err != nil {
+		logrus.WithError(err).Warn("failed to write the audit event")
+	}
+}
+
+func newAuditEvent(ctx ssh.Context, typ types.AuditEventType) types.AuditEvent {
+	e := types.AuditEvent{
+		Type:         typ,
+		Time:         time.Now(),
+		ConnectionID: ctx.SessionID(),
+		User:         ctx.User(),
+	}
+	if addr := ctx.RemoteAddr(); addr != nil {
+		e.RemoteAddr = addr.String()
+	}
+	if key, ok := ctx.Value(ssh.ContextKeyPublicKey).(ssh.PublicKey); ok && key != nil {
+		e.KeyFingerprint = gossh.FingerprintSHA256(key)
+	}
+	return e
+}
+
+// newRecording creates the asciinema recording of the PTY session.
+func (srv *Server) newRecording(sessionID string, s ssh.Session, ptyReq ssh.Pty) (*asciicast.Writer, io.Closer, string, error) {
+	if err := os.MkdirAll(srv.RecordingDir, 0700); err != nil {
+		return nil, nil, "", errors.Wrapf(err, "failed to create %s", srv.RecordingDir)
+	}
+	path := filepath.Join(srv.RecordingDir, sessionID+".cast")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, "", errors.Wrapf(err, "failed to create the recording %s", path)
+	}
+	w, err := asciicast.NewWriter(f, asciicast.Header{
+		Width:   ptyReq.Window.Width,
+		Height:  ptyReq.Window.Height,
+		Command: s.RawCommand(),
+		Env: map[string]string{
+			"SHELL": srv.Shell,
+			"TERM":  ptyReq.Term,
+		},
+	})
+	if err != nil {
+		f.Close()
+		return nil, nil, "", err
+	}
+	return w, f, path, nil
+}
+
+// recordingWriter never fails the session because of the recording.
+type recordingWriter struct {
+	logger *logrus.Entry
+	rec    *asciicast.Writer
+	failed bool
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	if w.failed {
+		return len(p), nil
+	}
+	if _, err := w.rec.Write(p); err != nil {
+		w.logger.WithError(err).Warn("failed to record the session, stop recording")
+		w.failed = true
+	}
+	return len(p), nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshd
+
+import (
+	"net"
+	"strings"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+// ForwardPolicy restricts the port forwarding requests. The zero value
+// allows all the requests.
+type ForwardPolicy struct {
+	// Disabled rejects all the requests.
+	Disabled bool
+	// Ports allows only the ports in the ranges if it is not empty.
+	Ports []types.PortRange
+	// Hosts allows only the hosts if it is not empty. The host is either
+	// a hostname, an IP address or a CIDR, e.g. `10.0.0.0/8`.
+	Hosts []string
+}
+
+// Allow returns true if the host and the port are allowed by the policy.
+func (p ForwardPolicy) Allow(host string, port uint32) bool {
+	if p.Disabled {
+		return false
+	}
+	return p.allowPort(port) && p.allowHost(host)
+}
+
+func (p ForwardPolicy) allowPort(port uint32) bool {
+	if len(p.Ports) == 0 {
+		return true
+	}
+	for _, r := range p.Ports {
+		if r.Contains(port) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p ForwardPolicy) allowHost(host string) bool {
+	if len(p.Hosts) == 0 {
+		return true
+	}
+	ip := net.ParseIP(host)
+	for _, h := range p.Hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(h); err == nil && ip != nil && cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
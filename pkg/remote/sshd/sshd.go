@@ -16,13 +16,15 @@
 package sshd
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unsafe"
@@ -33,6 +35,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/sftp"
 	"github.com/sirupsen/logrus"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/tensorchord/envd/pkg/types"
+	"github.com/tensorchord/envd/pkg/util/asciicast"
 )
 
 // LoadAuthorizedKeys loads path as an array.
@@ -71,7 +77,33 @@ type Server struct {
 	Shell string
 
 	AuthorizedKeys []ssh.PublicKey
-	Hostkey        ssh.Signer
+	// AuthorizedKeysPath is reloaded on every authentication if it is set,
+	// so that the keys can be rotated without restarting the server.
+	AuthorizedKeysPath string
+	Hostkey            ssh.Signer
+
+	// AuditLog logs the sessions and the port forwardings if it is set.
+	AuditLog *AuditLogger
+	// RecordingDir records the PTY sessions in the asciinema format if it is set.
+	RecordingDir string
+
+	// LocalForward restricts the destinations of the local port forwardings.
+	LocalForward ForwardPolicy
+	// RemoteForward restricts the addresses bound by the remote port forwardings.
+	RemoteForward ForwardPolicy
+	// DisableSFTP disables the sftp subsystem.
+	DisableSFTP bool
+	// MaxSessions limits the concurrent sessions if it is positive.
+	MaxSessions int
+	// IdleTimeout closes the connection without activity if it is positive.
+	IdleTimeout time.Duration
+
+	// WebTerminalPort serves the WebSocket terminal for the browsers if it is set.
+	WebTerminalPort int
+	// WebTerminalToken authenticates the web terminal requests.
+	WebTerminalToken string
+
+	sessions atomic.Int32
 }
 
 // ListenAndServe starts the SSH server using port
@@ -80,6 +112,22 @@ func (srv *Server) ListenAndServe() error {
 	if err != nil {
 		return errors.Wrap(err, "failed to parse server configs")
 	}
+	if srv.WebTerminalPort != 0 {
+		if srv.WebTerminalToken == "" {
+			return errors.New("the token is required by the web terminal")
+		}
+		web := &http.Server{
+			Addr:              fmt.Sprintf(":%d", srv.WebTerminalPort),
+			Handler:           srv.webTerminalHandler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			logrus.Infof("web terminal started in 0.0.0.0:%d", srv.WebTerminalPort)
+			if err := web.ListenAndServe(); err != nil {
+				logrus.WithError(err).Error("web terminal stopped")
+			}
+		}()
+	}
 	return server.ListenAndServe()
 }
 
@@ -88,27 +136,41 @@ func (srv *Server) getServer() (*ssh.Server, error) {
 	forwardHandler := &ssh.ForwardedTCPHandler{}
 
 	server := &ssh.Server{
-		Addr:    fmt.Sprintf(":%d", srv.Port),
-		Handler: srv.connectionHandler,
+		Addr:        fmt.Sprintf(":%d", srv.Port),
+		Handler:     srv.connectionHandler,
+		IdleTimeout: srv.IdleTimeout,
 		ChannelHandlers: map[string]ssh.ChannelHandler{
 			"direct-tcpip": ssh.DirectTCPIPHandler,
-			"session":      ssh.DefaultSessionHandler,
+			"session":      srv.sessionHandler,
 		},
 		LocalPortForwardingCallback: ssh.LocalPortForwardingCallback(func(ctx ssh.Context, dhost string, dport uint32) bool {
-			log.Println("Accepted forward", dhost, dport)
-			return true
+			granted := srv.LocalForward.Allow(dhost, dport)
+			logrus.WithFields(logrus.Fields{
+				"host":    dhost,
+				"port":    dport,
+				"granted": granted,
+			}).Info("local port forwarding requested")
+			srv.auditForward(ctx, "local", dhost, dport, granted)
+			return granted
 		}),
 		ReversePortForwardingCallback: ssh.ReversePortForwardingCallback(func(ctx ssh.Context, host string, port uint32) bool {
-			log.Println("attempt to bind", host, port, "granted")
-			return true
+			granted := srv.RemoteForward.Allow(host, port)
+			logrus.WithFields(logrus.Fields{
+				"host":    host,
+				"port":    port,
+				"granted": granted,
+			}).Info("remote port forwarding requested")
+			srv.auditForward(ctx, "remote", host, port, granted)
+			return granted
 		}),
 		RequestHandlers: map[string]ssh.RequestHandler{
 			"tcpip-forward":        forwardHandler.HandleSSHRequest,
 			"cancel-tcpip-forward": forwardHandler.HandleSSHRequest,
 		},
-		SubsystemHandlers: map[string]ssh.SubsystemHandler{
-			"sftp": sftpHandler,
-		},
+		SubsystemHandlers: map[string]ssh.SubsystemHandler{},
+	}
+	if !srv.DisableSFTP {
+		server.SubsystemHandlers["sftp"] = sftpHandler
 	}
 
 	if srv.AuthorizedKeys != nil {
@@ -125,18 +187,34 @@ func (srv *Server) getServer() (*ssh.Server, error) {
 	return server, nil
 }
 
-func (srv Server) buildCmd(logger *logrus.Entry, s ssh.Session) *exec.Cmd {
+// sessionHandler rejects the session channel if there are too many sessions.
+func (srv *Server) sessionHandler(server *ssh.Server, conn *gossh.ServerConn, newChan gossh.NewChannel, ctx ssh.Context) {
+	if srv.MaxSessions > 0 {
+		if n := srv.sessions.Add(1); int(n) > srv.MaxSessions {
+			srv.sessions.Add(-1)
+			logrus.Warnf("reject the session from %s, the limit of %d sessions is reached", conn.RemoteAddr(), srv.MaxSessions)
+			if err := newChan.Reject(gossh.ResourceShortage, fmt.Sprintf("the limit of %d sessions is reached", srv.MaxSessions)); err != nil {
+				logrus.WithError(err).Debug("failed to reject the session")
+			}
+			return
+		}
+		defer srv.sessions.Add(-1)
+	}
+	ssh.DefaultSessionHandler(server, conn, newChan, ctx)
+}
+
+func (srv *Server) buildCmd(ctx context.Context, logger *logrus.Entry, rawCommand string, environ []string) *exec.Cmd {
 	var cmd *exec.Cmd
 
-	if len(s.RawCommand()) == 0 {
-		cmd = exec.Command(srv.Shell)
+	if len(rawCommand) == 0 {
+		cmd = exec.CommandContext(ctx, srv.Shell)
 	} else {
-		args := []string{"-c", s.RawCommand()}
-		cmd = exec.Command(srv.Shell, args...)
+		args := []string{"-c", rawCommand}
+		cmd = exec.CommandContext(ctx, srv.Shell, args...)
 	}
 
 	cmd.Env = append(cmd.Env, os.Environ()...)
-	cmd.Env = append(cmd.Env, s.Environ()...)
+	cmd.Env = append(cmd.Env, environ...)
 
 	logger.Debugf("ssh server command: %s", cmd.String())
 	return cmd
@@ -149,14 +227,19 @@ func (srv *Server) connectionHandler(s ssh.Session) {
 	l.SetLevel(logrus.GetLevel())
 	logger := l.WithField("session.id", sessionID)
 
+	event := newAuditEvent(s.Context(), types.AuditEventSession)
+	event.SessionID = sessionID
+	event.Command = s.RawCommand()
 	defer func() {
 		s.Close()
 		logger.Info("session closed")
+		event.Duration = time.Since(event.Time).Seconds()
+		srv.AuditLog.Log(event)
 	}()
 
 	logger.Infof("starting ssh session with command '%+v'", s.RawCommand())
 
-	cmd := srv.buildCmd(logger, s)
+	cmd := srv.buildCmd(context.Background(), logger, s.RawCommand(), s.Environ())
 
 	if ssh.AgentRequested(s) {
 		logger.Info("agent requested")
@@ -174,11 +257,32 @@ func (srv *Server) connectionHandler(s ssh.Session) {
 	ptyReq, winCh, isPty := s.Pty()
 	if isPty {
 		logger.Infoln("handling PTY session")
-		if err := handlePTY(logger, cmd, s, ptyReq, winCh); err != nil {
+		event.PTY = true
+		event.Term = ptyReq.Term
+		var rec *asciicast.Writer
+		if srv.RecordingDir != "" {
+			var closer io.Closer
+			var err error
+			rec, closer, event.Recording, err = srv.newRecording(sessionID, s, ptyReq)
+			if err != nil {
+				logger.WithError(err).Warn("failed to record the session")
+			} else {
+				defer func() {
+					if err := rec.Flush(); err != nil {
+						logger.WithError(err).Warn("failed to flush the recording")
+					}
+					closer.Close()
+				}()
+			}
+		}
+		if err := handlePTY(logger, cmd, s, ptyReq, winCh, rec); err != nil {
+			code := getExitStatusFromError(err)
+			event.ExitCode = &code
 			sendErrAndExit(logger, s, err)
 			return
 		}
 
+		event.ExitCode = new(int)
 		err := s.Exit(0)
 		if err != nil {
 			logger.WithError(err).Warnln("exit session with error")
@@ -188,17 +292,22 @@ func (srv *Server) connectionHandler(s ssh.Session) {
 
 	logger.Infoln("handling non PTY session")
 	if err := handleNoTTY(logger, cmd, s); err != nil {
+		code := getExitStatusFromError(err)
+		event.ExitCode = &code
 		sendErrAndExit(logger, s, err)
 		return
 	}
 
+	event.ExitCode = new(int)
 	err := s.Exit(0)
 	if err != nil {
 		logger.WithError(err).Warnln("exit session with error")
 	}
 }
 
-func handlePTY(logger *logrus.Entry, cmd *exec.Cmd, s ssh.Session, ptyReq ssh.Pty, winCh <-chan ssh.Window) error {
+// handlePTY runs the command in the PTY with the input and output of s, the
+// output is recorded if rec is not nil.
+func handlePTY(logger *logrus.Entry, cmd *exec.Cmd, s io.ReadWriter, ptyReq ssh.Pty, winCh <-chan ssh.Window, rec *asciicast.Writer) error {
 	if len(ptyReq.Term) > 0 {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("TERM=%s", ptyReq.Term))
 	}
@@ -212,6 +321,11 @@ func handlePTY(logger *logrus.Entry, cmd *exec.Cmd, s ssh.Session, ptyReq ssh.Pt
 	go func() {
 		for win := range winCh {
 			setWinSize(f, win.Width, win.Height)
+			if rec != nil {
+				if err := rec.Resize(win.Width, win.Height); err != nil {
+					logger.WithError(err).Warn("failed to record the window size")
+				}
+			}
 		}
 	}()
 
@@ -225,7 +339,11 @@ func handlePTY(logger *logrus.Entry, cmd *exec.Cmd, s ssh.Session, ptyReq ssh.Pt
 	waitCh := make(chan struct{})
 	go func() {
 		defer close(waitCh)
-		_, err := io.Copy(s, f) // stdout
+		var out io.Writer = s
+		if rec != nil {
+			out = io.MultiWriter(s, &recordingWriter{logger: logger, rec: rec})
+		}
+		_, err := io.Copy(out, f) // stdout
 		if err != nil {
 			logger.WithError(err).Warningln("failed to copy stdin")
 		}
@@ -347,8 +465,26 @@ func handleNoTTY(logger *logrus.Entry, cmd *exec.Cmd, s ssh.Session) error {
 	return nil
 }
 
+func (srv *Server) auditForward(ctx ssh.Context, forward, host string, port uint32, granted bool) {
+	event := newAuditEvent(ctx, types.AuditEventForward)
+	event.Forward = forward
+	event.ForwardHost = host
+	event.ForwardPort = port
+	event.Granted = &granted
+	srv.AuditLog.Log(event)
+}
+
 func (srv *Server) authorize(ctx ssh.Context, key ssh.PublicKey) bool {
-	for _, k := range srv.AuthorizedKeys {
+	keys := srv.AuthorizedKeys
+	if srv.AuthorizedKeysPath != "" {
+		var err error
+		keys, err = LoadAuthorizedKeys(srv.AuthorizedKeysPath)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to load authorized keys at %s", srv.AuthorizedKeysPath)
+			return false
+		}
+	}
+	for _, k := range keys {
 		if ssh.KeysEqual(key, k) {
 			logrus.Debugf("authorized key: %s", k.Type())
 			return true
@@ -0,0 +1,222 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+// startServer serves srv on a random local port and returns a connected client.
+func startServer(t *testing.T, srv *Server) *gossh.Client {
+	t.Helper()
+	server, err := srv.getServer()
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(l) //nolint:errcheck
+	t.Cleanup(func() { server.Close() })
+
+	client, err := gossh.Dial("tcp", l.Addr().String(), &gossh.ClientConfig{
+		User:            "envd",
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+		Timeout:         5 * time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// startEcho starts a TCP echo server and returns its port.
+func startEcho(t *testing.T) uint32 {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn) //nolint:errcheck
+			}()
+		}
+	}()
+	return uint32(l.Addr().(*net.TCPAddr).Port)
+}
+
+func freePort(t *testing.T) uint32 {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return uint32(l.Addr().(*net.TCPAddr).Port)
+}
+
+func addr(host string, port uint32) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+func TestLocalForwardPolicy(t *testing.T) {
+	port := startEcho(t)
+	client := startServer(t, &Server{
+		LocalForward: ForwardPolicy{
+			Ports: []types.PortRange{{Min: port, Max: port}},
+			Hosts: []string{"127.0.0.0/8"},
+		},
+	})
+
+	conn, err := client.Dial("tcp", addr("127.0.0.1", port))
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+
+	// The port is not allowed.
+	_, err = client.Dial("tcp", addr("127.0.0.1", port+1))
+	require.Error(t, err)
+	// The host is not allowed.
+	_, err = client.Dial("tcp", addr("localhost", port))
+	require.Error(t, err)
+}
+
+func TestRemoteForwardPolicy(t *testing.T) {
+	allowed, denied := freePort(t), freePort(t)
+	client := startServer(t, &Server{
+		RemoteForward: ForwardPolicy{
+			Ports: []types.PortRange{{Min: allowed, Max: allowed}},
+		},
+	})
+
+	l, err := client.Listen("tcp", addr("127.0.0.1", allowed))
+	require.NoError(t, err)
+	l.Close()
+
+	_, err = client.Listen("tcp", addr("127.0.0.1", denied))
+	require.Error(t, err)
+}
+
+func TestDisablePortForward(t *testing.T) {
+	port := startEcho(t)
+	client := startServer(t, &Server{
+		LocalForward:  ForwardPolicy{Disabled: true},
+		RemoteForward: ForwardPolicy{Disabled: true},
+	})
+
+	_, err := client.Dial("tcp", addr("127.0.0.1", port))
+	require.Error(t, err)
+	_, err = client.Listen("tcp", addr("127.0.0.1", freePort(t)))
+	require.Error(t, err)
+}
+
+func TestDisableSFTP(t *testing.T) {
+	client := startServer(t, &Server{})
+	sftpClient, err := sftp.NewClient(client)
+	require.NoError(t, err)
+	sftpClient.Close()
+
+	client = startServer(t, &Server{DisableSFTP: true})
+	_, err = sftp.NewClient(client)
+	require.Error(t, err)
+}
+
+func TestMaxSessions(t *testing.T) {
+	client := startServer(t, &Server{MaxSessions: 1})
+
+	session, err := client.NewSession()
+	require.NoError(t, err)
+	_, err = client.NewSession()
+	require.Error(t, err)
+
+	// The slot is released when the session is closed.
+	session.Close()
+	require.Eventually(t, func() bool {
+		s, err := client.NewSession()
+		if err != nil {
+			return false
+		}
+		s.Close()
+		return true
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	client := startServer(t, &Server{IdleTimeout: 200 * time.Millisecond})
+
+	done := make(chan error, 1)
+	go func() { done <- client.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the idle connection is not closed")
+	}
+}
+
+func TestForwardPolicyAllow(t *testing.T) {
+	var p ForwardPolicy
+	require.True(t, p.Allow("example.com", 22))
+
+	p = ForwardPolicy{
+		Ports: []types.PortRange{{Min: 8000, Max: 9000}},
+		Hosts: []string{"LocalHost", "10.0.0.0/8"},
+	}
+	require.True(t, p.Allow("localhost", 8000))
+	require.True(t, p.Allow("10.1.2.3", 9000))
+	require.False(t, p.Allow("localhost", 9001))
+	require.False(t, p.Allow("192.168.0.1", 8888))
+
+	p.Disabled = true
+	require.False(t, p.Allow("localhost", 8000))
+}
+
+func TestAuditForward(t *testing.T) {
+	var buf bytes.Buffer
+	auditLog := NewAuditLogger(&buf)
+	client := startServer(t, &Server{
+		AuditLog:     auditLog,
+		LocalForward: ForwardPolicy{Disabled: true},
+	})
+	port := freePort(t)
+	_, err := client.Dial("tcp", addr("127.0.0.1", port))
+	require.Error(t, err)
+
+	// The event is logged before the request is rejected.
+	auditLog.mu.Lock()
+	defer auditLog.mu.Unlock()
+	var event types.AuditEvent
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	require.Equal(t, types.AuditEventForward, event.Type)
+	require.Equal(t, "local", event.Forward)
+	require.Equal(t, port, event.ForwardPort)
+	require.False(t, *event.Granted)
+}
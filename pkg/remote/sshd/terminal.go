@@ -0,0 +1,233 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshd
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gliderlabs/ssh"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+// The terminal protocol is compatible with the xterm.js attach addon:
+//   - the text messages from the browser are the input of the PTY
+//   - the binary messages from the browser are the JSON control messages
+//   - the binary messages to the browser are the output of the PTY
+const (
+	terminalControlResize = "resize"
+
+	defaultTerminalCols = 80
+	defaultTerminalRows = 24
+)
+
+//go:embed terminal.html
+var terminalPage []byte
+
+type terminalControl struct {
+	Type string `json:"type"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+// webTerminalHandler serves the terminal page on `/` and the WebSocket
+// endpoint on `/ws`, both of them require the token.
+func (srv *Server) webTerminalHandler() http.Handler {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		// The requests are authenticated by the token, and the origin may
+		// be rewritten by the gateway in front of the environment.
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if !srv.authorizeWebTerminal(r) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if _, err := w.Write(terminalPage); err != nil {
+			logrus.WithError(err).Debug("failed to write the terminal page")
+		}
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if !srv.authorizeWebTerminal(r) {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logrus.WithError(err).Warn("failed to upgrade the web terminal connection")
+			return
+		}
+		srv.webTerminalSession(r, conn)
+	})
+	return mux
+}
+
+// authorizeWebTerminal accepts the token in the `token` query parameter or
+// in the `Authorization: token <token>` header, the same as Jupyter.
+func (srv *Server) authorizeWebTerminal(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "token ") {
+		token = strings.TrimPrefix(auth, "token ")
+	}
+	return token != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(srv.WebTerminalToken)) == 1
+}
+
+func (srv *Server) webTerminalSession(r *http.Request, conn *websocket.Conn) {
+	sessionID := uuid.New().String()
+	l := logrus.New()
+	l.SetLevel(logrus.GetLevel())
+	logger := l.WithField("session.id", sessionID)
+
+	event := types.AuditEvent{
+		Type:       types.AuditEventSession,
+		Time:       time.Now(),
+		SessionID:  sessionID,
+		User:       "web-terminal",
+		RemoteAddr: r.RemoteAddr,
+		PTY:        true,
+		Term:       "xterm-256color",
+	}
+	defer func() {
+		conn.Close()
+		logger.Info("web terminal session closed")
+		event.Duration = time.Since(event.Time).Seconds()
+		srv.AuditLog.Log(event)
+	}()
+	logger.Infof("starting web terminal session from %s", r.RemoteAddr)
+
+	window := ssh.Window{
+		Width:  queryInt(r, "cols", defaultTerminalCols),
+		Height: queryInt(r, "rows", defaultTerminalRows),
+	}
+	t := newWebTerminalConn(conn, window)
+	go t.readLoop(logger)
+
+	// The shell is killed when the browser is disconnected.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-t.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	cmd := srv.buildCmd(ctx, logger, "", nil)
+	ptyReq := ssh.Pty{Term: event.Term, Window: window}
+	code := 0
+	if err := handlePTY(logger, cmd, t, ptyReq, t.winCh, nil); err != nil {
+		code = getExitStatusFromError(err)
+	}
+	event.ExitCode = &code
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, fmt.Sprintf("exit status %d", code))
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+		logger.WithError(err).Debug("failed to close the web terminal connection")
+	}
+}
+
+func queryInt(r *http.Request, key string, defaultValue int) int {
+	v, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil || v <= 0 {
+		return defaultValue
+	}
+	return v
+}
+
+// webTerminalConn adapts the WebSocket connection to the input and output
+// of the PTY.
+type webTerminalConn struct {
+	conn *websocket.Conn
+	// mu serializes the writes to the connection.
+	mu     sync.Mutex
+	input  *io.PipeReader
+	writer *io.PipeWriter
+	winCh  chan ssh.Window
+	closed chan struct{}
+}
+
+func newWebTerminalConn(conn *websocket.Conn, window ssh.Window) *webTerminalConn {
+	pr, pw := io.Pipe()
+	t := &webTerminalConn{
+		conn:   conn,
+		input:  pr,
+		writer: pw,
+		winCh:  make(chan ssh.Window, 1),
+		closed: make(chan struct{}),
+	}
+	t.winCh <- window
+	return t
+}
+
+func (t *webTerminalConn) Read(p []byte) (int, error) {
+	return t.input.Read(p)
+}
+
+func (t *webTerminalConn) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.conn.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (t *webTerminalConn) readLoop(logger *logrus.Entry) {
+	defer func() {
+		t.writer.Close()
+		close(t.winCh)
+		close(t.closed)
+	}()
+	for {
+		typ, data, err := t.conn.ReadMessage()
+		if err != nil {
+			logger.WithError(err).Debug("web terminal connection is closed")
+			return
+		}
+		switch typ {
+		case websocket.TextMessage:
+			if _, err := t.writer.Write(data); err != nil {
+				return
+			}
+		case websocket.BinaryMessage:
+			var control terminalControl
+			if err := json.Unmarshal(data, &control); err != nil {
+				logger.WithError(err).Warn("invalid web terminal control message")
+				continue
+			}
+			if control.Type == terminalControlResize && control.Cols > 0 && control.Rows > 0 {
+				t.winCh <- ssh.Window{Width: control.Cols, Height: control.Rows}
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sshd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+func TestWebTerminalToken(t *testing.T) {
+	srv := &Server{Shell: "bash", WebTerminalToken: "secret"}
+	ts := httptest.NewServer(srv.webTerminalHandler())
+	defer ts.Close()
+
+	for _, url := range []string{ts.URL, ts.URL + "/?token=wrong", ts.URL + "/ws"} {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode, url)
+	}
+
+	resp, err := http.Get(ts.URL + "/?token=secret")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "token secret")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestWebTerminalSession(t *testing.T) {
+	var audit bytes.Buffer
+	auditLog := NewAuditLogger(&audit)
+	srv := &Server{Shell: "bash", WebTerminalToken: "secret", AuditLog: auditLog}
+	ts := httptest.NewServer(srv.webTerminalHandler())
+	defer ts.Close()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws?token=secret&cols=100&rows=30"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	resize, err := json.Marshal(terminalControl{Type: terminalControlResize, Cols: 120, Rows: 40})
+	require.NoError(t, err)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, resize))
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("echo envd-$((1+1)); exit 3\n")))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+	var output strings.Builder
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), err.Error())
+			require.Contains(t, err.Error(), "exit status 3")
+			break
+		}
+		require.Equal(t, websocket.BinaryMessage, typ)
+		output.Write(data)
+	}
+	require.Contains(t, output.String(), "envd-2")
+
+	require.Eventually(t, func() bool {
+		auditLog.mu.Lock()
+		defer auditLog.mu.Unlock()
+		return audit.Len() > 0
+	}, 5*time.Second, 50*time.Millisecond)
+	auditLog.mu.Lock()
+	defer auditLog.mu.Unlock()
+	var event types.AuditEvent
+	require.NoError(t, json.Unmarshal(audit.Bytes(), &event))
+	require.True(t, event.PTY)
+	require.Equal(t, 3, *event.ExitCode)
+}
@@ -27,12 +27,32 @@ type EntryOptions struct {
 	IFace          string
 	Port           int
 	PrivateKeyPath string
+	// KnownHostsPath is the known_hosts file with the pinned host key,
+	// it is used if EnableHostKeyCheck is true.
+	KnownHostsPath string
 
 	EnableHostKeyCheck bool
 	EnableAgentForward bool
 	User               string
 }
 
+// WithPinnedHostKey enables the host key check if the host key of the
+// environment is pinned by envd.
+func (eo EntryOptions) WithPinnedHostKey() EntryOptions {
+	if !HostKeyPinned(eo.Name) {
+		logrus.Debugf("the host key of %s is not pinned, skip the host key check", eo.Name)
+		return eo
+	}
+	path, err := GetKnownHostsPath()
+	if err != nil {
+		logrus.Debugf("failed to get the known_hosts path: %s", err)
+		return eo
+	}
+	eo.EnableHostKeyCheck = true
+	eo.KnownHostsPath = path
+	return eo
+}
+
 // AddEntry adds an entry to the user's sshconfig
 func AddEntry(eo EntryOptions) error {
 	eo.Name = BuildHostname(eo.Name)
@@ -66,11 +86,18 @@ func add(path string, eo EntryOptions) error {
 		newParam(hostKeyAlgorithms, []string{"+ssh-rsa"}, nil),
 		newParam(hostNameKeyword, []string{eo.IFace}, nil),
 		newParam(portKeyword, []string{strconv.Itoa(eo.Port)}, nil),
-		newParam(userKnownHostsFileKeyword, []string{"/dev/null"}, nil),
 		newParam(identityFile, []string{"\"" + eo.PrivateKeyPath + "\""}, nil),
 	}
-	if !eo.EnableHostKeyCheck {
+	if eo.EnableHostKeyCheck {
+		// The host key is pinned for the alias instead of the address,
+		// since the port is changed when the environment is recreated.
 		host.params = append(host.params,
+			newParam(userKnownHostsFileKeyword, []string{"\"" + eo.KnownHostsPath + "\""}, nil),
+			newParam(hostKeyAliasKeyword, []string{eo.Name}, nil),
+			newParam(strictHostKeyCheckingKeyword, []string{"yes"}, nil))
+	} else {
+		host.params = append(host.params,
+			newParam(userKnownHostsFileKeyword, []string{"/dev/null"}, nil),
 			newParam(strictHostKeyCheckingKeyword, []string{"no"}, nil))
 	}
 	if eo.EnableAgentForward {
@@ -119,6 +146,13 @@ func addEntryToWSLHost(eo EntryOptions) error {
 	// Add the entry to the WSL host SSH config
 	logrus.Debugf("Adding entry to WSL's ssh-agent: %s", winSshConfig)
 	eo.PrivateKeyPath = winKeyPath
+	if eo.EnableHostKeyCheck {
+		winKnownHostsPath, err := osutil.CopyToWinEnvdHome(eo.KnownHostsPath, 0600)
+		if err != nil {
+			return err
+		}
+		eo.KnownHostsPath = winKnownHostsPath
+	}
 	err = add(winSshConfig, eo)
 	if err != nil {
 		return err
@@ -0,0 +1,239 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/tensorchord/envd/pkg/config"
+	"github.com/tensorchord/envd/pkg/util/fileutil"
+)
+
+// GetOrCreateHostKey returns the PEM encoded private host key of the
+// environment, which is copied into the environment before it starts. The
+// key is generated on the first call and reused by the later starts. Its
+// public half is pinned in the envd known_hosts file.
+func GetOrCreateHostKey(name string) ([]byte, error) {
+	public, private, err := getHostKeyPaths(name)
+	if err != nil {
+		return nil, err
+	}
+	if !KeyExists(public, private) {
+		if err := generateHostKey(public, private); err != nil {
+			return nil, err
+		}
+	}
+
+	pemBytes, err := os.ReadFile(private)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the host key %s", private)
+	}
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the host key %s", private)
+	}
+	knownHosts, err := GetKnownHostsPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := pinHostKey(knownHosts, BuildHostname(name), signer.PublicKey()); err != nil {
+		return nil, err
+	}
+	return pemBytes, nil
+}
+
+// UnpinHostKey removes the pinned host key of the environment, thus the host
+// key check is skipped, e.g. the environment generates its own host key.
+func UnpinHostKey(name string) error {
+	knownHosts, err := GetKnownHostsPath()
+	if err != nil {
+		return err
+	}
+	return unpinHostKey(knownHosts, BuildHostname(name))
+}
+
+// GetPinnedHostKey returns the host key pinned for the alias (`<env>.envd`),
+// or nil if there is no pinned key, e.g. the image is built by an old envd.
+func GetPinnedHostKey(alias string) (ssh.PublicKey, error) {
+	knownHosts, err := GetKnownHostsPath()
+	if err != nil {
+		return nil, err
+	}
+	return lookupHostKey(knownHosts, alias)
+}
+
+// HostKeyPinned returns true if the host key of the environment is pinned.
+func HostKeyPinned(name string) bool {
+	key, err := GetPinnedHostKey(BuildHostname(name))
+	if err != nil {
+		logrus.Debugf("failed to get the pinned host key of %s: %s", name, err)
+		return false
+	}
+	return key != nil
+}
+
+// GetKnownHostsPath returns the path to the known_hosts file managed by envd.
+func GetKnownHostsPath() (string, error) {
+	path, err := fileutil.ConfigFile(config.KnownHostsFile)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot get known_hosts path")
+	}
+	return path, nil
+}
+
+func getHostKeyPaths(name string) (string, string, error) {
+	if name == "" || strings.ContainsRune(name, os.PathSeparator) {
+		return "", "", errors.Newf("invalid environment name %q", name)
+	}
+	dir := filepath.Join(fileutil.DefaultConfigDir, config.HostKeysDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", errors.Wrapf(err, "failed to create %s", dir)
+	}
+	private := filepath.Join(dir, name)
+	return private + ".pub", private, nil
+}
+
+func generateHostKey(public, private string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate the host key")
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "envd host key")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the host key")
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate the public host key")
+	}
+
+	if err := os.WriteFile(public, ssh.MarshalAuthorizedKey(sshPub), 0600); err != nil {
+		return errors.Wrap(err, "failed to write the public host key")
+	}
+	if err := os.WriteFile(private, pem.EncodeToMemory(block), 0600); err != nil {
+		return errors.Wrap(err, "failed to write the private host key")
+	}
+	logrus.Debugf("created host key at %s and %s", public, private)
+	return nil
+}
+
+// pinHostKey replaces the entries of the alias in the known_hosts file.
+func pinHostKey(path, alias string, key ssh.PublicKey) error {
+	line := knownhosts.Line([]string{alias}, key)
+	var buf bytes.Buffer
+	pinned := false
+
+	dat, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to read %s", path)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	for scanner.Scan() {
+		l := scanner.Text()
+		fields := strings.Fields(l)
+		if len(fields) > 0 && fields[0] == alias {
+			if l != line || pinned {
+				continue
+			}
+			pinned = true
+		}
+		buf.WriteString(l + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "failed to read %s", path)
+	}
+	if pinned && buf.Len() == len(dat) {
+		return nil
+	}
+	if !pinned {
+		buf.WriteString(line + "\n")
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	logrus.Debugf("pinned the host key of %s in %s", alias, path)
+	return nil
+}
+
+// unpinHostKey removes the entries of the alias in the known_hosts file.
+func unpinHostKey(path, alias string) error {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrapf(err, "failed to read %s", path)
+	}
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(dat))
+	for scanner.Scan() {
+		l := scanner.Text()
+		if fields := strings.Fields(l); len(fields) > 0 && fields[0] == alias {
+			continue
+		}
+		buf.WriteString(l + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(err, "failed to read %s", path)
+	}
+	if buf.Len() == len(dat) {
+		return nil
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return errors.Wrapf(err, "failed to write %s", path)
+	}
+	logrus.Debugf("unpinned the host key of %s in %s", alias, path)
+	return nil
+}
+
+func lookupHostKey(path, alias string) (ssh.PublicKey, error) {
+	rest, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	for len(rest) > 0 {
+		var hosts []string
+		var key ssh.PublicKey
+		_, hosts, key, _, rest, err = ssh.ParseKnownHosts(rest)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, nil
+			}
+			return nil, errors.Wrapf(err, "failed to parse %s", path)
+		}
+		for _, h := range hosts {
+			if h == alias {
+				return key, nil
+			}
+		}
+	}
+	return nil, nil
+}
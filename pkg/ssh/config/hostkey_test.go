@@ -0,0 +1,115 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+)
+
+var _ = Describe("host key", func() {
+	var dir string
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "envd-hostkey")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+	})
+
+	newKey := func(name string) ssh.PublicKey {
+		public := filepath.Join(dir, name+".pub")
+		private := filepath.Join(dir, name)
+		Expect(generateHostKey(public, private)).To(Succeed())
+		pemBytes, err := os.ReadFile(private)
+		Expect(err).NotTo(HaveOccurred())
+		signer, err := ssh.ParsePrivateKey(pemBytes)
+		Expect(err).NotTo(HaveOccurred())
+		return signer.PublicKey()
+	}
+
+	It("Should pin the host key for the alias", func() {
+		knownHosts := filepath.Join(dir, "known_hosts")
+		key, err := lookupHostKey(knownHosts, "mnist.envd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(BeNil())
+
+		mnist := newKey("mnist")
+		other := newKey("other")
+		Expect(pinHostKey(knownHosts, "mnist.envd", mnist)).To(Succeed())
+		Expect(pinHostKey(knownHosts, "other.envd", other)).To(Succeed())
+		Expect(pinHostKey(knownHosts, "mnist.envd", mnist)).To(Succeed())
+
+		key, err = lookupHostKey(knownHosts, "mnist.envd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Marshal()).To(Equal(mnist.Marshal()))
+		key, err = lookupHostKey(knownHosts, "other.envd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Marshal()).To(Equal(other.Marshal()))
+
+		By("replacing the pinned host key")
+		renewed := newKey("renewed")
+		Expect(pinHostKey(knownHosts, "mnist.envd", renewed)).To(Succeed())
+		key, err = lookupHostKey(knownHosts, "mnist.envd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Marshal()).To(Equal(renewed.Marshal()))
+
+		dat, err := os.ReadFile(knownHosts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Count(string(dat), "\n")).To(Equal(2))
+	})
+
+	It("Should unpin the host key of the alias", func() {
+		knownHosts := filepath.Join(dir, "known_hosts")
+		Expect(unpinHostKey(knownHosts, "mnist.envd")).To(Succeed())
+
+		Expect(pinHostKey(knownHosts, "mnist.envd", newKey("mnist"))).To(Succeed())
+		other := newKey("other")
+		Expect(pinHostKey(knownHosts, "other.envd", other)).To(Succeed())
+		Expect(unpinHostKey(knownHosts, "mnist.envd")).To(Succeed())
+
+		key, err := lookupHostKey(knownHosts, "mnist.envd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key).To(BeNil())
+		key, err = lookupHostKey(knownHosts, "other.envd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(key.Marshal()).To(Equal(other.Marshal()))
+	})
+
+	It("Should reference the known_hosts in the entry", func() {
+		path := filepath.Join(dir, "config")
+		eo := EntryOptions{
+			Name:               BuildHostname("mnist"),
+			IFace:              "localhost",
+			Port:               8888,
+			PrivateKeyPath:     "key",
+			KnownHostsPath:     filepath.Join(dir, "known_hosts"),
+			EnableHostKeyCheck: true,
+		}
+		Expect(add(path, eo)).To(Succeed())
+
+		cfg, err := getConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		h := cfg.getHost(BuildHostname("mnist"))
+		Expect(h).NotTo(BeNil())
+		Expect(h.getParam(strictHostKeyCheckingKeyword).value()).To(Equal("yes"))
+		Expect(h.getParam(hostKeyAliasKeyword).value()).To(Equal("mnist.envd"))
+		Expect(h.getParam(userKnownHostsFileKeyword).value()).To(ContainSubstring("known_hosts"))
+	})
+})
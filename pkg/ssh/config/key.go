@@ -16,11 +16,15 @@
 package config
 
 import (
+	"crypto"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/cockroachdb/errors"
 	"github.com/sirupsen/logrus"
@@ -30,10 +34,29 @@ import (
 	"github.com/tensorchord/envd/pkg/util/fileutil"
 )
 
+// KeyType is the type of the SSH key pair generated by envd.
+type KeyType string
+
 const (
+	KeyTypeED25519 KeyType = "ed25519"
+	KeyTypeRSA     KeyType = "rsa"
+
+	// DefaultKeyType is the type of the key pairs generated by envd.
+	DefaultKeyType = KeyTypeED25519
+
 	bitSize = 4096
 )
 
+// KeyPair is the SSH key pair managed by envd.
+type KeyPair struct {
+	// Environment is empty for the default key pair.
+	Environment    string
+	Type           string
+	Fingerprint    string
+	PublicKeyPath  string
+	PrivateKeyPath string
+}
+
 // KeyExists returns true if the okteto key pair exists
 func KeyExists(public, private string) bool {
 	// public, private := getKeyPaths()
@@ -61,26 +84,46 @@ func GenerateKeys() error {
 	if err != nil {
 		return err
 	}
-	return generateKeys(publicKeyPath, privateKeyPath, bitSize)
-}
-
-func generateKeys(public, private string, bitSize int) error {
-	if KeyExists(public, private) {
+	if KeyExists(publicKeyPath, privateKeyPath) {
 		return nil
 	}
+	return GenerateKeyPair(publicKeyPath, privateKeyPath, DefaultKeyType)
+}
 
-	privateKey, err := generatePrivateKey(bitSize)
-	if err != nil {
-		return errors.Wrap(err, "failed to generate private SSH key")
-	}
-
-	publicKeyBytes, err := generatePublicKey(&privateKey.PublicKey)
-	if err != nil {
-		return errors.Wrap(err, "failed to generate public SSH key")
+// GenerateKeyPair generates a SSH key pair of the type, the existing files
+// are overwritten.
+func GenerateKeyPair(public, private string, keyType KeyType) error {
+	var privateKeyBytes, publicKeyBytes []byte
+	switch keyType {
+	case KeyTypeED25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate private SSH key")
+		}
+		block, err := ssh.MarshalPrivateKey(priv, "")
+		if err != nil {
+			return errors.Wrap(err, "failed to encode private SSH key")
+		}
+		privateKeyBytes = pem.EncodeToMemory(block)
+		publicKeyBytes, err = generatePublicKey(pub)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate public SSH key")
+		}
+	case KeyTypeRSA:
+		privateKey, err := generatePrivateKey(bitSize)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate private SSH key")
+		}
+		privateKeyBytes = encodePrivateKeyToPEM(privateKey)
+		publicKeyBytes, err = generatePublicKey(&privateKey.PublicKey)
+		if err != nil {
+			return errors.Wrap(err, "failed to generate public SSH key")
+		}
+	default:
+		return errors.Newf("unsupported key type %q, expected %s or %s",
+			keyType, KeyTypeED25519, KeyTypeRSA)
 	}
 
-	privateKeyBytes := encodePrivateKeyToPEM(privateKey)
-
 	if err := os.WriteFile(public, publicKeyBytes, 0600); err != nil {
 		return errors.Wrap(err, "failed to write public SSH key")
 	}
@@ -89,7 +132,7 @@ func generateKeys(public, private string, bitSize int) error {
 		return errors.Wrap(err, "failed to write private SSH key")
 	}
 
-	logrus.Debugf("created ssh keypair at  %s and %s", public, private)
+	logrus.Debugf("created %s ssh keypair at %s and %s", keyType, public, private)
 	return nil
 }
 
@@ -123,24 +166,129 @@ func encodePrivateKeyToPEM(privateKey *rsa.PrivateKey) []byte {
 	return privatePEM
 }
 
-func generatePublicKey(privatekey *rsa.PublicKey) ([]byte, error) {
-	publicRsaKey, err := ssh.NewPublicKey(privatekey)
+func generatePublicKey(publicKey crypto.PublicKey) ([]byte, error) {
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
 	if err != nil {
 		return nil, err
 	}
 
-	pubKeyBytes := ssh.MarshalAuthorizedKey(publicRsaKey)
+	pubKeyBytes := ssh.MarshalAuthorizedKey(sshPublicKey)
 
 	return pubKeyBytes, nil
 }
 
+// ReadKeyPair reads the type and the fingerprint of the key pair.
+func ReadKeyPair(env, public, private string) (KeyPair, error) {
+	dat, err := os.ReadFile(public)
+	if err != nil {
+		return KeyPair{}, errors.Wrapf(err, "failed to read public key %s", public)
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey(dat)
+	if err != nil {
+		return KeyPair{}, errors.Wrapf(err, "failed to parse public key %s", public)
+	}
+	return KeyPair{
+		Environment:    env,
+		Type:           key.Type(),
+		Fingerprint:    ssh.FingerprintSHA256(key),
+		PublicKeyPath:  public,
+		PrivateKeyPath: private,
+	}, nil
+}
+
+// ListKeyPairs returns the default key pair and the per-environment ones.
+func ListKeyPairs() ([]KeyPair, error) {
+	public, private, err := getDefaultKeyPaths()
+	if err != nil {
+		return nil, err
+	}
+	pair, err := ReadKeyPair("", public, private)
+	if err != nil {
+		return nil, err
+	}
+	pairs := []KeyPair{pair}
+
+	entries, err := os.ReadDir(filepath.Join(fileutil.DefaultConfigDir, config.EnvKeysDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pairs, nil
+		}
+		return nil, errors.Wrap(err, "failed to list the per-environment keys")
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, ".pub") {
+			continue
+		}
+		public, private, err := GetEnvKeyPaths(name)
+		if err != nil {
+			return nil, err
+		}
+		if !KeyExists(public, private) {
+			continue
+		}
+		pair, err := ReadKeyPair(name, public, private)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// GetEnvKeyPaths returns the paths to the per-environment key pair, which
+// may not exist.
+func GetEnvKeyPaths(name string) (string, string, error) {
+	if name == "" || strings.ContainsRune(name, os.PathSeparator) {
+		return "", "", errors.Newf("invalid environment name %q", name)
+	}
+	dir := filepath.Join(fileutil.DefaultConfigDir, config.EnvKeysDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", errors.Wrapf(err, "failed to create %s", dir)
+	}
+	private := filepath.Join(dir, name)
+	return private + ".pub", private, nil
+}
+
+// GetEnvKeyPair returns the per-environment key pair of the environment if
+// it exists, or the default key pair.
+func GetEnvKeyPair(name string) (string, string, error) {
+	public, private, err := GetEnvKeyPaths(name)
+	if err != nil {
+		return "", "", err
+	}
+	if KeyExists(public, private) {
+		return public, private, nil
+	}
+	return getDefaultKeyPaths()
+}
+
+// getDefaultKeyPaths returns the paths to the default key pair. The RSA key
+// pair generated by the old envd is used if it exists.
 func getDefaultKeyPaths() (string, string, error) {
-	public, err := fileutil.ConfigFile(config.PublicKeyFile)
+	public, private, err := GetDefaultKeyPathsForType(KeyTypeRSA)
+	if err != nil {
+		return "", "", err
+	}
+	if KeyExists(public, private) {
+		return public, private, nil
+	}
+	return GetDefaultKeyPathsForType(KeyTypeED25519)
+}
+
+// GetDefaultKeyPathsForType returns the paths to the default key pair of
+// the key type, which may not exist.
+func GetDefaultKeyPathsForType(keyType KeyType) (string, string, error) {
+	publicFile, privateFile := config.ED25519PublicKeyFile, config.ED25519PrivateKeyFile
+	if keyType == KeyTypeRSA {
+		publicFile, privateFile = config.PublicKeyFile, config.PrivateKeyFile
+	}
+	public, err := fileutil.ConfigFile(publicFile)
 	if err != nil {
 		return "", "", errors.Wrap(err, "Cannot get public key path")
 	}
 
-	private, err := fileutil.ConfigFile(config.PrivateKeyFile)
+	private, err := fileutil.ConfigFile(privateFile)
 	if err != nil {
 		return "", "", errors.Wrap(err, "Cannot get private key path")
 	}
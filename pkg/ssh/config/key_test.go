@@ -0,0 +1,100 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/tensorchord/envd/pkg/util/fileutil"
+)
+
+var _ = Describe("ssh keys", func() {
+	var dir string
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "envd-key")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+	})
+
+	DescribeTable("Should generate the key pair",
+		func(keyType KeyType, expected string) {
+			public := filepath.Join(dir, "key.pub")
+			private := filepath.Join(dir, "key")
+			Expect(GenerateKeyPair(public, private, keyType)).To(Succeed())
+
+			pair, err := ReadKeyPair("mnist", public, private)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pair.Type).To(Equal(expected))
+
+			pemBytes, err := os.ReadFile(private)
+			Expect(err).NotTo(HaveOccurred())
+			signer, err := ssh.ParsePrivateKey(pemBytes)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ssh.FingerprintSHA256(signer.PublicKey())).To(Equal(pair.Fingerprint))
+		},
+		Entry("ed25519", KeyTypeED25519, ssh.KeyAlgoED25519),
+		Entry("rsa", KeyTypeRSA, ssh.KeyAlgoRSA),
+	)
+
+	It("Should reject the unknown key type", func() {
+		err := GenerateKeyPair(filepath.Join(dir, "key.pub"), filepath.Join(dir, "key"), "dsa")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should prefer the legacy RSA key pair as the default key pair", func() {
+		configDir := fileutil.DefaultConfigDir
+		fileutil.DefaultConfigDir = dir
+		DeferCleanup(func() { fileutil.DefaultConfigDir = configDir })
+
+		rsaPublic, rsaPrivate, err := GetDefaultKeyPathsForType(KeyTypeRSA)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rsaPrivate).To(Equal(filepath.Join(dir, "id_rsa_envd")))
+		public, private, err := GetDefaultKeyPathsForType(KeyTypeED25519)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(public).To(Equal(filepath.Join(dir, "id_ed25519_envd.pub")))
+		Expect(private).To(Equal(filepath.Join(dir, "id_ed25519_envd")))
+
+		Expect(GenerateKeyPair(rsaPublic, rsaPrivate, KeyTypeRSA)).To(Succeed())
+		Expect(GetPrivateKey()).To(Equal(rsaPrivate))
+
+		Expect(os.Remove(rsaPublic)).To(Succeed())
+		Expect(os.Remove(rsaPrivate)).To(Succeed())
+		Expect(GetPublicKey()).To(Equal(public))
+		Expect(GetPrivateKey()).To(Equal(private))
+	})
+
+	It("Should change the identity file of the entry", func() {
+		path := filepath.Join(dir, "config")
+		eo := EntryOptions{
+			Name:           BuildHostname("mnist"),
+			IFace:          "localhost",
+			Port:           8888,
+			PrivateKeyPath: "old",
+		}
+		Expect(add(path, eo)).To(Succeed())
+		Expect(setIdentityFile(path, BuildHostname("mnist"), "/path/to/new")).To(Succeed())
+
+		cfg, err := getConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		h := cfg.getHost(BuildHostname("mnist"))
+		Expect(h.getParam(identityFile).args).To(Equal([]string{`"/path/to/new"`}))
+	})
+})
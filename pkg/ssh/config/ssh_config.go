@@ -60,6 +60,7 @@ const (
 	strictHostKeyCheckingKeyword  = "StrictHostKeyChecking"
 	hostKeyAlgorithms             = "HostKeyAlgorithms"
 	userKnownHostsFileKeyword     = "UserKnownHostsFile"
+	hostKeyAliasKeyword           = "HostKeyAlias"
 	identityFile                  = "IdentityFile"
 	userKeyword                   = "User"
 )
@@ -406,6 +407,83 @@ func GetPort(name string) (int, error) {
 	return port, nil
 }
 
+// GetEntry returns the entry of the dev env in the user's sshconfig.
+func GetEntry(name string) (EntryOptions, error) {
+	cfg, err := getConfig(getSSHConfigPath())
+	if err != nil {
+		return EntryOptions{}, err
+	}
+	i, found := findHost(cfg, BuildHostname(name))
+	if !found {
+		return EntryOptions{}, errors.Newf("cannot find the entry of %s in your SSH config file", name)
+	}
+
+	h := cfg.hosts[i]
+	eo := EntryOptions{Name: name}
+	if p := h.getParam(hostNameKeyword); p != nil {
+		eo.IFace = p.value()
+	}
+	if p := h.getParam(portKeyword); p != nil {
+		eo.Port, err = strconv.Atoi(p.value())
+		if err != nil {
+			return EntryOptions{}, errors.Newf("invalid port: %s", p.value())
+		}
+	}
+	if p := h.getParam(identityFile); p != nil {
+		eo.PrivateKeyPath = strings.Trim(strings.Join(p.args, " "), "\"")
+	}
+	if p := h.getParam(userKnownHostsFileKeyword); p != nil {
+		eo.KnownHostsPath = strings.Trim(strings.Join(p.args, " "), "\"")
+	}
+	if p := h.getParam(strictHostKeyCheckingKeyword); p != nil {
+		eo.EnableHostKeyCheck = p.value() == "yes"
+	}
+	if p := h.getParam(forwardAgentKeyword); p != nil {
+		eo.EnableAgentForward = p.value() == "yes"
+	}
+	if p := h.getParam(userKeyword); p != nil {
+		eo.User = p.value()
+	}
+	return eo, nil
+}
+
+// SetIdentityFile changes the private key of the dev env entry.
+func SetIdentityFile(name, privateKeyPath string) error {
+	if err := setIdentityFile(getSSHConfigPath(), BuildHostname(name), privateKeyPath); err != nil {
+		return err
+	}
+	if osutil.IsWsl() {
+		winSshConfig, err := osutil.GetWslHostSshConfig()
+		if err != nil {
+			return err
+		}
+		winKeyPath, err := osutil.CopyToWinEnvdHome(privateKeyPath, 0600)
+		if err != nil {
+			return err
+		}
+		return setIdentityFile(winSshConfig, BuildHostname(name), winKeyPath)
+	}
+	return nil
+}
+
+func setIdentityFile(path, name, privateKeyPath string) error {
+	cfg, err := getConfig(path)
+	if err != nil {
+		return err
+	}
+	i, found := findHost(cfg, name)
+	if !found {
+		return nil
+	}
+	value := []string{"\"" + privateKeyPath + "\""}
+	if p := cfg.hosts[i].getParam(identityFile); p != nil {
+		p.args = value
+	} else {
+		cfg.hosts[i].params = append(cfg.hosts[i].params, newParam(identityFile, value, nil))
+	}
+	return save(cfg, path)
+}
+
 func remove(path, name string) error {
 	cfg, err := getConfig(path)
 	if err != nil {
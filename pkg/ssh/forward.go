@@ -0,0 +1,269 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+const (
+	defaultKeepAliveInterval = 15 * time.Second
+	minReconnectBackoff      = time.Second
+	maxReconnectBackoff      = 30 * time.Second
+)
+
+// PortForwarder runs the port forwards over the SSH connection to the
+// environment. The local listeners are kept when the connection is dropped,
+// and the remote listeners are recreated after reconnecting.
+type PortForwarder struct {
+	Forwards []types.PortForward
+	// KeepAliveInterval is the interval of the keepalive requests to
+	// detect the dropped connection.
+	KeepAliveInterval time.Duration
+	// Ready is called after the first connection is established.
+	Ready func()
+
+	dial func() (*ssh.Client, error)
+	mu   sync.RWMutex
+	cli  *ssh.Client
+}
+
+func NewPortForwarder(opt Options, forwards []types.PortForward) *PortForwarder {
+	return &PortForwarder{
+		Forwards:          forwards,
+		KeepAliveInterval: defaultKeepAliveInterval,
+		dial: func() (*ssh.Client, error) {
+			c, err := newClient(opt)
+			if err != nil {
+				return nil, err
+			}
+			return c.cli, nil
+		},
+	}
+}
+
+// Run forwards the ports until the context is canceled. It returns the
+// error if the forwards cannot be set up on the first connection.
+func (f *PortForwarder) Run(ctx context.Context) error {
+	for _, fwd := range f.Forwards {
+		if fwd.Remote {
+			continue
+		}
+		l, err := net.Listen("tcp", fwd.BindAddr())
+		if err != nil {
+			return errors.Wrapf(err, "failed to listen on %s", fwd.BindAddr())
+		}
+		defer l.Close()
+		go f.serveLocal(l, fwd)
+	}
+
+	first := true
+	backoff := minReconnectBackoff
+	for {
+		cli, listeners, err := f.connect()
+		if err != nil {
+			if first {
+				return err
+			}
+			logrus.WithError(err).Warnf("failed to reconnect to the environment, retry in %s", backoff)
+		} else {
+			if first {
+				first = false
+				if f.Ready != nil {
+					f.Ready()
+				}
+			} else {
+				logrus.Info("reconnected to the environment")
+			}
+			backoff = minReconnectBackoff
+			err = f.wait(ctx, cli)
+			f.setClient(nil)
+			for _, l := range listeners {
+				l.Close()
+			}
+			cli.Close()
+			if ctx.Err() != nil {
+				return nil
+			}
+			logrus.WithError(err).Warn("the connection to the environment is lost, reconnecting")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
+// connect dials the environment and listens on the remote addresses.
+func (f *PortForwarder) connect() (*ssh.Client, []net.Listener, error) {
+	cli, err := f.dial()
+	if err != nil {
+		return nil, nil, err
+	}
+	var listeners []net.Listener
+	for _, fwd := range f.Forwards {
+		if !fwd.Remote {
+			continue
+		}
+		l, err := cli.Listen("tcp", fwd.BindAddr())
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			cli.Close()
+			return nil, nil, errors.Wrapf(err, "failed to listen on %s in the environment", fwd.BindAddr())
+		}
+		listeners = append(listeners, l)
+		go f.serveRemote(l, fwd)
+	}
+	f.setClient(cli)
+	return cli, listeners, nil
+}
+
+// wait blocks until the connection is dropped or the context is canceled.
+func (f *PortForwarder) wait(ctx context.Context, cli *ssh.Client) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- cli.Wait()
+	}()
+
+	interval := f.KeepAliveInterval
+	if interval <= 0 {
+		interval = defaultKeepAliveInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-done:
+			if err == nil {
+				err = io.EOF
+			}
+			return err
+		case <-ticker.C:
+			if err := keepAlive(cli, interval); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// keepAlive sends the keepalive request, the half-open connection is
+// detected by the timeout.
+func keepAlive(cli *ssh.Client, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := cli.SendRequest("keepalive@openssh.com", true, nil)
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		return errors.Wrap(err, "keepalive failed")
+	case <-time.After(timeout):
+		return errors.New("keepalive timed out")
+	}
+}
+
+func (f *PortForwarder) client() *ssh.Client {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.cli
+}
+
+func (f *PortForwarder) setClient(cli *ssh.Client) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cli = cli
+}
+
+func (f *PortForwarder) serveLocal(l net.Listener, fwd types.PortForward) {
+	logger := logrus.WithField("forward", fwd.String())
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			logger.WithError(err).Debug("stop accepting the local connections")
+			return
+		}
+		go func() {
+			cli := f.client()
+			if cli == nil {
+				logger.Warn("the connection to the environment is lost, drop the local connection")
+				conn.Close()
+				return
+			}
+			target, err := cli.Dial("tcp", fwd.TargetAddr())
+			if err != nil {
+				logger.WithError(err).Warnf("failed to connect to %s in the environment", fwd.TargetAddr())
+				conn.Close()
+				return
+			}
+			pipe(conn, target)
+		}()
+	}
+}
+
+func (f *PortForwarder) serveRemote(l net.Listener, fwd types.PortForward) {
+	logger := logrus.WithField("forward", fwd.String())
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			logger.WithError(err).Debug("stop accepting the remote connections")
+			return
+		}
+		go func() {
+			target, err := net.Dial("tcp", fwd.TargetAddr())
+			if err != nil {
+				logger.WithError(err).Warnf("failed to connect to %s", fwd.TargetAddr())
+				conn.Close()
+				return
+			}
+			pipe(conn, target)
+		}()
+	}
+}
+
+// pipe copies the data in both directions until either side is closed.
+func pipe(a, b net.Conn) {
+	defer a.Close()
+	defer b.Close()
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
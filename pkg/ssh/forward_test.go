@@ -0,0 +1,170 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	gliderssh "github.com/gliderlabs/ssh"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/tensorchord/envd/pkg/types"
+)
+
+// startForwardServer starts the ssh server allowing all the forwards and
+// returns its address.
+func startForwardServer(t *testing.T) string {
+	t.Helper()
+	forwardHandler := &gliderssh.ForwardedTCPHandler{}
+	server := &gliderssh.Server{
+		LocalPortForwardingCallback: func(gliderssh.Context, string, uint32) bool {
+			return true
+		},
+		ReversePortForwardingCallback: func(gliderssh.Context, string, uint32) bool {
+			return true
+		},
+		ChannelHandlers: map[string]gliderssh.ChannelHandler{
+			"session":      gliderssh.DefaultSessionHandler,
+			"direct-tcpip": gliderssh.DirectTCPIPHandler,
+		},
+		RequestHandlers: map[string]gliderssh.RequestHandler{
+			"tcpip-forward":        forwardHandler.HandleSSHRequest,
+			"cancel-tcpip-forward": forwardHandler.HandleSSHRequest,
+		},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(l) //nolint:errcheck
+	t.Cleanup(func() { server.Close() })
+	return l.Addr().String()
+}
+
+func startEcho(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn) //nolint:errcheck
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func runForwarder(t *testing.T, f *PortForwarder, serverAddr string) {
+	t.Helper()
+	f.dial = func() (*ssh.Client, error) {
+		return ssh.Dial("tcp", serverAddr, &ssh.ClientConfig{
+			User:            "envd",
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
+			Timeout:         5 * time.Second,
+		})
+	}
+	ready := make(chan struct{})
+	f.Ready = func() { close(ready) }
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- f.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("the forwarder exited: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the forwarder is not ready")
+	}
+}
+
+func requireEcho(t *testing.T, addr string) {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+}
+
+func TestPortForwarderLocal(t *testing.T) {
+	server := startForwardServer(t)
+	fwd := types.PortForward{
+		BindAddress: "127.0.0.1",
+		BindPort:    freePort(t),
+		TargetHost:  "127.0.0.1",
+		TargetPort:  startEcho(t),
+	}
+	f := &PortForwarder{Forwards: []types.PortForward{fwd}}
+	runForwarder(t, f, server)
+	requireEcho(t, fwd.BindAddr())
+
+	// Drop the connection, the forward works again after reconnecting.
+	old := f.client()
+	require.NoError(t, old.Close())
+	require.Eventually(t, func() bool {
+		cli := f.client()
+		return cli != nil && cli != old
+	}, 10*time.Second, 50*time.Millisecond)
+	requireEcho(t, fwd.BindAddr())
+}
+
+func TestPortForwarderRemote(t *testing.T) {
+	server := startForwardServer(t)
+	fwd := types.PortForward{
+		Remote:      true,
+		BindAddress: "127.0.0.1",
+		BindPort:    freePort(t),
+		TargetHost:  "127.0.0.1",
+		TargetPort:  startEcho(t),
+	}
+	runForwarder(t, &PortForwarder{Forwards: []types.PortForward{fwd}}, server)
+	requireEcho(t, fwd.BindAddr())
+}
+
+func TestPortForwarderFirstConnection(t *testing.T) {
+	f := &PortForwarder{
+		dial: func() (*ssh.Client, error) {
+			return nil, io.ErrUnexpectedEOF
+		},
+	}
+	require.ErrorIs(t, f.Run(context.Background()), io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/crypto/ssh"
+
+	envdconfig "github.com/tensorchord/envd/pkg/config"
+)
+
+// AuthorizedKey returns the line of the public key in the authorized_keys
+// of the environment.
+func AuthorizedKey(publicKeyPath string) (string, error) {
+	dat, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read public key %s", publicKeyPath)
+	}
+	// Validate the key since the line is written by the shell.
+	if _, _, _, _, err := ssh.ParseAuthorizedKey(dat); err != nil {
+		return "", errors.Wrapf(err, "failed to parse public key %s", publicKeyPath)
+	}
+	line := strings.TrimSpace(strings.SplitN(string(dat), "\n", 2)[0])
+	if strings.ContainsAny(line, "'\\") {
+		return "", errors.Newf("unexpected characters in public key %s", publicKeyPath)
+	}
+	return line + " envd", nil
+}
+
+// SetAuthorizedKeys replaces the authorized_keys in the running environment
+// over the SSH connection. The sshd reads the file on every authentication,
+// thus the keys take effect without restarting the environment.
+func SetAuthorizedKeys(opt Options, keys []string) error {
+	if len(keys) == 0 {
+		return errors.New("at least one authorized key is required")
+	}
+	cli, err := NewClient(opt)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to the environment")
+	}
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		quoted = append(quoted, "'"+key+"'")
+	}
+	tmp := envdconfig.ContainerAuthorizedKeysPath + ".tmp"
+	cmd := fmt.Sprintf("printf '%%s\\n' %s > %s && mv %s %s",
+		strings.Join(quoted, " "), tmp, tmp, envdconfig.ContainerAuthorizedKeysPath)
+	if out, err := cli.ExecWithOutput(cmd); err != nil {
+		return errors.Wrapf(err, "failed to update the authorized keys: %s", string(out))
+	}
+	return nil
+}
@@ -20,6 +20,7 @@
 package ssh
 
 import (
+	"bytes"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -28,6 +29,7 @@ import (
 	"os"
 
 	"github.com/cockroachdb/errors"
+	"github.com/pkg/sftp"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
@@ -41,6 +43,8 @@ type Client interface {
 	ExecWithOutput(cmd string) ([]byte, error)
 	LocalForward(localAddress, targetAddress string) error
 	RemoteForward(localAddress, targetAddress string) error
+	// SFTP opens the SFTP subsystem of the connection.
+	SFTP() (*sftp.Client, error)
 	Close() error
 }
 
@@ -52,6 +56,9 @@ type Options struct {
 	Auth            bool
 	PrivateKeyPath  string
 	PrivateKeyPwd   string
+	// HostKeyAlias is the alias (`<env>.envd`) of the pinned host key in the
+	// envd known_hosts file. The host key is not verified if it is empty.
+	HostKeyAlias string
 }
 
 func DefaultOptions() Options {
@@ -63,19 +70,28 @@ func DefaultOptions() Options {
 	}
 }
 
+// GetOptions returns the options to connect to the entry in the user's
+// sshconfig.
 func GetOptions(entry string) (*Options, error) {
-	path, err := config.GetPrivateKey()
+	eo, err := config.GetEntry(entry)
 	if err != nil {
-		return nil, errors.Wrap(err, "getting private key failed")
-	}
-	port, err := config.GetPort(entry)
-	if err != nil {
-		return nil, errors.Wrap(err, "getting port failed")
+		return nil, errors.Wrap(err, "getting the ssh config entry failed")
 	}
 	// TODO(gaocegege): Make it configurable.
 	opt := DefaultOptions()
-	opt.Port = port
-	opt.PrivateKeyPath = path
+	opt.Port = eo.Port
+	opt.Server = eo.IFace
+	opt.PrivateKeyPath = eo.PrivateKeyPath
+	if opt.PrivateKeyPath == "" {
+		opt.PrivateKeyPath, err = config.GetPrivateKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "getting private key failed")
+		}
+	}
+	if eo.User != "" {
+		opt.User = eo.User
+	}
+	opt.HostKeyAlias = config.BuildHostname(entry)
 	return &opt, nil
 }
 
@@ -85,6 +101,10 @@ type generalClient struct {
 }
 
 func NewClient(opt Options) (Client, error) {
+	return newClient(opt)
+}
+
+func newClient(opt Options) (*generalClient, error) {
 	logger := logrus.WithFields(logrus.Fields{
 		"user":             opt.User,
 		"port":             opt.Port,
@@ -94,12 +114,13 @@ func NewClient(opt Options) (Client, error) {
 	})
 	logger.Debug("ssh to the environment")
 
+	hostKeyCallback, err := newHostKeyCallback(opt.HostKeyAlias)
+	if err != nil {
+		return nil, err
+	}
 	config := &ssh.ClientConfig{
-		User: opt.User,
-		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-			// use OpenSSH's known_hosts file if you care about host validation
-			return nil
-		},
+		User:            opt.User,
+		HostKeyCallback: hostKeyCallback,
 	}
 
 	var cli *ssh.Client
@@ -157,10 +178,46 @@ func NewClient(opt Options) (Client, error) {
 	}, nil
 }
 
+// newHostKeyCallback verifies the host key against the one pinned for the
+// alias. The environments started by the old envd have no pinned host key,
+// thus the check is skipped for them, otherwise a mismatched key fails.
+func newHostKeyCallback(alias string) (ssh.HostKeyCallback, error) {
+	if alias == "" {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	pinned, err := config.GetPinnedHostKey(alias)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the pinned host key")
+	}
+	if pinned == nil {
+		logrus.Debugf("the host key of %s is not pinned, skip the host key check", alias)
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if bytes.Equal(key.Marshal(), pinned.Marshal()) {
+			return nil
+		}
+		return errors.Newf("host key verification failed for %s (%s): "+
+			"got %s key %s, but %s is pinned. Someone could be eavesdropping "+
+			"on the connection, or the environment is built with another host key, "+
+			"rebuild it with `envd up --force` if it is expected",
+			alias, hostname, key.Type(), ssh.FingerprintSHA256(key),
+			ssh.FingerprintSHA256(pinned))
+	}, nil
+}
+
 func (c generalClient) Close() error {
 	return c.cli.Close()
 }
 
+func (c generalClient) SFTP() (*sftp.Client, error) {
+	cli, err := sftp.NewClient(c.cli)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the sftp subsystem")
+	}
+	return cli, nil
+}
+
 func (c generalClient) ExecWithOutput(cmd string) ([]byte, error) {
 	defer c.cli.Close()
 
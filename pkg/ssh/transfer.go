@@ -0,0 +1,243 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/cockroachdb/errors"
+	"github.com/pkg/sftp"
+	"github.com/schollz/progressbar/v3"
+	"github.com/sirupsen/logrus"
+)
+
+// File is the file opened in the FileSystem.
+type File interface {
+	io.ReadWriteSeeker
+	io.Closer
+}
+
+// FileSystem is the local or the remote file system to copy the files.
+type FileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+	Glob(pattern string) ([]string, error)
+	Open(name string) (File, error)
+	OpenFile(name string, flag int) (File, error)
+	MkdirAll(name string) error
+	Chmod(name string, mode os.FileMode) error
+	Join(elem ...string) string
+	Base(name string) string
+}
+
+// LocalFileSystem is the file system of the host.
+type LocalFileSystem struct{}
+
+func (LocalFileSystem) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
+func (LocalFileSystem) ReadDir(name string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (LocalFileSystem) Glob(pattern string) ([]string, error) { return filepath.Glob(pattern) }
+
+func (LocalFileSystem) Open(name string) (File, error) { return os.Open(name) }
+
+func (LocalFileSystem) OpenFile(name string, flag int) (File, error) {
+	return os.OpenFile(name, flag, 0644)
+}
+
+func (LocalFileSystem) MkdirAll(name string) error { return os.MkdirAll(name, 0755) }
+
+func (LocalFileSystem) Chmod(name string, mode os.FileMode) error { return os.Chmod(name, mode) }
+
+func (LocalFileSystem) Join(elem ...string) string { return filepath.Join(elem...) }
+
+func (LocalFileSystem) Base(name string) string { return filepath.Base(name) }
+
+// RemoteFileSystem is the file system of the environment over SFTP.
+type RemoteFileSystem struct {
+	*sftp.Client
+}
+
+func (fs RemoteFileSystem) Open(name string) (File, error) { return fs.Client.Open(name) }
+
+func (fs RemoteFileSystem) OpenFile(name string, flag int) (File, error) {
+	return fs.Client.OpenFile(name, flag)
+}
+
+func (RemoteFileSystem) Base(name string) string { return path.Base(name) }
+
+// TransferOptions configures the transfer.
+type TransferOptions struct {
+	// Resume continues the partially copied files instead of overwriting
+	// them. The files with the same size are skipped.
+	Resume bool
+	// Quiet disables the progress bars.
+	Quiet bool
+}
+
+// Transfer copies the files matched by the pattern in src to dst. The
+// directories are copied recursively. The files are copied into dst if it
+// is an existing directory or the pattern matches multiple files.
+func Transfer(src FileSystem, pattern string, dst FileSystem, dstPath string, opt TransferOptions) error {
+	matches, err := src.Glob(pattern)
+	if err != nil {
+		return errors.Wrapf(err, "invalid pattern %s", pattern)
+	}
+	if len(matches) == 0 {
+		return errors.Newf("no such file or directory: %s", pattern)
+	}
+
+	intoDir := false
+	info, err := dst.Stat(dstPath)
+	switch {
+	case err == nil:
+		intoDir = info.IsDir()
+		if !intoDir && len(matches) > 1 {
+			return errors.Newf("%s is not a directory, cannot copy %d files into it", dstPath, len(matches))
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if len(matches) > 1 {
+			if err := dst.MkdirAll(dstPath); err != nil {
+				return errors.Wrapf(err, "failed to create the directory %s", dstPath)
+			}
+			intoDir = true
+		}
+	default:
+		return errors.Wrapf(err, "failed to stat %s", dstPath)
+	}
+
+	t := transfer{src: src, dst: dst, opt: opt}
+	for _, m := range matches {
+		target := dstPath
+		if intoDir {
+			target = dst.Join(dstPath, src.Base(m))
+		}
+		if err := t.copy(m, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type transfer struct {
+	src FileSystem
+	dst FileSystem
+	opt TransferOptions
+}
+
+func (t transfer) copy(srcPath, dstPath string) error {
+	info, err := t.src.Stat(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to stat %s", srcPath)
+	}
+	if !info.IsDir() {
+		return t.copyFile(srcPath, dstPath, info)
+	}
+
+	if err := t.dst.MkdirAll(dstPath); err != nil {
+		return errors.Wrapf(err, "failed to create the directory %s", dstPath)
+	}
+	entries, err := t.src.ReadDir(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the directory %s", srcPath)
+	}
+	for _, e := range entries {
+		if err := t.copy(t.src.Join(srcPath, e.Name()), t.dst.Join(dstPath, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t transfer) copyFile(srcPath, dstPath string, info os.FileInfo) error {
+	logger := logrus.WithFields(logrus.Fields{
+		"src": srcPath,
+		"dst": dstPath,
+	})
+	var offset int64
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if t.opt.Resume {
+		if dstInfo, err := t.dst.Stat(dstPath); err == nil && !dstInfo.IsDir() {
+			switch {
+			case dstInfo.Size() == info.Size():
+				logger.Debug("skip the copied file")
+				return nil
+			case dstInfo.Size() < info.Size():
+				offset = dstInfo.Size()
+				flag = os.O_WRONLY
+			}
+		}
+	}
+
+	in, err := t.src.Open(srcPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open %s", srcPath)
+	}
+	defer in.Close()
+	out, err := t.dst.OpenFile(dstPath, flag)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create %s", dstPath)
+	}
+	defer out.Close()
+
+	if offset > 0 {
+		logger.Debugf("resume the copy from %d bytes", offset)
+		if _, err := in.Seek(offset, io.SeekStart); err != nil {
+			return errors.Wrapf(err, "failed to seek %s", srcPath)
+		}
+		if _, err := out.Seek(offset, io.SeekStart); err != nil {
+			return errors.Wrapf(err, "failed to seek %s", dstPath)
+		}
+	}
+
+	var bar *progressbar.ProgressBar
+	if t.opt.Quiet {
+		bar = progressbar.DefaultBytesSilent(info.Size(), srcPath)
+	} else {
+		bar = progressbar.DefaultBytes(info.Size(), srcPath)
+	}
+	if err := bar.Set64(offset); err != nil {
+		return err
+	}
+	reader := progressbar.NewReader(in, bar)
+	if _, err := io.Copy(out, &reader); err != nil {
+		return errors.Wrapf(err, "failed to copy %s to %s", srcPath, dstPath)
+	}
+	if err := bar.Finish(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return errors.Wrapf(err, "failed to close %s", dstPath)
+	}
+	return errors.Wrapf(t.dst.Chmod(dstPath, info.Mode().Perm()),
+		"failed to change the mode of %s", dstPath)
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ssh
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0640))
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	dat, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(dat)
+}
+
+// newRemoteFileSystem serves the SFTP subsystem in-process.
+func newRemoteFileSystem(t *testing.T) RemoteFileSystem {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{sr, sw})
+	require.NoError(t, err)
+	go server.Serve() //nolint:errcheck
+	client, err := sftp.NewClientPipe(cr, cw)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return RemoteFileSystem{Client: client}
+}
+
+func TestTransferDirectory(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"data/a.txt":     "a",
+		"data/sub/b.txt": "b",
+	})
+	remote := newRemoteFileSystem(t)
+	opt := TransferOptions{Quiet: true}
+
+	// Upload into the existing directory.
+	require.NoError(t, Transfer(LocalFileSystem{}, filepath.Join(src, "data"), remote, dst, opt))
+	require.Equal(t, "b", readFile(t, filepath.Join(dst, "data", "sub", "b.txt")))
+	info, err := os.Stat(filepath.Join(dst, "data", "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// Download as the new directory.
+	back := filepath.Join(t.TempDir(), "back")
+	require.NoError(t, Transfer(remote, filepath.Join(dst, "data"), LocalFileSystem{}, back, opt))
+	require.Equal(t, "a", readFile(t, filepath.Join(back, "a.txt")))
+	require.Equal(t, "b", readFile(t, filepath.Join(back, "sub", "b.txt")))
+}
+
+func TestTransferGlob(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"a.log": "a",
+		"b.log": "b",
+		"c.txt": "c",
+	})
+	target := filepath.Join(dst, "logs")
+	require.NoError(t, Transfer(LocalFileSystem{}, filepath.Join(src, "*.log"),
+		LocalFileSystem{}, target, TransferOptions{Quiet: true}))
+	entries, err := os.ReadDir(target)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	err = Transfer(LocalFileSystem{}, filepath.Join(src, "*.log"),
+		LocalFileSystem{}, filepath.Join(src, "c.txt"), TransferOptions{Quiet: true})
+	require.Error(t, err)
+	err = Transfer(LocalFileSystem{}, filepath.Join(src, "*.csv"),
+		LocalFileSystem{}, target, TransferOptions{Quiet: true})
+	require.Error(t, err)
+}
+
+func TestTransferResume(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	writeFiles(t, src, map[string]string{"model.bin": "0123456789"})
+	// The partial file is continued, the content is not compared.
+	writeFiles(t, dst, map[string]string{"model.bin": "01234"})
+	remote := newRemoteFileSystem(t)
+
+	require.NoError(t, Transfer(LocalFileSystem{}, filepath.Join(src, "model.bin"),
+		remote, filepath.Join(dst, "model.bin"), TransferOptions{Quiet: true, Resume: true}))
+	require.Equal(t, "0123456789", readFile(t, filepath.Join(dst, "model.bin")))
+
+	writeFiles(t, dst, map[string]string{"model.bin": "abcdefghij"})
+	require.NoError(t, Transfer(LocalFileSystem{}, filepath.Join(src, "model.bin"),
+		remote, filepath.Join(dst, "model.bin"), TransferOptions{Quiet: true, Resume: true}))
+	require.Equal(t, "abcdefghij", readFile(t, filepath.Join(dst, "model.bin")))
+
+	require.NoError(t, Transfer(LocalFileSystem{}, filepath.Join(src, "model.bin"),
+		remote, filepath.Join(dst, "model.bin"), TransferOptions{Quiet: true}))
+	require.Equal(t, "0123456789", readFile(t, filepath.Join(dst, "model.bin")))
+}
@@ -41,8 +41,13 @@ const (
 	HorustServiceDir = "/etc/horust/services"
 	HorustLogDir     = "/var/log/horust"
 	HorustSocketDir  = "/var/run/horust"
+	HorustScriptDir  = "/etc/horust/scripts"
 	// env
 	EnvdWorkDir = "ENVD_WORKDIR"
+	// EnvdWebTerminalToken is the token of the web terminal served by envd-sshd.
+	EnvdWebTerminalToken = "ENVD_WEB_TERMINAL_TOKEN"
+	// EnvdVSCodeServerToken is the connection token of the OpenVSCode Server.
+	EnvdVSCodeServerToken = "ENVD_VSCODE_SERVER_TOKEN"
 )
 
 var EnvdSshdImage = fmt.Sprintf(
@@ -141,8 +146,11 @@ const (
 )
 
 type Dependency struct {
-	APTPackages  []string `json:"apt_packages,omitempty"`
-	PyPIPackages []string `json:"pypi_packages,omitempty"`
+	APTPackages   []string `json:"apt_packages,omitempty"`
+	PyPIPackages  []string `json:"pypi_packages,omitempty"`
+	RPackages     []string `json:"r_packages,omitempty"`
+	JuliaPackages []string `json:"julia_packages,omitempty"`
+	NodePackages  []string `json:"node_packages,omitempty"`
 }
 
 type RepoInfo struct {
@@ -171,6 +179,9 @@ type EnvdAuth struct {
 type AuthConfig struct {
 	Name     string `json:"name,omitempty"`
 	JWTToken string `json:"jwt_token,omitempty"`
+	// CredentialHelper is the docker credential helper storing the token,
+	// e.g. osxkeychain. The token is stored in the encrypted file if empty.
+	CredentialHelper string `json:"credential_helper,omitempty"`
 }
 
 func NewImageFromSummary(image image.Summary) (*EnvdImage, error) {
@@ -320,9 +331,42 @@ func NewDependencyFromLabels(label map[string]string) (*Dependency, error) {
 		}
 		dep.PyPIPackages = pkgs
 	}
+	if groups, ok := label[ImageLabelR]; ok {
+		pkgs, err := parsePackageGroups(groups)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse r packages")
+		}
+		dep.RPackages = pkgs
+	}
+	if groups, ok := label[ImageLabelJulia]; ok {
+		pkgs, err := parsePackageGroups(groups)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse julia packages")
+		}
+		dep.JuliaPackages = pkgs
+	}
+	if groups, ok := label[ImageLabelNode]; ok {
+		pkgs, err := parsePackageGroups(groups)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse node packages")
+		}
+		dep.NodePackages = pkgs
+	}
 	return &dep, nil
 }
 
+func parsePackageGroups(lst string) ([]string, error) {
+	var groups [][]string
+	if err := json.Unmarshal([]byte(lst), &groups); err != nil {
+		return nil, err
+	}
+	var pkgs []string
+	for _, group := range groups {
+		pkgs = append(pkgs, group...)
+	}
+	return pkgs, nil
+}
+
 func parseAPTPackages(lst string) ([]string, error) {
 	var pkgs []string
 	err := json.Unmarshal([]byte(lst), &pkgs)
@@ -0,0 +1,42 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("dependency", func() {
+	g.It("Should flatten the R, Julia and Node.js package groups", func() {
+		dep, err := NewDependencyFromLabels(map[string]string{
+			ImageLabelAPT:   `["git"]`,
+			ImageLabelR:     `[["dplyr"],["renv==1.0.7","rlang==1.1.4"]]`,
+			ImageLabelJulia: `[["CSV==0.10.14"]]`,
+			ImageLabelNode:  `[["typescript"],["react@18.3.1"]]`,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.APTPackages).To(Equal([]string{"git"}))
+		Expect(dep.RPackages).To(Equal([]string{"dplyr", "renv==1.0.7", "rlang==1.1.4"}))
+		Expect(dep.JuliaPackages).To(Equal([]string{"CSV==0.10.14"}))
+		Expect(dep.NodePackages).To(Equal([]string{"typescript", "react@18.3.1"}))
+	})
+
+	g.It("Should accept the empty R packages", func() {
+		dep, err := NewDependencyFromLabels(map[string]string{ImageLabelR: "null"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.RPackages).To(BeEmpty())
+	})
+})
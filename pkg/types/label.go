@@ -19,6 +19,8 @@ const (
 	ContainerLabelJupyterAddr       = "ai.tensorchord.envd.jupyter.address"
 	ContainerLabelRStudioServerAddr = "ai.tensorchord.envd.rstudio.server.address"
 	ContainerLabelSSHPort           = "ai.tensorchord.envd.ssh.port"
+	ContainerLabelWebTerminalAddr   = "ai.tensorchord.envd.web.terminal.address"
+	ContainerLabelVSCodeServerAddr  = "ai.tensorchord.envd.vscode.server.address"
 
 	ImageLabelContainerName = "ai.tensorchord.envd.container.name"
 	ImageLabelVendor        = "ai.tensorchord.envd.vendor"
@@ -28,6 +30,9 @@ const (
 	ImageLabelAPT           = "ai.tensorchord.envd.apt.packages"
 	ImageLabelPyPI          = "ai.tensorchord.envd.pypi.commands"
 	ImageLabelR             = "ai.tensorchord.envd.r.packages"
+	ImageLabelJulia         = "ai.tensorchord.envd.julia.packages"
+	ImageLabelNode          = "ai.tensorchord.envd.node.packages"
+	ImageLabelJupyterKernel = "ai.tensorchord.envd.jupyter.kernels"
 	ImageLabelCUDA          = "ai.tensorchord.envd.gpu.cuda"
 	ImageLabelCUDNN         = "ai.tensorchord.envd.gpu.cudnn"
 	ImageLabelContext       = "ai.tensorchord.envd.build.context"
@@ -35,6 +40,9 @@ const (
 	ImageLabelSyntaxVer     = "ai.tensorchord.envd.syntax.version"
 	RuntimeGraphCode        = "ai.tensorchord.envd.graph.runtime"
 	GeneralGraphCode        = "ai.tensorchord.envd.graph.general"
+	// ImageLabelBuildGraphHash is the hash of the graph without the reloadable
+	// runtime graph, see `ir.RuntimeGraph.Reloadable`.
+	ImageLabelBuildGraphHash = "ai.tensorchord.envd.graph.build.hash"
 
 	ImageVendorEnvd = "envd"
 )
@@ -0,0 +1,98 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+type AuditEventType string
+
+const (
+	// AuditEventSession is logged when the ssh session is closed.
+	AuditEventSession AuditEventType = "session"
+	// AuditEventForward is logged when the port forwarding is requested.
+	AuditEventForward AuditEventType = "forward"
+)
+
+// AuditEvent is a line of the envd-sshd audit log.
+type AuditEvent struct {
+	Type AuditEventType `json:"type"`
+	Time time.Time      `json:"time"`
+	// ConnectionID is shared by the sessions and the port forwardings of the
+	// same ssh connection.
+	ConnectionID   string `json:"connection_id"`
+	SessionID      string `json:"session_id,omitempty"`
+	User           string `json:"user"`
+	RemoteAddr     string `json:"remote_addr"`
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
+
+	Command  string  `json:"command,omitempty"`
+	PTY      bool    `json:"pty,omitempty"`
+	Term     string  `json:"term,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	// ExitCode is nil if the session is closed without the exit status.
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Recording is the asciinema v2 recording of the PTY session.
+	Recording string `json:"recording,omitempty"`
+
+	// Forward is `local` for `ssh -L` or `remote` for `ssh -R`.
+	Forward     string `json:"forward,omitempty"`
+	ForwardHost string `json:"forward_host,omitempty"`
+	ForwardPort uint32 `json:"forward_port,omitempty"`
+	Granted     *bool  `json:"granted,omitempty"`
+}
+
+// EnvdSession is the ssh session with the port forwardings of its connection.
+type EnvdSession struct {
+	AuditEvent
+	Forwards []string `json:"forwards,omitempty"`
+}
+
+// NewSessionsFromAuditLog parses the audit log of envd-sshd.
+func NewSessionsFromAuditLog(dat []byte) ([]EnvdSession, error) {
+	var sessions []EnvdSession
+	forwards := make(map[string][]string)
+	for i, line := range bytes.Split(dat, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e AuditEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse line %d of the audit log", i+1)
+		}
+		switch e.Type {
+		case AuditEventSession:
+			sessions = append(sessions, EnvdSession{AuditEvent: e})
+		case AuditEventForward:
+			f := fmt.Sprintf("%s %s", e.Forward, net.JoinHostPort(e.ForwardHost, strconv.Itoa(int(e.ForwardPort))))
+			if e.Granted == nil || !*e.Granted {
+				f += " (denied)"
+			}
+			forwards[e.ConnectionID] = append(forwards[e.ConnectionID], f)
+		}
+	}
+	for i := range sessions {
+		sessions[i].Forwards = forwards[sessions[i].ConnectionID]
+	}
+	return sessions, nil
+}
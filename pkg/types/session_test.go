@@ -0,0 +1,45 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("audit log", func() {
+	g.It("Should group the port forwardings by the connection", func() {
+		log := `{"type":"forward","time":"2023-01-01T00:00:00Z","connection_id":"c1","user":"envd","remote_addr":"172.17.0.1:5000","forward":"local","forward_host":"localhost","forward_port":8888,"granted":true}
+{"type":"forward","time":"2023-01-01T00:00:01Z","connection_id":"c2","user":"envd","remote_addr":"172.17.0.1:5001","forward":"remote","forward_host":"0.0.0.0","forward_port":9000,"granted":false}
+{"type":"session","time":"2023-01-01T00:00:00Z","connection_id":"c1","session_id":"s1","user":"envd","remote_addr":"172.17.0.1:5000","key_fingerprint":"SHA256:abc","pty":true,"duration":3.5,"exit_code":0,"recording":"/var/envd/sessions/s1.cast"}
+
+{"type":"session","time":"2023-01-01T00:00:02Z","connection_id":"c2","session_id":"s2","user":"envd","remote_addr":"172.17.0.1:5001","command":"ls"}
+`
+		sessions, err := NewSessionsFromAuditLog([]byte(log))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions).To(HaveLen(2))
+		Expect(sessions[0].SessionID).To(Equal("s1"))
+		Expect(sessions[0].Forwards).To(Equal([]string{"local localhost:8888"}))
+		Expect(*sessions[0].ExitCode).To(Equal(0))
+		Expect(sessions[1].Command).To(Equal("ls"))
+		Expect(sessions[1].ExitCode).To(BeNil())
+		Expect(sessions[1].Forwards).To(Equal([]string{"remote 0.0.0.0:9000 (denied)"}))
+	})
+
+	g.It("Should return an error for the malformed line", func() {
+		_, err := NewSessionsFromAuditLog([]byte("{\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
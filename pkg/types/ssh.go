@@ -0,0 +1,144 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// PortRange is an inclusive range of the TCP ports, e.g. `8000-9000`.
+type PortRange struct {
+	Min uint32
+	Max uint32
+}
+
+// ParsePortRange parses the single port `8888` or the range `8000-9000`.
+func ParsePortRange(s string) (PortRange, error) {
+	first, last, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	var r PortRange
+	var err error
+	if r.Min, err = parsePort(first); err != nil {
+		return PortRange{}, errors.Wrapf(err, "invalid port range %q", s)
+	}
+	r.Max = r.Min
+	if isRange {
+		if r.Max, err = parsePort(last); err != nil {
+			return PortRange{}, errors.Wrapf(err, "invalid port range %q", s)
+		}
+	}
+	if r.Min > r.Max {
+		return PortRange{}, errors.Newf("invalid port range %q: %d is greater than %d", s, r.Min, r.Max)
+	}
+	return r, nil
+}
+
+func parsePort(s string) (uint32, error) {
+	port, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(port), nil
+}
+
+// Contains returns true if the port is in the range.
+func (r PortRange) Contains(port uint32) bool {
+	return port >= r.Min && port <= r.Max
+}
+
+func (r PortRange) String() string {
+	if r.Min == r.Max {
+		return strconv.FormatUint(uint64(r.Min), 10)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// PortForward is the TCP forward of `envd port-forward`. The local forward
+// listens on the host and connects to the target from the environment, the
+// remote forward listens in the environment and connects to the target
+// from the host.
+type PortForward struct {
+	Remote      bool   `json:"remote"`
+	BindAddress string `json:"bind_address"`
+	BindPort    int    `json:"bind_port"`
+	TargetHost  string `json:"target_host"`
+	TargetPort  int    `json:"target_port"`
+}
+
+// ParsePortForward parses the forward in the format of
+// `[bind_address:]port:host:hostport`, `port:hostport` or `port`. The
+// address is bound to the loopback interface and the host is localhost if
+// they are omitted.
+func ParsePortForward(s string, remote bool) (PortForward, error) {
+	f := PortForward{
+		Remote:      remote,
+		BindAddress: "127.0.0.1",
+		TargetHost:  "localhost",
+	}
+	fields := strings.Split(s, ":")
+	var bindPort, targetPort string
+	switch len(fields) {
+	case 1:
+		bindPort, targetPort = fields[0], fields[0]
+	case 2:
+		bindPort, targetPort = fields[0], fields[1]
+	case 3:
+		bindPort, f.TargetHost, targetPort = fields[0], fields[1], fields[2]
+	case 4:
+		f.BindAddress, bindPort, f.TargetHost, targetPort = fields[0], fields[1], fields[2], fields[3]
+	default:
+		return PortForward{}, errors.Newf("invalid port forward %q, expected [bind_address:]port:host:hostport", s)
+	}
+	if f.BindAddress == "" || f.TargetHost == "" {
+		return PortForward{}, errors.Newf("invalid port forward %q: the address is empty", s)
+	}
+	port, err := parsePort(bindPort)
+	if err != nil {
+		return PortForward{}, errors.Wrapf(err, "invalid port forward %q", s)
+	}
+	f.BindPort = int(port)
+	if port, err = parsePort(targetPort); err != nil || port == 0 {
+		return PortForward{}, errors.Newf("invalid port forward %q: invalid target port %s", s, targetPort)
+	}
+	f.TargetPort = int(port)
+	return f, nil
+}
+
+// BindAddr returns the address to listen on.
+func (f PortForward) BindAddr() string {
+	return net.JoinHostPort(f.BindAddress, strconv.Itoa(f.BindPort))
+}
+
+// TargetAddr returns the address to connect to.
+func (f PortForward) TargetAddr() string {
+	return net.JoinHostPort(f.TargetHost, strconv.Itoa(f.TargetPort))
+}
+
+func (f PortForward) String() string {
+	return fmt.Sprintf("%s -> %s", f.BindAddr(), f.TargetAddr())
+}
+
+// PortForwardSession is the running `envd port-forward` process.
+type PortForwardSession struct {
+	PID      int           `json:"pid"`
+	Env      string        `json:"env"`
+	Started  time.Time     `json:"started"`
+	Forwards []PortForward `json:"forwards"`
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("port range", func() {
+	g.DescribeTable("Should parse the port range",
+		func(s string, expected PortRange) {
+			r, err := ParsePortRange(s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r).To(Equal(expected))
+			Expect(r.Contains(expected.Min)).To(BeTrue())
+			Expect(r.Contains(expected.Max + 1)).To(BeFalse())
+		},
+		g.Entry("single port", "8888", PortRange{Min: 8888, Max: 8888}),
+		g.Entry("range", "8000-9000", PortRange{Min: 8000, Max: 9000}),
+	)
+
+	g.DescribeTable("Should reject the invalid port range",
+		func(s string) {
+			_, err := ParsePortRange(s)
+			Expect(err).To(HaveOccurred())
+		},
+		g.Entry("empty", ""),
+		g.Entry("not a number", "http"),
+		g.Entry("out of range", "65536"),
+		g.Entry("reversed", "9000-8000"),
+	)
+})
+
+var _ = g.Describe("port forward", func() {
+	g.DescribeTable("Should parse the port forward",
+		func(s string, expected PortForward) {
+			f, err := ParsePortForward(s, expected.Remote)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(f).To(Equal(expected))
+		},
+		g.Entry("single port", "8888", PortForward{
+			BindAddress: "127.0.0.1", BindPort: 8888, TargetHost: "localhost", TargetPort: 8888}),
+		g.Entry("port pair", "8080:80", PortForward{
+			BindAddress: "127.0.0.1", BindPort: 8080, TargetHost: "localhost", TargetPort: 80}),
+		g.Entry("target host", "5432:db:5432", PortForward{
+			Remote: true, BindAddress: "127.0.0.1", BindPort: 5432, TargetHost: "db", TargetPort: 5432}),
+		g.Entry("bind address", "0.0.0.0:8080:web:80", PortForward{
+			BindAddress: "0.0.0.0", BindPort: 8080, TargetHost: "web", TargetPort: 80}),
+	)
+
+	g.DescribeTable("Should reject the invalid port forward",
+		func(s string) {
+			_, err := ParsePortForward(s, false)
+			Expect(err).To(HaveOccurred())
+		},
+		g.Entry("empty", ""),
+		g.Entry("not a number", "http:80"),
+		g.Entry("zero target port", "8080:0"),
+		g.Entry("empty host", "8080::80"),
+		g.Entry("too many fields", "a:1:b:2:3"),
+	)
+
+	g.It("Should format the addresses", func() {
+		f, err := ParsePortForward("8080:80", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.String()).To(Equal("127.0.0.1:8080 -> localhost:80"))
+	})
+})
@@ -0,0 +1,221 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package asciicast writes and replays the terminal recordings in the
+// asciinema v2 format, see https://docs.asciinema.org/manual/asciicast/v2/
+package asciicast
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	EventOutput = "o"
+	EventResize = "r"
+)
+
+// Header is the first line of the recording.
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event is a line after the header, encoded as `[time, type, data]`.
+type Event struct {
+	Time float64
+	Type string
+	Data string
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{e.Time, e.Type, e.Data})
+}
+
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return errors.Newf("expected 3 elements in the event, got %d", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &e.Time); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw[1], &e.Type); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[2], &e.Data)
+}
+
+// Writer records the output written to it. It is safe for concurrent use.
+type Writer struct {
+	mu      sync.Mutex
+	w       io.Writer
+	start   time.Time
+	pending []byte
+}
+
+// NewWriter writes the header and returns the writer of the events.
+func NewWriter(w io.Writer, header Header) (*Writer, error) {
+	header.Version = 2
+	if header.Timestamp == 0 {
+		header.Timestamp = time.Now().Unix()
+	}
+	if err := json.NewEncoder(w).Encode(header); err != nil {
+		return nil, errors.Wrap(err, "failed to write the asciicast header")
+	}
+	return &Writer{w: w, start: time.Now()}, nil
+}
+
+// Write records the output. The incomplete UTF-8 sequence at the end is
+// kept until the next write, since the event data is a JSON string.
+func (r *Writer) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.pending, p...)
+	end := incompleteUTF8Start(data)
+	r.pending = append([]byte(nil), data[end:]...)
+	if end == 0 {
+		return len(p), nil
+	}
+	if err := r.writeEvent(EventOutput, string(data[:end])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// incompleteUTF8Start returns the index of the incomplete UTF-8 sequence at
+// the end of data, or len(data) if there is none.
+func incompleteUTF8Start(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return len(data)
+			}
+			return i
+		}
+	}
+	return len(data)
+}
+
+// Flush records the pending bytes even if they are not valid UTF-8.
+func (r *Writer) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) == 0 {
+		return nil
+	}
+	data := string(r.pending)
+	r.pending = nil
+	return r.writeEvent(EventOutput, data)
+}
+
+// Resize records the terminal size change.
+func (r *Writer) Resize(width, height int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.writeEvent(EventResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+func (r *Writer) writeEvent(typ, data string) error {
+	b, err := json.Marshal(Event{
+		Time: time.Since(r.start).Seconds(),
+		Type: typ,
+		Data: data,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.w.Write(append(b, '\n'))
+	return err
+}
+
+// Read reads the header and the events of the recording.
+func Read(rd io.Reader) (Header, []Event, error) {
+	var header Header
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return header, nil, err
+		}
+		return header, nil, errors.New("empty recording")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return header, nil, errors.Wrap(err, "failed to parse the asciicast header")
+	}
+	if header.Version != 2 {
+		return header, nil, errors.Newf("unsupported asciicast version %d", header.Version)
+	}
+
+	var events []Event
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return header, nil, errors.Wrap(err, "failed to parse the asciicast event")
+		}
+		events = append(events, e)
+	}
+	return header, events, scanner.Err()
+}
+
+// Replay writes the output events to w with the recorded timing divided by
+// the speed. The idle time between the events is limited to maxIdle if it
+// is positive.
+func Replay(ctx context.Context, w io.Writer, events []Event, speed float64, maxIdle time.Duration) error {
+	if speed <= 0 {
+		return errors.Newf("invalid speed %v", speed)
+	}
+	var last float64
+	for _, e := range events {
+		delay := time.Duration((e.Time - last) / speed * float64(time.Second))
+		last = e.Time
+		if maxIdle > 0 && delay > maxIdle {
+			delay = maxIdle
+		}
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+		if e.Type != EventOutput {
+			continue
+		}
+		if _, err := io.WriteString(w, e.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package asciicast
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAndReplay(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Width: 80, Height: 24, Env: map[string]string{"TERM": "xterm"}})
+	require.NoError(t, err)
+
+	// "é" is split across the writes.
+	_, err = w.Write([]byte("caf\xc3"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("\xa9\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Resize(100, 40))
+	_, err = w.Write([]byte("$ "))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	header, events, err := Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, 2, header.Version)
+	require.Equal(t, 80, header.Width)
+	require.Equal(t, "xterm", header.Env["TERM"])
+	require.Len(t, events, 4)
+	require.Equal(t, Event{Time: events[0].Time, Type: EventOutput, Data: "caf"}, events[0])
+	require.Equal(t, "é\r\n", events[1].Data)
+	require.Equal(t, Event{Time: events[2].Time, Type: EventResize, Data: "100x40"}, events[2])
+
+	var out bytes.Buffer
+	require.NoError(t, Replay(context.Background(), &out, events, 100, 0))
+	require.Equal(t, "café\r\n$ ", out.String())
+}
+
+func TestRead(t *testing.T) {
+	cast := `{"version": 2, "width": 80, "height": 24}
+[0.5, "o", "hello"]
+[1.0, "i", "x"]
+`
+	_, events, err := Read(strings.NewReader(cast))
+	require.NoError(t, err)
+	require.Equal(t, []Event{{0.5, "o", "hello"}, {1.0, "i", "x"}}, events)
+
+	_, _, err = Read(strings.NewReader(`{"version": 1}`))
+	require.Error(t, err)
+	_, _, err = Read(strings.NewReader("{\"version\": 2}\n[0.5, \"o\"]\n"))
+	require.Error(t, err)
+}
+
+func TestFlushInvalidUTF8(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Header{Width: 80, Height: 24})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("\xe4\xb8"))
+	require.NoError(t, err)
+	require.NoError(t, w.Flush())
+
+	_, events, err := Read(&buf)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	// Every invalid byte is replaced by the JSON encoder.
+	require.Equal(t, "\ufffd\ufffd", events[0].Data)
+}
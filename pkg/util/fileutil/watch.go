@@ -0,0 +1,172 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileutil
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+)
+
+// Watcher watches the given files and reports the changed files after the
+// debounce duration. The parent directories are watched instead of the files
+// because most editors replace the file on saving.
+type Watcher struct {
+	watcher  *fsnotify.Watcher
+	debounce time.Duration
+
+	mu      sync.Mutex
+	files   map[string]struct{}
+	dirs    map[string]struct{}
+	pending map[string]struct{}
+	notify  chan struct{}
+	errCh   chan error
+}
+
+func NewWatcher(debounce time.Duration) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the file watcher")
+	}
+	w := &Watcher{
+		watcher:  fw,
+		debounce: debounce,
+		files:    make(map[string]struct{}),
+		dirs:     make(map[string]struct{}),
+		pending:  make(map[string]struct{}),
+		notify:   make(chan struct{}, 1),
+		errCh:    make(chan error, 1),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Watch replaces the watched files with the given files.
+func (w *Watcher) Watch(files []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	newFiles := make(map[string]struct{})
+	newDirs := make(map[string]struct{})
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get the absolute path of %s", f)
+		}
+		newFiles[abs] = struct{}{}
+		newDirs[filepath.Dir(abs)] = struct{}{}
+	}
+	for dir := range w.dirs {
+		if _, ok := newDirs[dir]; !ok {
+			if err := w.watcher.Remove(dir); err != nil {
+				logrus.WithError(err).Debugf("failed to stop watching %s", dir)
+			}
+		}
+	}
+	for dir := range newDirs {
+		if _, ok := w.dirs[dir]; ok {
+			continue
+		}
+		if err := w.watcher.Add(dir); err != nil {
+			return errors.Wrapf(err, "failed to watch %s", dir)
+		}
+	}
+	w.files = newFiles
+	w.dirs = newDirs
+	return nil
+}
+
+// Wait blocks until the watched files are changed and no more changes happen
+// in the debounce duration, then returns the changed files.
+func (w *Watcher) Wait(ctx context.Context) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err := <-w.errCh:
+		return nil, err
+	case <-w.notify:
+	}
+
+	timer := time.NewTimer(w.debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err := <-w.errCh:
+			return nil, err
+		case <-w.notify:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(w.debounce)
+		case <-timer.C:
+			w.mu.Lock()
+			changed := make([]string, 0, len(w.pending))
+			for f := range w.pending {
+				changed = append(changed, f)
+			}
+			w.pending = make(map[string]struct{})
+			w.mu.Unlock()
+			sort.Strings(changed)
+			return changed, nil
+		}
+	}
+}
+
+func (w *Watcher) Close() error {
+	return w.watcher.Close()
+}
+
+func (w *Watcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) &&
+				!event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			w.mu.Lock()
+			_, watched := w.files[event.Name]
+			if watched {
+				w.pending[event.Name] = struct{}{}
+			}
+			w.mu.Unlock()
+			if watched {
+				select {
+				case w.notify <- struct{}{}:
+				default:
+				}
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			select {
+			case w.errCh <- err:
+			default:
+			}
+		}
+	}
+}
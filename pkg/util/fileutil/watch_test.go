@@ -0,0 +1,65 @@
+// Copyright 2023 The envd Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "build.envd")
+	requirements := filepath.Join(dir, "requirements.txt")
+	unrelated := filepath.Join(dir, "main.py")
+	for _, f := range []string{manifest, requirements, unrelated} {
+		require.NoError(t, os.WriteFile(f, []byte("init"), 0644))
+	}
+
+	w, err := NewWatcher(100 * time.Millisecond)
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, w.Watch([]string{manifest, requirements}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Changes of the unwatched file should be ignored, and the changes of
+	// watched files in the debounce duration should be merged.
+	require.NoError(t, os.WriteFile(unrelated, []byte("changed"), 0644))
+	require.NoError(t, os.WriteFile(manifest, []byte("changed"), 0644))
+	require.NoError(t, os.WriteFile(requirements, []byte("changed"), 0644))
+	changed, err := w.Wait(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{manifest, requirements}, changed)
+
+	// Replace the file like most editors do.
+	tmp := filepath.Join(dir, ".build.envd.swp")
+	require.NoError(t, os.WriteFile(tmp, []byte("saved"), 0644))
+	require.NoError(t, os.Rename(tmp, manifest))
+	changed, err = w.Wait(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{manifest}, changed)
+
+	cancelCtx, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	_, err = w.Wait(cancelCtx)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -17,6 +17,7 @@ package starlarkutil
 import (
 	"github.com/cockroachdb/errors"
 
+	starlarkjson "go.starlark.net/lib/json"
 	"go.starlark.net/starlark"
 )
 
@@ -36,3 +37,17 @@ func ToStringSlice(v *starlark.List) ([]string, error) {
 
 	return s, nil
 }
+
+// ToJSON encodes the value such as a dict to the JSON string.
+func ToJSON(thread *starlark.Thread, v starlark.Value) (string, error) {
+	if v == nil || v == starlark.None {
+		return "", nil
+	}
+
+	encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{v}, nil)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to encode %s to JSON", v.Type())
+	}
+	str, _ := starlark.AsString(encoded)
+	return str, nil
+}
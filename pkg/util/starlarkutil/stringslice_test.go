@@ -67,3 +67,16 @@ func TestToStringSliceHappy(t *testing.T) {
 	assert.Equal(t, len(resultSlice), 2)
 	assert.Nil(t, err)
 }
+
+func TestToJSON(t *testing.T) {
+	dict := starlark.NewDict(1)
+	assert.Nil(t, dict.SetKey(starlark.String("@jupyterlab/apputils-extension:themes"),
+		starlark.NewList([]starlark.Value{starlark.String("dark")})))
+	str, err := ToJSON(&starlark.Thread{}, dict)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"@jupyterlab/apputils-extension:themes":["dark"]}`, str)
+
+	str, err = ToJSON(&starlark.Thread{}, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, str)
+}